@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/pgparser"
+	"github.com/nu11ptr/parsegen/pkg/pgtoken"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// grammar is a loaded grammar file along with the .pg file that referenced it
+// (if any)
+type grammar struct {
+	// PGFile is the path of the .pg file or empty if a grammar was given directly
+	PGFile string
+	// Body is the parsed .pg file or nil if a grammar was given directly
+	Body *ast.Body
+	// File is the path of the grammar file
+	File string
+	// TopLevel is the parsed grammar
+	TopLevel *ast.TopLevel
+}
+
+// loadGrammar loads either a .pg file and the grammar it references or a
+// grammar file by itself. The files read are added to a file set so problems
+// found in them can be shown with their source
+func loadGrammar(files *runtime.FileSet, filename string) (*grammar, error) {
+	if !isPGFile(filename) {
+		top, err := parseGrammarFile(files, filename)
+		if err != nil {
+			return nil, err
+		}
+		return &grammar{File: filename, TopLevel: top}, nil
+	}
+
+	body, err := parsePGFile(files, filename)
+	if err != nil {
+		return nil, err
+	}
+	if body.Parser == "" {
+		return nil, fmt.Errorf("%s: no parser grammar given", filename)
+	}
+
+	// The grammar is relative to the .pg file, not the current directory
+	file := body.Parser
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(filename), file)
+	}
+	top, err := parseGrammarFile(files, file)
+	if err != nil {
+		return nil, err
+	}
+	return &grammar{PGFile: filename, Body: body, File: file, TopLevel: top}, nil
+}
+
+// checkGrammar writes the problems found by checking a grammar to stderr. It
+// returns an error if any of them are errors
+func checkGrammar(g *grammar, files *runtime.FileSet, stderr io.Writer) error {
+	p := newPrinter(files, stderr)
+	errs := 0
+	for _, diag := range g.TopLevel.Check() {
+		// Rules from other grammars are reported against their own file
+		file := diag.Span.File
+		if file == "" {
+			file = g.File
+		}
+		d := &runtime.Diagnostic{
+			Severity: diag.Severity, Filename: file, Line: int(diag.Span.Start.Row), Col: int(diag.Span.Start.Col), Message: diag.Message,
+		}
+		if f := files.Lookup(file); f != nil && diag.Span.IsValid() {
+			d.Span = runtime.Span{File: f.ID, Start: diag.Span.Start.Offset, End: diag.Span.End.Offset}
+		}
+		if diag.Severity == runtime.SeverityError {
+			errs++
+		}
+		fmt.Fprint(stderr, p.Format(d))
+	}
+	if errs > 0 {
+		return fmt.Errorf("%s: grammar has %d error(s)", g.File, errs)
+	}
+	return nil
+}
+
+func isPGFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".pg")
+}
+
+// parseGrammarFile parses a grammar file and merges in the grammars it imports
+// or uses as its token vocabulary, which are read from the same directory
+func parseGrammarFile(files *runtime.FileSet, filename string) (*ast.TopLevel, error) {
+	top, err := parseSingleGrammarFile(files, filename)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	err = top.Resolve(func(name string) (*ast.TopLevel, error) {
+		return parseSingleGrammarFile(files, filepath.Join(dir, name+".g4"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+func parseSingleGrammarFile(files *runtime.FileSet, filename string) (*ast.TopLevel, error) {
+	f, err := files.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	lex := runtime.NewLexerForFile(f)
+	p := runtime.NewParser(token.New(lex))
+	p.SetFilename(filename)
+	top, err := parser.New(p).Parse()
+	if err == nil {
+		err = lexError(filename, lex)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+func parsePGFile(files *runtime.FileSet, filename string) (*ast.Body, error) {
+	f, err := files.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	lex := runtime.NewLexerForFile(f)
+	p := runtime.NewParser(pgtoken.New(lex))
+	p.SetFilename(filename)
+	body, err := pgparser.New(p).Parse()
+	if err == nil {
+		err = lexError(filename, lex)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// lexError returns the first problem the lexer found in the input, if any.
+// These can go unnoticed by the parser (ex: invalid UTF-8 in a comment)
+func lexError(filename string, lex *runtime.Lexer) error {
+	if errs := lex.Errors(); len(errs) > 0 {
+		return fmt.Errorf("%s:%w", filename, errs[0])
+	}
+	return nil
+}
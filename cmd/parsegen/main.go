@@ -0,0 +1,281 @@
+// Command parsegen generates Go parsers and tokenizers from grammar files.
+//
+// Usage:
+//
+//	parsegen generate [flags] file.pg|file.g4
+//	parsegen check file.pg|file.g4
+//	parsegen dump file.pg|file.g4
+//	parsegen repl [flags] file.pg|file.g4
+//
+// It is meant to be usable from go:generate lines, for example:
+//
+//	//go:generate go run github.com/nu11ptr/parsegen/cmd/parsegen generate ../../grammars/antlr.pg
+//
+// When run by go generate, the package name defaults to $GOPACKAGE. A grammar
+// can be generated without a .pg file, in which case every parser rule gets a
+// generated result type.
+//
+// The repl command parses input typed in with the rules of a grammar without
+// generating any code, showing the token stream and the parse tree of each
+// input, which makes it a playground for trying out changes to a grammar.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/gen"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+const usage = `Usage: parsegen <command> [flags] <file>
+
+Commands:
+  generate   generate Go source code from a .pg or grammar file
+  check      parse and validate a .pg or grammar file
+  dump       print the parsed grammar tree of a .pg or grammar file
+  repl       parse input typed in with the rules of a .pg or grammar file
+
+Run 'parsegen <command> -h' for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command given by args and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var cmd func([]string, io.Writer, io.Writer) int
+	switch args[0] {
+	case "generate":
+		cmd = generate
+	case "check":
+		cmd = check
+	case "dump":
+		cmd = dump
+	case "repl":
+		cmd = func(args []string, stdout, stderr io.Writer) int {
+			return repl(args, stdin, stdout, stderr)
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "parsegen: unknown command: %s\n\n%s", args[0], usage)
+		return 2
+	}
+	return cmd(args[1:], stdout, stderr)
+}
+
+// newFlagSet creates a flag set for a command that takes a single file argument
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: parsegen %s [flags] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the flags of a command and returns its single file
+// argument. It returns false if the command line was invalid
+func parseFlags(flags *flag.FlagSet, args []string) (string, bool) {
+	if err := flags.Parse(args); err != nil {
+		return "", false
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", false
+	}
+	return flags.Arg(0), true
+}
+
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "parsegen: %v\n", err)
+	return 1
+}
+
+// failInput is like fail, but shows syntax and lexer errors in the files read
+// with the source they were found in
+func failInput(stderr io.Writer, files *runtime.FileSet, err error) int {
+	if d, ok := runtime.ErrorDiagnostic(err); ok {
+		fmt.Fprint(stderr, newPrinter(files, stderr).Format(d))
+		return 1
+	}
+	return fail(stderr, err)
+}
+
+// newPrinter creates a printer for problems found in files. Output to a
+// terminal is in color unless the NO_COLOR environment variable is set
+func newPrinter(files *runtime.FileSet, w io.Writer) *runtime.DiagnosticPrinter {
+	color := false
+	if f, ok := w.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			color = true
+		}
+	}
+	return &runtime.DiagnosticPrinter{Files: files, Color: color}
+}
+
+// *** generate ***
+
+// stringsFlag is a flag that can be given more than once
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func generate(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("generate", "file.pg|file.g4", stderr)
+	outDir := flags.String("o", ".", "output directory")
+	pkg := flags.String("pkg", "", "package name (default $GOPACKAGE or the output directory name)")
+	tokenImport := flags.String("token-import", "",
+		"import path of the package with the token types (default: generate a tokenizer)")
+	var imports stringsFlag
+	flags.Var(&imports, "import", "additional import needed by code blocks (can be repeated)")
+	lossless := flags.Bool("lossless", false,
+		"keep skipped tokens as trivia and generate a concrete syntax tree (for formatters and refactoring tools)")
+
+	filename, ok := parseFlags(flags, args)
+	if !ok {
+		return 2
+	}
+
+	if *pkg == "" {
+		*pkg = os.Getenv("GOPACKAGE")
+	}
+	if *pkg == "" {
+		dir, err := filepath.Abs(*outDir)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		*pkg = filepath.Base(dir)
+	}
+	if !token.IsIdentifier(*pkg) {
+		return fail(stderr, fmt.Errorf("invalid package name: %q (use -pkg to set one)", *pkg))
+	}
+
+	files := runtime.NewFileSet()
+	g, err := loadGrammar(files, filename)
+	if err != nil {
+		return failInput(stderr, files, err)
+	}
+	if err := checkGrammar(g, files, stderr); err != nil {
+		return fail(stderr, err)
+	}
+	opts := &gen.Options{Package: *pkg, TokenImport: *tokenImport, Imports: imports, Lossless: *lossless}
+
+	generated, err := generateFiles(g, opts)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return fail(stderr, err)
+	}
+	for _, name := range []string{"tokenizer.go", "parser.go"} {
+		code, ok := generated[name]
+		if !ok {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(*outDir, name), code, 0644); err != nil {
+			return fail(stderr, err)
+		}
+	}
+	return 0
+}
+
+// generateFiles generates the source code for a .pg or grammar file keyed by
+// file name. A tokenizer is only generated when the token types aren't
+// imported and the grammar has lexer rules. Without a .pg file, a parser is
+// only generated when the grammar has parser rules
+func generateFiles(g *grammar, opts *gen.Options) (map[string][]byte, error) {
+	files := make(map[string][]byte, 2)
+
+	if opts.TokenImport == "" && len(g.TopLevel.LexerRules) > 0 {
+		opts.Combined = true
+		code, err := gen.GenerateTokenizer(g.TopLevel, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", g.File, err)
+		}
+		files["tokenizer.go"] = code
+	}
+
+	body, file := g.Body, g.PGFile
+	if body == nil {
+		if len(g.TopLevel.ParserRules) == 0 {
+			return files, nil
+		}
+		// No code blocks, so every rule gets a generated result type
+		body = &ast.Body{Parser: filepath.Base(g.File), CodeBlocks: &ast.CodeBlocks{Language: "go"}}
+		file = g.File
+	}
+	code, err := gen.GenerateParser(g.TopLevel, body, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	files["parser.go"] = code
+	return files, nil
+}
+
+// *** check ***
+
+func check(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("check", "file.pg|file.g4", stderr)
+	filename, ok := parseFlags(flags, args)
+	if !ok {
+		return 2
+	}
+
+	files := runtime.NewFileSet()
+	g, err := loadGrammar(files, filename)
+	if err != nil {
+		return failInput(stderr, files, err)
+	}
+	if err := checkGrammar(g, files, stderr); err != nil {
+		return fail(stderr, err)
+	}
+
+	// Generating code (and throwing it away) validates the code blocks against
+	// the grammar
+	if _, err := generateFiles(g, &gen.Options{Package: "check"}); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+// *** dump ***
+
+func dump(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("dump", "file.pg|file.g4", stderr)
+	filename, ok := parseFlags(flags, args)
+	if !ok {
+		return 2
+	}
+
+	files := runtime.NewFileSet()
+	g, err := loadGrammar(files, filename)
+	if err != nil {
+		return failInput(stderr, files, err)
+	}
+	fmt.Fprint(stdout, g.TopLevel.String())
+	return 0
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const listTree = `TopLevel:
+   └──ParserRule: list
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '['
+            └──ZeroOrOne:
+               └──ParserRuleRef: items
+            └──Token Literal:
+               └──Data: ']'
+            └──LexerRuleRef: EOF
+   └──ParserRule: items
+      └──Alternatives:
+         └──Alternative 0:
+            └──LexerRuleRef: NAME
+            └──ZeroOrMore:
+               └──Alternatives:
+                  └──Alternative 0:
+                     └──Token Literal:
+                        └──Data: ','
+                     └──LexerRuleRef: NAME
+   └──LexerRule: LBRACK
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '['
+   └──LexerRule: RBRACK
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: ']'
+   └──LexerRule: COMMA
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: ','
+   └──LexerRule: NAME
+      └──Alternatives:
+         └──Alternative 0:
+            └──OneOrMore:
+               └──CharClass: [a-z]
+   └──LexerRule: WS
+      └──Alternatives:
+         └──Alternative 0:
+            └──OneOrMore:
+               └──CharClass: [\t\n\r ]
+      └──Actions:
+         └──Skip
+`
+
+func runCmd(args ...string) (code int, stdout, stderr string) {
+	return runInput("", args...)
+}
+
+func runInput(input string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(input), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := runCmd()
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: parsegen <command>")
+
+	code, _, stderr = runCmd("bogus")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown command: bogus")
+
+	code, _, stderr = runCmd("check")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: parsegen check")
+}
+
+func TestDump(t *testing.T) {
+	// A .pg file dumps the grammar it references
+	for _, file := range []string{"testdata/list.g4", "testdata/list.pg"} {
+		code, stdout, stderr := runCmd("dump", file)
+		assert.Equal(t, 0, code, stderr)
+		assert.Equal(t, listTree, stdout)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		file string
+		err  string
+	}{
+		{file: "testdata/list.g4"},
+		{file: "testdata/list.pg"},
+		{file: "testdata/imports.g4"},
+		{file: "testdata/bom.g4"},
+		{file: "../../grammars/antlr_parser.g4"},
+		{file: "../../grammars/antlr.pg"},
+		{file: "../../grammars/pg.pg"},
+		{file: "testdata/badimport.g4", err: "parsegen: testdata/badimport.g4:1:8: import missing: open testdata/missing.g4: no such file or directory\n"},
+		{file: "testdata/syntax.g4", err: "testdata/syntax.g4:2:1: error: unexpected EOF\n 2 |\n   | ^\n" +
+			"   = note: expected '+', '*', '?', '&', '!', RULE_NAME, TOKEN_NAME, '(', TOKEN_LIT, '#', '|' or ';'\n"},
+		{file: "testdata/encoding.g4", err: "testdata/encoding.g4:1:14: error: invalid UTF-8 encoding \"\\xff\"\n" +
+			" 1 | a: B; // bad \xff\n   |              ^\n"},
+		{file: "testdata/invalid.g4", err: "testdata/invalid.g4:1:4: error: undefined parser rule: b\n" +
+			" 1 | a: b C ';';\n   |    ^\n" +
+			"testdata/invalid.g4:1:6: error: undefined token: C\n" +
+			" 1 | a: b C ';';\n   |      ^\n" +
+			"testdata/invalid.g4:1:8: warning: no lexer rule for literal ';', an implicit token will be used\n" +
+			" 1 | a: b C ';';\n   |        ^^^\n" +
+			"testdata/invalid.g4:3:1: warning: unreachable parser rule: unused (start rule is a)\n" +
+			" 3 | unused: A;\n   | ^^^^^^^^^^\n" +
+			"testdata/invalid.g4:7:1: error: duplicate lexer rule: A (first defined at 5:1)\n" +
+			" 7 | A: 'b';\n   | ^^^^^^^\n" +
+			"parsegen: testdata/invalid.g4: grammar has 3 error(s)\n"},
+		{file: "testdata/bad.pg", err: "parsegen: testdata/bad.pg: code blocks do not match any rule: missing\n"},
+		{file: "testdata/missing.pg", err: "parsegen: open testdata/missing.pg: no such file or directory\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			code, _, stderr := runCmd("check", test.file)
+			if test.err == "" {
+				assert.Equal(t, 0, code, stderr)
+			} else {
+				assert.Equal(t, 1, code)
+			}
+			assert.Equal(t, test.err, stderr)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parsegen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "list")
+
+	code, _, stderr := runCmd("generate", "-o", out, "-token-import", "example.com/tok", "testdata/list.pg")
+	require.Equal(t, 0, code, stderr)
+
+	src, err := ioutil.ReadFile(filepath.Join(out, "parser.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "// Code generated by parsegen from list.g4. DO NOT EDIT.\n\npackage list\n")
+	assert.Contains(t, string(src), "p.p.MatchTokenOrRollback(tok.NAME, oldPos)")
+
+	_, err = os.Stat(filepath.Join(out, "tokenizer.go"))
+	assert.True(t, os.IsNotExist(err), "no tokenizer expected when token types are imported")
+
+	// Without imported token types, a tokenizer is generated alongside the parser
+	code, _, stderr = runCmd("generate", "-o", out, "testdata/list.pg")
+	require.Equal(t, 0, code, stderr)
+
+	src, err = ioutil.ReadFile(filepath.Join(out, "tokenizer.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "package list\n")
+	assert.Contains(t, string(src), "func NewTokenizer(lex *runtime.Lexer) *Tokenizer {")
+	assert.Contains(t, string(src), "func (t *Tokenizer) matchName() bool {")
+
+	src, err = ioutil.ReadFile(filepath.Join(out, "parser.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "func NewParser(p *runtime.Parser) *Parser {")
+	assert.Contains(t, string(src), "p.p.MatchTokenOrRollback(NAME, oldPos)")
+
+	// Without a .pg file, the rules get generated result types
+	code, _, stderr = runCmd("generate", "-o", out, "testdata/list.g4")
+	require.Equal(t, 0, code, stderr)
+
+	src, err = ioutil.ReadFile(filepath.Join(out, "parser.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "// Code generated by parsegen from list.g4. DO NOT EDIT.\n\npackage list\n")
+	assert.Contains(t, string(src), "func (p *Parser) Parse() (*List, error) {")
+	assert.Contains(t, string(src), "type Items struct {\n\tNameTok    *runtime.Token\n\tItemsSub1s []*ItemsSub1\n}")
+
+	code, _, stderr = runCmd("generate", "-o", out, "-pkg", "not-valid", "testdata/list.pg")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `invalid package name: "not-valid"`)
+}
+
+func TestRepl(t *testing.T) {
+	code, stdout, stderr := runInput("[a, \\\nb]\n:start items\n:tokens\na,\n:start list\n[a b]\n:start bogus\n:quit\nnot read\n",
+		"repl", "testdata/list.g4")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, `Parsing from list. Enter :help for the commands.
+list> ....> 1:1  '['   "["
+1:2  NAME  "a"
+1:3  ','   ","
+1:4  WS    " \n"  skipped
+2:1  NAME  "b"
+2:2  ']'   "]"
+2:3  EOF   ""
+list:
+   └──"["
+   └──items:
+      └──"a"
+      └──","
+      └──"b"
+   └──"]"
+   └──""
+list> items> not showing the token stream
+items> items:
+   └──"a"
+note: items stopped before the end of the input: ","
+items> list> 1:4: error: unexpected NAME "b"
+ 1 | [a b]
+   |    ^
+   = note: expected ',' or ']'
+list> undefined parser rule: bogus
+list> `, stdout)
+
+	code, _, stderr = runCmd("repl", "-start", "bogus", "testdata/list.g4")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "parsegen: testdata/list.g4: undefined parser rule: bogus\n", stderr)
+}
+
+// Reloading picks up edits to the grammar, but keeps the old one if the new
+// one has errors
+func TestReplReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parsegen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "x.g4")
+	require.NoError(t, ioutil.WriteFile(file, []byte("a: A;\nA: 'a';\n"), 0644))
+
+	edit := func(grammar string) func() {
+		return func() { require.NoError(t, ioutil.WriteFile(file, []byte(grammar), 0644)) }
+	}
+	stdin := &lineReader{lines: []string{"ab\n", ":reload\n", "ab\n", ":reload\n", "ab\n"}, before: map[int]func(){
+		1: edit("a: A B?;\nA: 'a';\nB: 'b';\n"),
+		3: edit("a: A C;\nA: 'a';\n"),
+	}}
+	var out, errOut bytes.Buffer
+	code := run([]string{"repl", "-no-tokens", file}, stdin, &out, &errOut)
+	require.Equal(t, 0, code, errOut.String())
+
+	assert.Equal(t, `Parsing from a. Enter :help for the commands.
+a> a:
+   └──"a"
+note: a stopped before the end of the input: "b"
+a> reloaded `+file+`
+a> a:
+   └──"a"
+   └──"b"
+a> error: `+file+`: grammar has 1 error(s)
+still using the grammar loaded before
+a> a:
+   └──"a"
+   └──"b"
+a> 
+`, out.String())
+	assert.Equal(t, file+":1:6: error: undefined token: C\n 1 | a: A C;\n   |      ^\n", errOut.String())
+}
+
+// lineReader reads one line at a time, running a function before reading some
+// of them
+type lineReader struct {
+	lines  []string
+	before map[int]func()
+	read   int
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if r.read == len(r.lines) {
+		return 0, io.EOF
+	}
+	if fn, ok := r.before[r.read]; ok {
+		fn()
+	}
+	r.read++
+	return copy(p, r.lines[r.read-1]), nil
+}
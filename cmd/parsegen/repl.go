@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nu11ptr/parsegen/pkg/interp"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// *** repl ***
+
+const replHelp = `Enter input to parse it from the start rule. End a line with \ to continue
+the input on the next line.
+
+Commands:
+  :start [rule]  show or change the start rule
+  :rules         list the parser rules
+  :tokens        turn showing the token stream on or off
+  :reload        read the grammar again (ex: after editing it)
+  :help          show this help
+  :quit          exit (as does the end of the input)
+`
+
+// session is the state of an interactive session with a grammar
+type session struct {
+	filename string
+	in       *interp.Interpreter
+	start    string
+	tokens   bool
+
+	stdout, stderr io.Writer
+}
+
+func repl(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("repl", "file.pg|file.g4", stderr)
+	start := flags.String("start", "", "parser rule to start parsing from (default: the first one)")
+	noTokens := flags.Bool("no-tokens", false, "don't show the token stream of each input")
+	filename, ok := parseFlags(flags, args)
+	if !ok {
+		return 2
+	}
+
+	s := &session{filename: filename, start: *start, tokens: !*noTokens, stdout: stdout, stderr: stderr}
+	files := runtime.NewFileSet()
+	if err := s.load(files); err != nil {
+		return failInput(stderr, files, err)
+	}
+	fmt.Fprintf(stdout, "Parsing from %s. Enter :help for the commands.\n", s.start)
+
+	scanner := bufio.NewScanner(stdin)
+	input := ""
+	for s.prompt(input != ""); scanner.Scan(); s.prompt(input != "") {
+		line := scanner.Text()
+		if strings.HasSuffix(line, `\`) {
+			input += strings.TrimSuffix(line, `\`) + "\n"
+			continue
+		}
+		input += line
+
+		if strings.HasPrefix(input, ":") {
+			if !s.command(strings.Fields(input)) {
+				return 0
+			}
+		} else {
+			s.parse(input)
+		}
+		input = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(stderr, err)
+	}
+	fmt.Fprintln(stdout)
+	return 0
+}
+
+// load reads the grammar into a file set and creates an interpreter for it.
+// The session is left as is if that fails
+func (s *session) load(files *runtime.FileSet) error {
+	g, err := loadGrammar(files, s.filename)
+	if err != nil {
+		return err
+	}
+	if err := checkGrammar(g, files, s.stderr); err != nil {
+		return err
+	}
+	in, err := interp.New(g.TopLevel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", g.File, err)
+	}
+
+	rules := in.RuleNames()
+	if len(rules) == 0 {
+		return fmt.Errorf("%s: grammar has no parser rules", g.File)
+	}
+	start := s.start
+	if start == "" {
+		start = rules[0]
+	} else if !hasRule(rules, start) {
+		return fmt.Errorf("%s: undefined parser rule: %s", g.File, start)
+	}
+	s.in, s.start = in, start
+	return nil
+}
+
+func hasRule(rules []string, name string) bool {
+	for _, rule := range rules {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// prompt shows the start rule, or that more input is expected
+func (s *session) prompt(more bool) {
+	if more {
+		fmt.Fprintf(s.stdout, "%s> ", strings.Repeat(".", len(s.start)))
+		return
+	}
+	fmt.Fprintf(s.stdout, "%s> ", s.start)
+}
+
+// command runs a command. It returns false if the session should end
+func (s *session) command(fields []string) bool {
+	switch fields[0] {
+	case ":start":
+		if len(fields) == 1 {
+			fmt.Fprintln(s.stdout, s.start)
+		} else if hasRule(s.in.RuleNames(), fields[1]) {
+			s.start = fields[1]
+		} else {
+			fmt.Fprintf(s.stdout, "undefined parser rule: %s\n", fields[1])
+		}
+	case ":rules":
+		fmt.Fprintln(s.stdout, strings.Join(s.in.RuleNames(), " "))
+	case ":tokens":
+		s.tokens = !s.tokens
+		if s.tokens {
+			fmt.Fprintln(s.stdout, "showing the token stream")
+		} else {
+			fmt.Fprintln(s.stdout, "not showing the token stream")
+		}
+	case ":reload":
+		files := runtime.NewFileSet()
+		if err := s.load(files); err != nil {
+			fmt.Fprint(s.stdout, newPrinter(files, s.stdout).FormatError(err))
+			fmt.Fprintln(s.stdout, "still using the grammar loaded before")
+		} else {
+			fmt.Fprintf(s.stdout, "reloaded %s\n", s.filename)
+		}
+	case ":help":
+		fmt.Fprint(s.stdout, replHelp)
+	case ":quit", ":q":
+		return false
+	default:
+		fmt.Fprintf(s.stdout, "unknown command: %s (enter :help for the commands)\n", fields[0])
+	}
+	return true
+}
+
+// parse shows the token stream of the input and its parse tree or syntax error
+func (s *session) parse(input string) {
+	if s.tokens {
+		s.printTokens(input)
+	}
+
+	// The input is a file of its own so syntax errors can show it
+	files := runtime.NewFileSet()
+	f := files.AddFile("", []byte(input))
+	tree, err := s.in.Parse(s.start, runtime.NewLexerForFile(f))
+	if err != nil {
+		fmt.Fprint(s.stdout, newPrinter(files, s.stdout).FormatError(err))
+		return
+	}
+	fmt.Fprint(s.stdout, tree.String())
+
+	// Like generated parsers, the start rule doesn't have to match everything
+	if text := tree.Text(); len(text) < len(input) {
+		fmt.Fprintf(s.stdout, "note: %s stopped before the end of the input: %q\n", s.start, input[len(text):])
+	}
+}
+
+// printTokens shows the tokens of the input in a table along with the skipped
+// ones and those on other channels, which the parser doesn't see
+func (s *session) printTokens(input string) {
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 8, 2, ' ', 0)
+	t := s.in.NewTokenizer(runtime.NewLexerFromString(input))
+	for {
+		var tok runtime.Token
+		t.NextToken(&tok)
+		for _, trivia := range tok.Trivia {
+			s.printToken(w, &trivia, "skipped")
+		}
+
+		note := ""
+		switch {
+		case tok.Err != nil:
+			note = tok.Err.Kind.String()
+		case tok.Channel == runtime.HiddenChannel:
+			note = "hidden channel"
+		case tok.Channel != runtime.DefaultChannel:
+			note = fmt.Sprintf("channel %d", tok.Channel)
+		}
+		s.printToken(w, &tok, note)
+
+		if tok.Type == runtime.EOF {
+			break
+		}
+	}
+	w.Flush()
+
+	// Tokens without a note would otherwise end in padding
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		fmt.Fprintln(s.stdout, strings.TrimRight(line, " "))
+	}
+}
+
+func (s *session) printToken(w io.Writer, tok *runtime.Token, note string) {
+	fmt.Fprintf(w, "%d:%d\t%s\t%q\t%s\n", tok.StartRow, tok.StartCol, s.in.TokenName(tok.Type), tok.Data, note)
+}
@@ -0,0 +1,3 @@
+import missing;
+
+a: B;
@@ -0,0 +1,3 @@
+﻿a: B;
+
+B: [a-z]+;
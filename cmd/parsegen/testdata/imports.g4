@@ -0,0 +1,5 @@
+grammar imports;
+
+import list;
+
+start: list;
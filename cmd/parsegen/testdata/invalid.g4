@@ -0,0 +1,7 @@
+a: b C ';';
+
+unused: A;
+
+A: 'a';
+
+A: 'b';
@@ -0,0 +1,13 @@
+list: '[' items? ']' EOF;
+
+items: NAME (',' NAME)*;
+
+LBRACK: '[';
+
+RBRACK: ']';
+
+COMMA: ',';
+
+NAME: [a-z]+;
+
+WS: [ \t\r\n]+ -> skip;
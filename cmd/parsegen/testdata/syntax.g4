@@ -0,0 +1 @@
+list: '[' items? ']'
@@ -0,0 +1,15 @@
+calc: expr EOF;
+
+// Indirectly left recursive through binary
+expr: binary | term;
+
+binary: lhs=expr op=('+' | '-') rhs=term;
+
+// Directly left recursive
+term: lhs=term op=('*' | '/') rhs=factor # Mul | factor # Factor;
+
+factor: '-' factor # Neg | '(' expr ')' # Paren | NUM # Num;
+
+NUM: [0-9]+;
+
+WS: [ \t\r\n]+ -> skip;
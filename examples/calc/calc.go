@@ -0,0 +1,20 @@
+// Package calc is an example integer calculator. Its grammar is left
+// recursive, both directly (term) and indirectly (expr through binary), so
+// the usual left associative grammar for arithmetic can be used as is.
+package calc
+
+//go:generate go run github.com/nu11ptr/parsegen/cmd/parsegen generate -import strconv calc.pg
+
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// Eval evaluates an integer expression using +, -, *, / and parentheses
+func Eval(expr string) (int, error) {
+	p := NewParser(runtime.NewParser(NewTokenizer(runtime.NewLexerFromString(expr))))
+	result, err := p.Parse()
+	if err != nil {
+		return 0, err
+	}
+	return *result, nil
+}
@@ -0,0 +1,53 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/parsegen/examples/calc"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr  string
+		value int
+	}{
+		{expr: "42", value: 42},
+		{expr: "1 - 2 - 3", value: -4},
+		{expr: "2 * 3 + 4 * 5", value: 26},
+		{expr: "100 / 10 / 5", value: 2},
+		{expr: "2 * (3 + 4) - -1", value: 15},
+		{expr: "1 + 2 * 3 - 4 / 2 + 5", value: 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			value, err := calc.Eval(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.value, value)
+		})
+	}
+}
+
+// The exported parse functions of left recursive rules grow the whole result
+// like Parse does
+func TestParseExpr(t *testing.T) {
+	lex := runtime.NewLexerFromString("1-2-3")
+	p := calc.NewParser(runtime.NewParser(calc.NewTokenizer(lex)))
+	value := p.ParseExpr()
+	require.NotNil(t, value)
+	assert.Equal(t, -4, *value)
+
+	lex = runtime.NewLexerFromString("2*3*4")
+	p = calc.NewParser(runtime.NewParser(calc.NewTokenizer(lex)))
+	value = p.ParseTerm()
+	require.NotNil(t, value)
+	assert.Equal(t, 24, *value)
+}
+
+func TestEvalError(t *testing.T) {
+	_, err := calc.Eval("1 + * 2")
+	assert.EqualError(t, err, "1:5: expected '-', '(' or NUM, found '*'")
+}
@@ -0,0 +1,426 @@
+// Code generated by parsegen from calc.g4. DO NOT EDIT.
+
+package calc
+
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"strconv"
+)
+
+// Parser is a packrat parser that memoizes the result of each rule
+type Parser struct {
+	p *runtime.Parser
+
+	calcMap        map[int]runtime.Memo
+	exprMap        map[int]runtime.Memo
+	binarySub1Map  map[int]runtime.Memo
+	termMap        map[int]runtime.Memo
+	termSub2Map    map[int]runtime.Memo
+	factorMap      map[int]runtime.Memo
+	factorNegMap   map[int]runtime.Memo
+	factorParenMap map[int]runtime.Memo
+}
+
+// NewParser creates a new parser that reads tokens from the given runtime parser
+func NewParser(p *runtime.Parser) *Parser {
+	return &Parser{
+		p:              p,
+		calcMap:        make(map[int]runtime.Memo, 8),
+		exprMap:        make(map[int]runtime.Memo, 8),
+		binarySub1Map:  make(map[int]runtime.Memo, 8),
+		termMap:        make(map[int]runtime.Memo, 8),
+		termSub2Map:    make(map[int]runtime.Memo, 8),
+		factorMap:      make(map[int]runtime.Memo, 8),
+		factorNegMap:   make(map[int]runtime.Memo, 8),
+		factorParenMap: make(map[int]runtime.Memo, 8),
+	}
+}
+
+// Parse parses the input starting from the "calc" parser rule. If
+// parsing fails, the error describes the farthest point reached
+func (p *Parser) Parse() (*int, error) {
+	calc := p.ParseCalc()
+	if calc == nil {
+		return nil, p.p.Err()
+	}
+	return calc, nil
+}
+
+// *** calc ***
+
+func (p *Parser) memoParseCalc() *int {
+	pos := p.p.Pos()
+	if memo, ok := p.calcMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		calc, _ := memo.Result.(*int)
+		return calc
+	}
+	calc := p.ParseCalc()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.calcMap[pos] = runtime.Memo{Result: calc, EndPos: p.p.Pos()}
+	return calc
+}
+
+// ParseCalc parses the "calc" parser rule
+func (p *Parser) ParseCalc() *int {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### expr ###
+	expr := p.memoParseExpr()
+	if expr == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### EOF ###
+	eofTok := p.p.MatchTokenOrRollback(runtime.EOF, oldPos)
+	if eofTok == nil {
+		return nil
+	}
+
+	return expr
+}
+
+// *** expr ***
+
+func (p *Parser) memoParseExpr() *int {
+	// Left recursive - grow the result from a failed seed until it stops getting longer
+	result := p.p.GrowSeed(p.exprMap, func() (interface{}, bool) {
+		expr := p.parseExpr()
+		return expr, expr != nil
+	})
+	expr, _ := result.(*int)
+	return expr
+}
+
+// ParseExpr parses the "expr" parser rule
+func (p *Parser) ParseExpr() *int {
+	return p.memoParseExpr()
+}
+
+// parseExpr parses one pass of the left recursive "expr" parser rule
+func (p *Parser) parseExpr() *int {
+	// ### binary ###
+	if binary := p.memoParseBinary(); binary != nil {
+		return binary
+	}
+
+	// ### term ###
+	if term := p.memoParseTerm(); term != nil {
+		return term
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** binary ***
+
+func (p *Parser) memoParseBinary() *int {
+	// Part of a left recursive rule, so the result changes as the rule grows
+	return p.ParseBinary()
+}
+
+// ParseBinary parses the "binary" parser rule
+func (p *Parser) ParseBinary() *int {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### lhs=expr ###
+	lhs := p.memoParseExpr()
+	if lhs == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### op=('+' | '-') ###
+	op := p.memoParseBinarySub1()
+	if op == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### rhs=term ###
+	rhs := p.memoParseTerm()
+	if rhs == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	value := *lhs + *rhs
+	if op.dashTok != nil {
+		value = *lhs - *rhs
+	}
+	return &value
+}
+
+// *** binary - '+' | '-' ***
+
+type binarySub1 struct {
+	plusTok *runtime.Token
+	dashTok *runtime.Token
+}
+
+func (p *Parser) memoParseBinarySub1() *binarySub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.binarySub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		binarySub1, _ := memo.Result.(*binarySub1)
+		return binarySub1
+	}
+	binarySub1 := p.parseBinarySub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.binarySub1Map[pos] = runtime.Memo{Result: binarySub1, EndPos: p.p.Pos()}
+	return binarySub1
+}
+
+// parseBinarySub1 parses a sub-rule of the "binary" parser rule
+func (p *Parser) parseBinarySub1() *binarySub1 {
+	// ### '+' ###
+	if plusTok := p.p.TryMatchToken(PLUS); plusTok != nil {
+		return &binarySub1{plusTok: plusTok}
+	}
+
+	// ### '-' ###
+	if dashTok := p.p.TryMatchToken(DASH); dashTok != nil {
+		return &binarySub1{dashTok: dashTok}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** term ***
+
+func (p *Parser) memoParseTerm() *int {
+	// Left recursive - grow the result from a failed seed until it stops getting longer
+	result := p.p.GrowSeed(p.termMap, func() (interface{}, bool) {
+		term := p.parseTerm()
+		return term, term != nil
+	})
+	term, _ := result.(*int)
+	return term
+}
+
+// ParseTerm parses the "term" parser rule
+func (p *Parser) ParseTerm() *int {
+	return p.memoParseTerm()
+}
+
+// parseTerm parses one pass of the left recursive "term" parser rule
+func (p *Parser) parseTerm() *int {
+	// ### lhs=term op=('*' | '/') rhs=factor ###
+	if termMul := p.memoParseTermMul(); termMul != nil {
+		return termMul
+	}
+
+	// ### factor ###
+	if factor := p.memoParseFactor(); factor != nil {
+		return factor
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** term - lhs=term op=('*' | '/') rhs=factor ***
+
+func (p *Parser) memoParseTermMul() *int {
+	// Part of a left recursive rule, so the result changes as the rule grows
+	return p.parseTermMul()
+}
+
+// parseTermMul parses a sub-rule of the "term" parser rule
+func (p *Parser) parseTermMul() *int {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### lhs=term ###
+	lhs := p.memoParseTerm()
+	if lhs == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### op=('*' | '/') ###
+	op := p.memoParseTermSub2()
+	if op == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### rhs=factor ###
+	rhs := p.memoParseFactor()
+	if rhs == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	value := *lhs * *rhs
+	if op.slashTok != nil {
+		value = *lhs / *rhs
+	}
+	return &value
+}
+
+// *** term - '*' | '/' ***
+
+type termSub2 struct {
+	starTok  *runtime.Token
+	slashTok *runtime.Token
+}
+
+func (p *Parser) memoParseTermSub2() *termSub2 {
+	pos := p.p.Pos()
+	if memo, ok := p.termSub2Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		termSub2, _ := memo.Result.(*termSub2)
+		return termSub2
+	}
+	termSub2 := p.parseTermSub2()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.termSub2Map[pos] = runtime.Memo{Result: termSub2, EndPos: p.p.Pos()}
+	return termSub2
+}
+
+// parseTermSub2 parses a sub-rule of the "term" parser rule
+func (p *Parser) parseTermSub2() *termSub2 {
+	// ### '*' ###
+	if starTok := p.p.TryMatchToken(STAR); starTok != nil {
+		return &termSub2{starTok: starTok}
+	}
+
+	// ### '/' ###
+	if slashTok := p.p.TryMatchToken(SLASH); slashTok != nil {
+		return &termSub2{slashTok: slashTok}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** factor ***
+
+func (p *Parser) memoParseFactor() *int {
+	pos := p.p.Pos()
+	if memo, ok := p.factorMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		factor, _ := memo.Result.(*int)
+		return factor
+	}
+	factor := p.ParseFactor()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.factorMap[pos] = runtime.Memo{Result: factor, EndPos: p.p.Pos()}
+	return factor
+}
+
+// ParseFactor parses the "factor" parser rule
+func (p *Parser) ParseFactor() *int {
+	// ### '-' factor ###
+	if factorNeg := p.memoParseFactorNeg(); factorNeg != nil {
+		return factorNeg
+	}
+
+	// ### '(' expr ')' ###
+	if factorParen := p.memoParseFactorParen(); factorParen != nil {
+		return factorParen
+	}
+
+	// ### NUM ###
+	if numTok := p.p.TryMatchToken(NUM); numTok != nil {
+		value, _ := strconv.Atoi(numTok.Data)
+		return &value
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** factor - '-' factor ***
+
+func (p *Parser) memoParseFactorNeg() *int {
+	pos := p.p.Pos()
+	if memo, ok := p.factorNegMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		factorNeg, _ := memo.Result.(*int)
+		return factorNeg
+	}
+	factorNeg := p.parseFactorNeg()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.factorNegMap[pos] = runtime.Memo{Result: factorNeg, EndPos: p.p.Pos()}
+	return factorNeg
+}
+
+// parseFactorNeg parses a sub-rule of the "factor" parser rule
+func (p *Parser) parseFactorNeg() *int {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '-' ###
+	dashTok := p.p.MatchTokenOrRollback(DASH, oldPos)
+	if dashTok == nil {
+		return nil
+	}
+
+	// ### factor ###
+	factor := p.memoParseFactor()
+	if factor == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	value := -*factor
+	return &value
+}
+
+// *** factor - '(' expr ')' ***
+
+func (p *Parser) memoParseFactorParen() *int {
+	pos := p.p.Pos()
+	if memo, ok := p.factorParenMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		factorParen, _ := memo.Result.(*int)
+		return factorParen
+	}
+	factorParen := p.parseFactorParen()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.factorParenMap[pos] = runtime.Memo{Result: factorParen, EndPos: p.p.Pos()}
+	return factorParen
+}
+
+// parseFactorParen parses a sub-rule of the "factor" parser rule
+func (p *Parser) parseFactorParen() *int {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '(' ###
+	lparenTok := p.p.MatchTokenOrRollback(LPAREN, oldPos)
+	if lparenTok == nil {
+		return nil
+	}
+
+	// ### expr ###
+	expr := p.memoParseExpr()
+	if expr == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ')' ###
+	rparenTok := p.p.MatchTokenOrRollback(RPAREN, oldPos)
+	if rparenTok == nil {
+		return nil
+	}
+
+	return expr
+}
@@ -0,0 +1,163 @@
+// Code generated by parsegen. DO NOT EDIT.
+
+package calc
+
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+const (
+	NUM runtime.TokenType = iota + runtime.EOF + 1
+	PLUS
+	DASH
+	STAR
+	SLASH
+	LPAREN
+	RPAREN
+)
+
+var tokenNames = map[runtime.TokenType]string{
+	NUM:    "NUM",
+	PLUS:   "'+'",
+	DASH:   "'-'",
+	STAR:   "'*'",
+	SLASH:  "'/'",
+	LPAREN: "'('",
+	RPAREN: "')'",
+}
+
+// Tokenizer splits its input into tokens by taking the longest match of
+// any lexer rule at each position
+type Tokenizer struct {
+	lex      *runtime.Lexer
+	matchers []func() bool
+}
+
+// NewTokenizer creates a new tokenizer that reads characters from the given lexer
+func NewTokenizer(lex *runtime.Lexer) *Tokenizer {
+	t := &Tokenizer{lex: lex}
+	t.matchers = []func() bool{
+		t.matchNum,
+		t.matchWs,
+		t.matchPlus,
+		t.matchDash,
+		t.matchStar,
+		t.matchSlash,
+		t.matchLparen,
+		t.matchRparen,
+	}
+	return t
+}
+
+// TokenName returns the name of a token type for use in syntax errors
+func (t *Tokenizer) TokenName(tt runtime.TokenType) string {
+	return tokenNames[tt]
+}
+
+// NextToken matches the next token in the input, discarding any skipped
+func (t *Tokenizer) NextToken(tok *runtime.Token) {
+	for {
+		switch t.lex.LongestMatch(t.matchers) {
+		case 0: // NUM
+			t.lex.BuildTokenData(NUM, tok)
+		case 2: // PLUS
+			t.lex.BuildToken(PLUS, tok)
+		case 3: // DASH
+			t.lex.BuildToken(DASH, tok)
+		case 4: // STAR
+			t.lex.BuildToken(STAR, tok)
+		case 5: // SLASH
+			t.lex.BuildToken(SLASH, tok)
+		case 6: // LPAREN
+			t.lex.BuildToken(LPAREN, tok)
+		case 7: // RPAREN
+			t.lex.BuildToken(RPAREN, tok)
+		case 1: // WS
+			t.lex.DiscardTokenData()
+			continue
+		default:
+			if t.lex.CurrChar() == runtime.EOFChar {
+				t.lex.BuildToken(runtime.EOF, tok)
+			} else {
+				t.lex.BuildIllegalToken(tok)
+			}
+		}
+		return
+	}
+}
+
+// *** NUM ***
+
+// matchNum matches the NUM lexer rule
+func (t *Tokenizer) matchNum() bool {
+	// [0-9]+
+	if !t.lex.MatchCharInRange('0', '9') {
+		return false
+	}
+	for t.lex.MatchCharInRange('0', '9') {
+	}
+
+	return true
+}
+
+// *** WS ***
+
+// matchWs matches the WS lexer rule
+func (t *Tokenizer) matchWs() bool {
+	// [\t\n\r ]+
+	if !t.lex.MatchCharInSeq("\t\n\r ") {
+		return false
+	}
+	for t.lex.MatchCharInSeq("\t\n\r ") {
+	}
+
+	return true
+}
+
+// *** PLUS ***
+
+// matchPlus matches the PLUS lexer rule
+func (t *Tokenizer) matchPlus() bool {
+	// '+'
+	return t.lex.MatchChar('+')
+}
+
+// *** DASH ***
+
+// matchDash matches the DASH lexer rule
+func (t *Tokenizer) matchDash() bool {
+	// '-'
+	return t.lex.MatchChar('-')
+}
+
+// *** STAR ***
+
+// matchStar matches the STAR lexer rule
+func (t *Tokenizer) matchStar() bool {
+	// '*'
+	return t.lex.MatchChar('*')
+}
+
+// *** SLASH ***
+
+// matchSlash matches the SLASH lexer rule
+func (t *Tokenizer) matchSlash() bool {
+	// '/'
+	return t.lex.MatchChar('/')
+}
+
+// *** LPAREN ***
+
+// matchLparen matches the LPAREN lexer rule
+func (t *Tokenizer) matchLparen() bool {
+	// '('
+	return t.lex.MatchChar('(')
+}
+
+// *** RPAREN ***
+
+// matchRparen matches the RPAREN lexer rule
+func (t *Tokenizer) matchRparen() bool {
+	// ')'
+	return t.lex.MatchChar(')')
+}
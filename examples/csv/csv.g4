@@ -0,0 +1,16 @@
+grammar CSV;
+
+// The last row needs no newline, so at the end of the input a row matches
+// nothing, which ends the loop (parsegen check warns about this)
+file: row* EOF;
+row: fields+=field (',' fields+=field)* NEWLINE?;
+field: value?;
+value: TEXT # Text
+     | STRING # Quoted
+     ;
+
+COMMA: ',';
+NEWLINE: '\r'? '\n';
+STRING: '"' ('""' | ~'"')* '"';
+TEXT: ~[,"\r\n#]+;
+COMMENT: '#' ~[\r\n]* ('\r'? '\n')? -> skip;
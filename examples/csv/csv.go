@@ -0,0 +1,56 @@
+// Package csv is an example CSV reader. Its grammar has no code blocks, so
+// the parser returns generated result types, which are read with a visitor.
+// It is generated in lossless mode, so comments are kept as trivia and the
+// concrete syntax tree of the input reproduces it exactly.
+package csv
+
+//go:generate go run github.com/nu11ptr/parsegen/cmd/parsegen generate -lossless csv.g4
+
+import (
+	"strings"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// Read returns the records of CSV input, one per line, leaving out comments
+func Read(input string) ([][]string, error) {
+	p := NewParser(runtime.NewParser(NewTokenizer(runtime.NewLexerFromString(input))))
+	file, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reader{records: [][]string{}}
+	Walk(r, file)
+	return r.records, nil
+}
+
+// reader collects the records of a file as it is walked
+type reader struct {
+	BaseVisitor
+	records [][]string
+}
+
+func (r *reader) VisitRow(row *Row) bool {
+	// The rows loop keeps the empty row it matches at the end of the input,
+	// which is not a record
+	if row.NewlineTok == nil && len(row.Fields) == 1 && row.Fields[0].Value == nil {
+		return false
+	}
+	r.records = append(r.records, []string{})
+	return true
+}
+
+func (r *reader) VisitField(field *Field) bool {
+	value := ""
+	switch v := field.Value.(type) {
+	case *ValueText:
+		value = v.TextTok.Data
+	case *ValueQuoted:
+		data := v.StringTok.Data
+		value = strings.ReplaceAll(data[1:len(data)-1], `""`, `"`)
+	}
+	record := &r.records[len(r.records)-1]
+	*record = append(*record, value)
+	return false
+}
@@ -0,0 +1,180 @@
+package csv_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/parsegen/examples/csv"
+	"github.com/nu11ptr/parsegen/pkg/interp"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const input = "# name, quote\nada,\"say \"\"hi\"\"\"\n# empty fields\n,x,\n"
+
+func newParser(input string) *csv.Parser {
+	return csv.NewParser(runtime.NewParser(csv.NewTokenizer(runtime.NewLexerFromString(input))))
+}
+
+func TestRead(t *testing.T) {
+	records, err := csv.Read(input)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ada", `say "hi"`}, {"", "x", ""}}, records)
+
+	// The last row can match nothing, so the rows loop must stop once it does
+	records, err = csv.Read("a,b\nc")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, records)
+
+	records, err = csv.Read("")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{}, records)
+
+	_, err = csv.Read("a,\"b\n")
+	assert.EqualError(t, err, "1:3: expected TEXT, STRING, ',', NEWLINE or EOF, found ILLEGAL \"\\\"b\\n\" (unterminated token)")
+}
+
+// The generated types hold each element of their rule
+func TestParse(t *testing.T) {
+	file, err := newParser(input).Parse()
+	require.NoError(t, err)
+	// The last row is the empty one at the end of the input
+	require.Len(t, file.Rows, 3)
+
+	row := file.Rows[0]
+	require.Len(t, row.Fields, 2)
+	assert.Equal(t, "ada", row.Fields[0].Value.(*csv.ValueText).TextTok.Data)
+	assert.Equal(t, `"say ""hi"""`, row.Fields[1].Value.(*csv.ValueQuoted).StringTok.Data)
+	assert.Equal(t, "# name, quote\n", row.Field.Value.(*csv.ValueText).TextTok.Trivia[0].Data)
+
+	row = file.Rows[1]
+	require.Len(t, row.Fields, 3)
+	assert.Nil(t, row.Fields[0].Value)
+	assert.Nil(t, row.Fields[2].Value)
+
+	row = file.Rows[2]
+	require.Len(t, row.Fields, 1)
+	assert.Nil(t, row.Fields[0].Value)
+	assert.Nil(t, row.NewlineTok)
+}
+
+// counter counts the nodes and tokens a walk reaches
+type counter struct {
+	csv.BaseListener
+	rows, texts, tokens int
+	events              []string
+}
+
+func (c *counter) EnterRow(*csv.Row) { c.rows++; c.events = append(c.events, "enter row") }
+
+func (c *counter) ExitRow(*csv.Row) { c.events = append(c.events, "exit row") }
+
+func (c *counter) EnterValueText(*csv.ValueText) { c.texts++ }
+
+func (c *counter) VisitToken(*runtime.Token) { c.tokens++ }
+
+func TestWalkListener(t *testing.T) {
+	file, err := newParser(input).Parse()
+	require.NoError(t, err)
+
+	c := &counter{}
+	csv.WalkListener(c, file)
+	assert.Equal(t, 3, c.rows)
+	assert.Equal(t, 2, c.texts)
+	// ada , "say ""hi""" \n , x , \n EOF - comments are trivia
+	assert.Equal(t, 9, c.tokens)
+	assert.Equal(t, []string{"enter row", "exit row", "enter row", "exit row", "enter row", "exit row"}, c.events)
+}
+
+// skipper visits rows without going below them
+type skipper struct {
+	csv.BaseVisitor
+	rows, fields int
+}
+
+func (s *skipper) VisitRow(*csv.Row) bool { s.rows++; return s.rows > 1 }
+
+func (s *skipper) VisitField(*csv.Field) bool { s.fields++; return true }
+
+func TestWalk(t *testing.T) {
+	file, err := newParser(input).Parse()
+	require.NoError(t, err)
+
+	s := &skipper{}
+	csv.Walk(s, file)
+	assert.Equal(t, 3, s.rows)
+	// Only the fields of the rows after the first are visited
+	assert.Equal(t, 4, s.fields)
+}
+
+// The concrete syntax tree covers the whole input, trivia included
+func TestParseCST(t *testing.T) {
+	for _, input := range []string{input, "a\n# trailing comment", "a,b", "\n\n", "", "\ufeffa,b\n", "\ufeff"} {
+		cst, err := newParser(input).ParseCST()
+		require.NoError(t, err)
+		assert.Equal(t, input, cst.Text())
+	}
+
+	// A byte order mark is trivia of the first token, which keeps its offset
+	file, err := newParser("\ufeffa\n").Parse()
+	require.NoError(t, err)
+	tok := file.Rows[0].Field.Value.(*csv.ValueText).TextTok
+	assert.Equal(t, 3, tok.StartOffset)
+	require.Len(t, tok.Trivia, 1)
+	assert.Equal(t, runtime.BOM, tok.Trivia[0].Type)
+
+	cst, err := newParser("a,b\n").ParseCST()
+	require.NoError(t, err)
+	assert.Equal(t, `file:
+   └──row:
+      └──field:
+         └──value.Text:
+            └──"a"
+      └──row.sub1:
+         └──","
+         └──field:
+            └──value.Text:
+               └──"b"
+      └──"\n"
+   └──row:
+      └──field:
+   └──""
+`, cst.String())
+}
+
+// withoutSubRules replaces the sub-rule nodes of a tree with their children,
+// as the interpreter has no nodes for sub-rules
+func withoutSubRules(node *runtime.CSTNode) []*runtime.CSTNode {
+	children := []*runtime.CSTNode{}
+	for _, child := range node.Children {
+		children = append(children, withoutSubRules(child)...)
+	}
+	if strings.Contains(node.Name, ".sub") {
+		return children
+	}
+	return []*runtime.CSTNode{{Name: node.Name, Token: node.Token, Children: children}}
+}
+
+// The interpreter and the generated parser match the same way, loops that
+// stop after matching nothing included, so they build the same tree
+func TestInterp(t *testing.T) {
+	lex, err := runtime.NewLexerFromFile("csv.g4")
+	require.NoError(t, err)
+	top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+	in, err := interp.New(top)
+	require.NoError(t, err)
+
+	for _, input := range []string{input, "a,b\nc", "a,\"\"\"b\"\n\n", "", "# only a comment", "\ufeffa"} {
+		expected, err := newParser(input).ParseCST()
+		require.NoError(t, err)
+		actual, err := in.ParseString("", input)
+		require.NoError(t, err)
+
+		assert.Equal(t, withoutSubRules(expected)[0].String(), actual.String(), "%q", input)
+		assert.Equal(t, input, actual.Text())
+	}
+}
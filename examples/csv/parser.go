@@ -0,0 +1,574 @@
+// Code generated by parsegen from csv.g4. DO NOT EDIT.
+
+package csv
+
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// Parser is a packrat parser that memoizes the result of each rule
+type Parser struct {
+	p *runtime.Parser
+
+	fileMap        map[int]runtime.Memo
+	rowMap         map[int]runtime.Memo
+	rowSub1Map     map[int]runtime.Memo
+	fieldMap       map[int]runtime.Memo
+	valueMap       map[int]runtime.Memo
+	valueTextMap   map[int]runtime.Memo
+	valueQuotedMap map[int]runtime.Memo
+}
+
+// NewParser creates a new parser that reads tokens from the given runtime parser
+func NewParser(p *runtime.Parser) *Parser {
+	return &Parser{
+		p:              p,
+		fileMap:        make(map[int]runtime.Memo, 8),
+		rowMap:         make(map[int]runtime.Memo, 8),
+		rowSub1Map:     make(map[int]runtime.Memo, 8),
+		fieldMap:       make(map[int]runtime.Memo, 8),
+		valueMap:       make(map[int]runtime.Memo, 8),
+		valueTextMap:   make(map[int]runtime.Memo, 8),
+		valueQuotedMap: make(map[int]runtime.Memo, 8),
+	}
+}
+
+// Parse parses the input starting from the "file" parser rule. If
+// parsing fails, the error describes the farthest point reached
+func (p *Parser) Parse() (*File, error) {
+	file := p.ParseFile()
+	if file == nil {
+		return nil, p.p.Err()
+	}
+	return file, nil
+}
+
+// ParseCST parses the input like Parse, but returns its concrete syntax tree.
+// Trivia at the end of the input belongs to the EOF token, which is added to
+// the tree when the start rule doesn't match EOF itself
+func (p *Parser) ParseCST() (*runtime.CSTNode, error) {
+	file, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	cst := CST(file)
+	if tok := p.p.CurrToken(); tok.Type == runtime.EOF && len(tok.Trivia) > 0 {
+		cst.Children = append(cst.Children, &runtime.CSTNode{Token: tok})
+	}
+	return cst, nil
+}
+
+// *** file ***
+
+// File is the result of the "file" parser rule
+type File struct {
+	Rows   []*Row
+	EofTok *runtime.Token
+}
+
+func (n *File) eachChild(fn func(child interface{})) {
+	for _, child := range n.Rows {
+		fn(child)
+	}
+	if n.EofTok != nil {
+		fn(n.EofTok)
+	}
+}
+
+func (p *Parser) memoParseFile() *File {
+	pos := p.p.Pos()
+	if memo, ok := p.fileMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		file, _ := memo.Result.(*File)
+		return file
+	}
+	file := p.ParseFile()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.fileMap[pos] = runtime.Memo{Result: file, EndPos: p.p.Pos()}
+	return file
+}
+
+// ParseFile parses the "file" parser rule
+func (p *Parser) ParseFile() *File {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### row* ###
+	rows := []*Row{}
+	for {
+		loopPos := p.p.Pos()
+		row := p.memoParseRow()
+		if row == nil {
+			break
+		}
+		rows = append(rows, row)
+		if p.p.Pos() == loopPos {
+			// No progress made - stop, otherwise this would never end
+			break
+		}
+	}
+
+	// ### EOF ###
+	eofTok := p.p.MatchTokenOrRollback(runtime.EOF, oldPos)
+	if eofTok == nil {
+		return nil
+	}
+
+	return &File{Rows: rows, EofTok: eofTok}
+}
+
+// *** row ***
+
+// Row is the result of the "row" parser rule
+type Row struct {
+	Field      *Field
+	RowSub1s   []*RowSub1
+	NewlineTok *runtime.Token
+	Fields     []*Field
+}
+
+func (n *Row) eachChild(fn func(child interface{})) {
+	if n.Field != nil {
+		fn(n.Field)
+	}
+	for _, child := range n.RowSub1s {
+		fn(child)
+	}
+	if n.NewlineTok != nil {
+		fn(n.NewlineTok)
+	}
+}
+
+func (p *Parser) memoParseRow() *Row {
+	pos := p.p.Pos()
+	if memo, ok := p.rowMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		row, _ := memo.Result.(*Row)
+		return row
+	}
+	row := p.ParseRow()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.rowMap[pos] = runtime.Memo{Result: row, EndPos: p.p.Pos()}
+	return row
+}
+
+// ParseRow parses the "row" parser rule
+func (p *Parser) ParseRow() *Row {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	fields := []*Field{}
+
+	// ### fields+=field ###
+	field := p.memoParseField()
+	if field == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+	fields = append(fields, field)
+
+	// ### (',' fields+=field)* ###
+	rowSub1s := []*RowSub1{}
+	for {
+		rowSub1 := p.memoParseRowSub1()
+		if rowSub1 == nil {
+			break
+		}
+		rowSub1s = append(rowSub1s, rowSub1)
+	}
+	for _, rowSub1 := range rowSub1s {
+		fields = append(fields, rowSub1.Fields...)
+	}
+
+	// ### NEWLINE? ###
+	newlineTok := p.p.TryMatchToken(NEWLINE)
+
+	return &Row{Field: field, RowSub1s: rowSub1s, NewlineTok: newlineTok, Fields: fields}
+}
+
+// *** row - ',' fields+=field ***
+
+// RowSub1 is the result of a sub-rule of the "row" parser rule
+type RowSub1 struct {
+	CommaTok *runtime.Token
+	Field    *Field
+	Fields   []*Field
+}
+
+func (n *RowSub1) eachChild(fn func(child interface{})) {
+	if n.CommaTok != nil {
+		fn(n.CommaTok)
+	}
+	if n.Field != nil {
+		fn(n.Field)
+	}
+}
+
+func (p *Parser) memoParseRowSub1() *RowSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.rowSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		rowSub1, _ := memo.Result.(*RowSub1)
+		return rowSub1
+	}
+	rowSub1 := p.parseRowSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.rowSub1Map[pos] = runtime.Memo{Result: rowSub1, EndPos: p.p.Pos()}
+	return rowSub1
+}
+
+// parseRowSub1 parses a sub-rule of the "row" parser rule
+func (p *Parser) parseRowSub1() *RowSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	fields := []*Field{}
+
+	// ### ',' ###
+	commaTok := p.p.MatchTokenOrRollback(COMMA, oldPos)
+	if commaTok == nil {
+		return nil
+	}
+
+	// ### fields+=field ###
+	field := p.memoParseField()
+	if field == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+	fields = append(fields, field)
+
+	return &RowSub1{CommaTok: commaTok, Field: field, Fields: fields}
+}
+
+// *** field ***
+
+// Field is the result of the "field" parser rule
+type Field struct {
+	Value Value
+}
+
+func (n *Field) eachChild(fn func(child interface{})) {
+	if n.Value != nil {
+		fn(n.Value)
+	}
+}
+
+func (p *Parser) memoParseField() *Field {
+	pos := p.p.Pos()
+	if memo, ok := p.fieldMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		field, _ := memo.Result.(*Field)
+		return field
+	}
+	field := p.ParseField()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.fieldMap[pos] = runtime.Memo{Result: field, EndPos: p.p.Pos()}
+	return field
+}
+
+// ParseField parses the "field" parser rule
+func (p *Parser) ParseField() *Field {
+	// ### value? ###
+	value := p.memoParseValue()
+
+	return &Field{Value: value}
+}
+
+// *** value ***
+
+// Value is the result of the "value" parser rule.
+// Each of its alternatives has a type implementing it
+type Value interface {
+	Node
+	isValue()
+}
+
+func (p *Parser) memoParseValue() Value {
+	pos := p.p.Pos()
+	if memo, ok := p.valueMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		value, _ := memo.Result.(Value)
+		return value
+	}
+	value := p.ParseValue()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.valueMap[pos] = runtime.Memo{Result: value, EndPos: p.p.Pos()}
+	return value
+}
+
+// ParseValue parses the "value" parser rule
+func (p *Parser) ParseValue() Value {
+	// ### TEXT ###
+	if valueText := p.memoParseValueText(); valueText != nil {
+		return valueText
+	}
+
+	// ### STRING ###
+	if valueQuoted := p.memoParseValueQuoted(); valueQuoted != nil {
+		return valueQuoted
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** value - TEXT ***
+
+// ValueText is the result of the Text alternative of the "value" parser rule
+type ValueText struct {
+	TextTok *runtime.Token
+}
+
+func (*ValueText) isValue() {}
+
+func (n *ValueText) eachChild(fn func(child interface{})) {
+	if n.TextTok != nil {
+		fn(n.TextTok)
+	}
+}
+
+func (p *Parser) memoParseValueText() *ValueText {
+	pos := p.p.Pos()
+	if memo, ok := p.valueTextMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		valueText, _ := memo.Result.(*ValueText)
+		return valueText
+	}
+	valueText := p.parseValueText()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.valueTextMap[pos] = runtime.Memo{Result: valueText, EndPos: p.p.Pos()}
+	return valueText
+}
+
+// parseValueText parses a sub-rule of the "value" parser rule
+func (p *Parser) parseValueText() *ValueText {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### TEXT ###
+	textTok := p.p.MatchTokenOrRollback(TEXT, oldPos)
+	if textTok == nil {
+		return nil
+	}
+
+	return &ValueText{TextTok: textTok}
+}
+
+// *** value - STRING ***
+
+// ValueQuoted is the result of the Quoted alternative of the "value" parser rule
+type ValueQuoted struct {
+	StringTok *runtime.Token
+}
+
+func (*ValueQuoted) isValue() {}
+
+func (n *ValueQuoted) eachChild(fn func(child interface{})) {
+	if n.StringTok != nil {
+		fn(n.StringTok)
+	}
+}
+
+func (p *Parser) memoParseValueQuoted() *ValueQuoted {
+	pos := p.p.Pos()
+	if memo, ok := p.valueQuotedMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		valueQuoted, _ := memo.Result.(*ValueQuoted)
+		return valueQuoted
+	}
+	valueQuoted := p.parseValueQuoted()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.valueQuotedMap[pos] = runtime.Memo{Result: valueQuoted, EndPos: p.p.Pos()}
+	return valueQuoted
+}
+
+// parseValueQuoted parses a sub-rule of the "value" parser rule
+func (p *Parser) parseValueQuoted() *ValueQuoted {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### STRING ###
+	stringTok := p.p.MatchTokenOrRollback(STRING, oldPos)
+	if stringTok == nil {
+		return nil
+	}
+
+	return &ValueQuoted{StringTok: stringTok}
+}
+
+// *** Visitor ***
+
+// Node is implemented by every generated type of the parse tree
+type Node interface {
+	// eachChild calls fn with each token and node directly below the node in order
+	eachChild(fn func(child interface{}))
+}
+
+// Visitor visits the nodes of a parse tree in depth first order. A visit method
+// returns whether the nodes below the visited node are visited. Sub-rules without
+// a label have no visit method, but the nodes below them are visited
+type Visitor interface {
+	VisitFile(n *File) bool
+	VisitRow(n *Row) bool
+	VisitField(n *Field) bool
+	VisitValueText(n *ValueText) bool
+	VisitValueQuoted(n *ValueQuoted) bool
+	VisitToken(tok *runtime.Token)
+}
+
+// BaseVisitor visits every node without doing anything. Embed it in a visitor
+// to only implement the visit methods needed
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitFile(*File) bool               { return true }
+func (BaseVisitor) VisitRow(*Row) bool                 { return true }
+func (BaseVisitor) VisitField(*Field) bool             { return true }
+func (BaseVisitor) VisitValueText(*ValueText) bool     { return true }
+func (BaseVisitor) VisitValueQuoted(*ValueQuoted) bool { return true }
+func (BaseVisitor) VisitToken(*runtime.Token)          {}
+
+// Walk visits a node and the nodes and tokens below it with a visitor
+func Walk(v Visitor, node Node) {
+	if !visitNode(v, node) {
+		return
+	}
+	node.eachChild(func(child interface{}) {
+		switch child := child.(type) {
+		case *runtime.Token:
+			v.VisitToken(child)
+		case Node:
+			Walk(v, child)
+		}
+	})
+}
+
+func visitNode(v Visitor, node Node) bool {
+	switch n := node.(type) {
+	case *File:
+		return v.VisitFile(n)
+	case *Row:
+		return v.VisitRow(n)
+	case *Field:
+		return v.VisitField(n)
+	case *ValueText:
+		return v.VisitValueText(n)
+	case *ValueQuoted:
+		return v.VisitValueQuoted(n)
+	}
+	return true
+}
+
+// Listener is notified as a walk enters and exits each node of a parse tree. As
+// with visitors, sub-rules without a label have no methods of their own
+type Listener interface {
+	EnterFile(n *File)
+	ExitFile(n *File)
+	EnterRow(n *Row)
+	ExitRow(n *Row)
+	EnterField(n *Field)
+	ExitField(n *Field)
+	EnterValueText(n *ValueText)
+	ExitValueText(n *ValueText)
+	EnterValueQuoted(n *ValueQuoted)
+	ExitValueQuoted(n *ValueQuoted)
+	VisitToken(tok *runtime.Token)
+}
+
+// BaseListener ignores everything. Embed it in a listener to only implement the
+// methods needed
+type BaseListener struct{}
+
+func (BaseListener) EnterFile(*File)               {}
+func (BaseListener) ExitFile(*File)                {}
+func (BaseListener) EnterRow(*Row)                 {}
+func (BaseListener) ExitRow(*Row)                  {}
+func (BaseListener) EnterField(*Field)             {}
+func (BaseListener) ExitField(*Field)              {}
+func (BaseListener) EnterValueText(*ValueText)     {}
+func (BaseListener) ExitValueText(*ValueText)      {}
+func (BaseListener) EnterValueQuoted(*ValueQuoted) {}
+func (BaseListener) ExitValueQuoted(*ValueQuoted)  {}
+func (BaseListener) VisitToken(*runtime.Token)     {}
+
+// WalkListener walks a node and the nodes and tokens below it, notifying a
+// listener as it goes
+func WalkListener(l Listener, node Node) {
+	enterNode(l, node)
+	node.eachChild(func(child interface{}) {
+		switch child := child.(type) {
+		case *runtime.Token:
+			l.VisitToken(child)
+		case Node:
+			WalkListener(l, child)
+		}
+	})
+	exitNode(l, node)
+}
+
+func enterNode(l Listener, node Node) {
+	switch n := node.(type) {
+	case *File:
+		l.EnterFile(n)
+	case *Row:
+		l.EnterRow(n)
+	case *Field:
+		l.EnterField(n)
+	case *ValueText:
+		l.EnterValueText(n)
+	case *ValueQuoted:
+		l.EnterValueQuoted(n)
+	}
+}
+
+func exitNode(l Listener, node Node) {
+	switch n := node.(type) {
+	case *File:
+		l.ExitFile(n)
+	case *Row:
+		l.ExitRow(n)
+	case *Field:
+		l.ExitField(n)
+	case *ValueText:
+		l.ExitValueText(n)
+	case *ValueQuoted:
+		l.ExitValueQuoted(n)
+	}
+}
+
+// *** CST ***
+
+// CST builds the concrete syntax tree of a node. It has a node for the node
+// itself and each node and token below it
+func CST(node Node) *runtime.CSTNode {
+	cst := &runtime.CSTNode{Name: nodeName(node)}
+	node.eachChild(func(child interface{}) {
+		switch child := child.(type) {
+		case *runtime.Token:
+			cst.Children = append(cst.Children, &runtime.CSTNode{Token: child})
+		case Node:
+			cst.Children = append(cst.Children, CST(child))
+		}
+	})
+	return cst
+}
+
+func nodeName(node Node) string {
+	switch node.(type) {
+	case *File:
+		return "file"
+	case *Row:
+		return "row"
+	case *RowSub1:
+		return "row.sub1"
+	case *Field:
+		return "field"
+	case *ValueText:
+		return "value.Text"
+	case *ValueQuoted:
+		return "value.Quoted"
+	}
+	return ""
+}
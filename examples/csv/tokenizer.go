@@ -0,0 +1,181 @@
+// Code generated by parsegen. DO NOT EDIT.
+
+package csv
+
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+const (
+	COMMA runtime.TokenType = iota + runtime.EOF + 1
+	NEWLINE
+	STRING
+	TEXT
+	COMMENT
+)
+
+var tokenNames = map[runtime.TokenType]string{
+	runtime.BOM: "BOM",
+	COMMA:       "','",
+	NEWLINE:     "NEWLINE",
+	STRING:      "STRING",
+	TEXT:        "TEXT",
+	COMMENT:     "COMMENT",
+}
+
+// Tokenizer splits its input into tokens by taking the longest match of
+// any lexer rule at each position
+type Tokenizer struct {
+	lex      *runtime.Lexer
+	matchers []func() bool
+}
+
+// NewTokenizer creates a new tokenizer that reads characters from the given lexer
+func NewTokenizer(lex *runtime.Lexer) *Tokenizer {
+	lex.KeepBOM()
+	t := &Tokenizer{lex: lex}
+	t.matchers = []func() bool{
+		t.matchComma,
+		t.matchNewline,
+		t.matchString,
+		t.matchText,
+		t.matchComment,
+	}
+	return t
+}
+
+// TokenName returns the name of a token type for use in syntax errors
+func (t *Tokenizer) TokenName(tt runtime.TokenType) string {
+	return tokenNames[tt]
+}
+
+// NextToken matches the next token in the input, keeping any skipped as trivia
+func (t *Tokenizer) NextToken(tok *runtime.Token) {
+	for {
+		switch t.lex.LongestMatch(t.matchers) {
+		case 0: // COMMA
+			t.lex.BuildTokenData(COMMA, tok)
+		case 1: // NEWLINE
+			t.lex.BuildTokenData(NEWLINE, tok)
+		case 2: // STRING
+			t.lex.BuildTokenData(STRING, tok)
+		case 3: // TEXT
+			t.lex.BuildTokenData(TEXT, tok)
+		case 4: // COMMENT
+			t.lex.BuildTrivia(COMMENT)
+			continue
+		default:
+			if t.lex.CurrChar() == runtime.EOFChar {
+				t.lex.BuildToken(runtime.EOF, tok)
+			} else {
+				t.lex.BuildIllegalToken(tok)
+			}
+		}
+		return
+	}
+}
+
+// *** COMMA ***
+
+// matchComma matches the COMMA lexer rule
+func (t *Tokenizer) matchComma() bool {
+	// ','
+	return t.lex.MatchChar(',')
+}
+
+// *** NEWLINE ***
+
+// matchNewline matches the NEWLINE lexer rule
+func (t *Tokenizer) matchNewline() bool {
+	pos := t.lex.Pos()
+
+	// '\r'?
+	t.lex.MatchChar('\r')
+
+	// '\n'
+	if !t.lex.MatchChar('\n') {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
+
+// *** STRING ***
+
+// matchString matches the STRING lexer rule
+func (t *Tokenizer) matchString() bool {
+	pos := t.lex.Pos()
+
+	// '"'
+	if !t.lex.MatchChar('"') {
+		return false
+	}
+
+	// ('""' | ~'"')*
+	for t.matchStringSub1() {
+	}
+
+	// '"'
+	if !t.lex.MatchChar('"') {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
+
+// matchStringSub1 matches part of the STRING lexer rule
+func (t *Tokenizer) matchStringSub1() bool {
+	// '""' | ~'"'
+	return t.lex.MatchSeq("\"\"") || t.lex.MatchCharExcept('"')
+}
+
+// *** TEXT ***
+
+// matchText matches the TEXT lexer rule
+func (t *Tokenizer) matchText() bool {
+	// ~[\n\r"#,]+
+	if !t.lex.MatchCharExceptInSeq("\n\r\"#,") {
+		return false
+	}
+	for t.lex.MatchCharExceptInSeq("\n\r\"#,") {
+	}
+
+	return true
+}
+
+// *** COMMENT ***
+
+// matchComment matches the COMMENT lexer rule
+func (t *Tokenizer) matchComment() bool {
+	// '#'
+	if !t.lex.MatchChar('#') {
+		return false
+	}
+
+	// ~[\n\r]*
+	for t.lex.MatchCharExceptInSeq("\n\r") {
+	}
+
+	// ('\r'? '\n')?
+	t.matchCommentSub1()
+
+	return true
+}
+
+// matchCommentSub1 matches part of the COMMENT lexer rule
+func (t *Tokenizer) matchCommentSub1() bool {
+	pos := t.lex.Pos()
+
+	// '\r'?
+	t.lex.MatchChar('\r')
+
+	// '\n'
+	if !t.lex.MatchChar('\n') {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
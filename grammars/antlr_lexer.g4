@@ -10,16 +10,14 @@ RULE_NAME: [a-z] NAME;
 TOKEN_NAME: [A-Z] NAME;
 
 TOKEN_LIT
-	: '\'' ('\\\'' | ~'\'')+ '\''
+	: '\'' ('\\' . | ~['\\])+ '\''
 	; // TODO: Handle escape chars as fragment
 
 // *** Skip ***
 
 COMMENT: '//' ~[\r\n]* -> skip;
 
-ML_COMMENT
-	: '/*' .*? '*/' -> skip
-	; // TODO: Will we support reluctant matchers?
+ML_COMMENT: '/*' .*? '*/' -> skip;
 
 WS: [ \t\r\n\f]+ -> skip;
 
@@ -33,6 +31,20 @@ PUSH_ACTION: 'pushMode';
 
 POP_ACTION: 'popMode';
 
+CHANNEL_ACTION: 'channel';
+
+MODE: 'mode';
+
+GRAMMAR: 'grammar';
+
+PARSER: 'parser';
+
+LEXER: 'lexer';
+
+OPTIONS: 'options';
+
+IMPORT: 'import';
+
 // *** Basic Sequences ****
 
 RARROW: '->';
@@ -59,6 +71,20 @@ TILDE: '~';
 
 COMMA: ',';
 
+EQUALS: '=';
+
+PLUS_ASSIGN: '+=';
+
+POUND: '#';
+
+AMP: '&';
+
+BANG: '!';
+
+LBRACE: '{';
+
+RBRACE: '}';
+
 LBRACK: '[' -> pushMode(CHAR_CLASS);
 
 // *** Lexer: CHAR_CLASS ***
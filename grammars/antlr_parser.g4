@@ -5,15 +5,40 @@ options {
 	tokenVocab = antlr_lexer;
 }
 
-top_level: (parse_rule | lex_rule)* EOF;
+top_level: grammar_decl? (options_spec | imports)* (parse_rule | lex_rule | lex_mode)* EOF;
+
+// *** Grammar header ***
+
+grammar_decl: grammar_type? 'grammar' ident ';';
+
+grammar_type: 'parser' | 'lexer';
+
+options_spec: 'options' '{' option* '}';
+
+option: ident '=' option_value ';';
+
+option_value: ident ('.' ident)* | TOKEN_LIT;
+
+imports: 'import' ident (',' ident)* ';';
+
+ident: RULE_NAME | TOKEN_NAME;
 
 // *** Parser parser ***
 
 parse_rule: RULE_NAME ':' rule_body ';';
 
-rule_body: rule_sect+ ('|' rule_sect+)*;
+rule_body: rule_sect+ alt_label? ('|' rule_sect+ alt_label?)*;
+
+alt_label: '#' ident;
+
+rule_sect
+	: predicate rule_part suffix?       # Predicate
+	| element_label? rule_part suffix? # Element
+	;
 
-rule_sect: rule_part suffix?;
+predicate: '&' | '!';
+
+element_label: ident ('=' | '+=');
 
 rule_part
 	: '(' rule_body ')'
@@ -30,13 +55,21 @@ lex_rule
 	: 'fragment'? TOKEN_NAME ':' lex_rule_body ('->' lex_actions)? ';'
 	;
 
+lex_mode: 'mode' TOKEN_NAME ';';
+
 lex_actions: lex_action (',' lex_action)*;
 
-lex_action: 'skip' | 'pushMode' '(' TOKEN_NAME ')' | 'popMode';
+lex_action
+	: 'skip'
+	| 'pushMode' '(' TOKEN_NAME ')'
+	| 'popMode'
+	| 'channel' '(' TOKEN_NAME ')'
+	;
 
 lex_rule_body: lex_rule_sect+ ('|' lex_rule_sect+)*;
 
-lex_rule_sect: '~'? lex_rule_part suffix?;
+// A question mark after the suffix makes it non-greedy
+lex_rule_sect: '~'? lex_rule_part (suffix '?'?)?;
 
 lex_rule_part
 	: '(' lex_rule_body ')'
@@ -46,7 +79,8 @@ lex_rule_part
 	| char_set
 	;
 
-char_set: '[' (char_lit | char_range)+ ']';
+// Ranges must come first as a range starts with a char_lit
+char_set: '[' (char_range | char_lit)+ ']';
 
 char_lit: UNICODE_ESCAPE_CHAR | ESCAPE_CHAR | BASIC_CHAR;
 
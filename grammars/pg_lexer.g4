@@ -13,7 +13,7 @@ CODE_BLOCK: '{{' ~'}}'+ '}}';
 
 COMMENT: '//' ~[\r\n]* -> skip;
 
-ML_COMMENT: '/*' ~'*/'* '*/' -> skip;
+ML_COMMENT: '/*' .*? '*/' -> skip;
 
 WS: [ \t\r\n\f]+ -> skip;
 
@@ -23,6 +23,8 @@ PARSER: 'parser';
 
 CODE: 'code';
 
+MEMBERS: 'members';
+
 // *** Basic Sequences ****
 
 EQUALS: '=';
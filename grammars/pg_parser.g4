@@ -11,4 +11,7 @@ parser_decl: 'parser' '=' STRING;
 
 code_blocks: 'code' '(' STRING ')' '{' code_block* '}';
 
-code_block: RULE_NAME TYPE? CODE_BLOCK;
\ No newline at end of file
+code_block
+	: RULE_NAME TYPE? CODE_BLOCK # Rule
+	| 'members' CODE_BLOCK       # Members
+	;
@@ -0,0 +1,146 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// CharRange is an inclusive range of chars
+type CharRange struct {
+	Lo, Hi rune
+}
+
+// CharSet is a sorted list of non-overlapping, non-adjacent char ranges. The
+// zero value is the empty set
+type CharSet []CharRange
+
+// NewCharSet sorts and merges the given ranges into a char set
+func NewCharSet(ranges ...CharRange) CharSet {
+	sorted := append([]CharRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Lo < sorted[j].Lo })
+
+	set := CharSet{}
+	for _, r := range sorted {
+		if last := len(set) - 1; last >= 0 && r.Lo <= set[last].Hi+1 {
+			if r.Hi > set[last].Hi {
+				set[last].Hi = r.Hi
+			}
+			continue
+		}
+		set = append(set, r)
+	}
+	return set
+}
+
+// Contains returns true if the given char is in the set
+func (s CharSet) Contains(ch rune) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i].Hi >= ch })
+	return i < len(s) && s[i].Lo <= ch
+}
+
+// Union returns a new set with the chars of both sets
+func (s CharSet) Union(other CharSet) CharSet {
+	return NewCharSet(append(append([]CharRange{}, s...), other...)...)
+}
+
+// Complement returns a new set with every valid char not in this set
+func (s CharSet) Complement() CharSet {
+	set := CharSet{}
+	next := rune(0)
+	for _, r := range s {
+		if r.Lo > next {
+			set = append(set, CharRange{next, r.Lo - 1})
+		}
+		next = r.Hi + 1
+	}
+	if next <= unicode.MaxRune {
+		set = append(set, CharRange{next, unicode.MaxRune})
+	}
+	return set
+}
+
+// String returns the set in char class syntax (without the brackets). Ranges
+// of up to three chars are written out char by char as they read better
+func (s CharSet) String() string {
+	buff := strings.Builder{}
+	for _, r := range s {
+		if r.Hi-r.Lo < 3 {
+			for ch := r.Lo; ch <= r.Hi; ch++ {
+				buff.WriteString(escapeChar(ch))
+			}
+			continue
+		}
+		buff.WriteString(escapeChar(r.Lo))
+		buff.WriteByte('-')
+		buff.WriteString(escapeChar(r.Hi))
+	}
+	return buff.String()
+}
+
+var charEscapes = map[rune]string{
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+	'\b': `\b`,
+	'\f': `\f`,
+	']':  `\]`,
+	'\\': `\\`,
+	'-':  `\-`,
+}
+
+var charUnescapes = map[rune]rune{
+	'n': '\n',
+	'r': '\r',
+	't': '\t',
+	'b': '\b',
+	'f': '\f',
+}
+
+func escapeChar(ch rune) string {
+	if esc, ok := charEscapes[ch]; ok {
+		return esc
+	}
+	if unicode.IsPrint(ch) {
+		return string(ch)
+	}
+	if ch > 0xFFFF {
+		return fmt.Sprintf(`\u{%X}`, ch)
+	}
+	return fmt.Sprintf(`\u%04X`, ch)
+}
+
+// decodeChar decodes a single, possibly escaped, char from a char class
+func decodeChar(tok *runtime.Token) (rune, error) {
+	data := tok.Data
+
+	switch tok.Type {
+	case token.UNICODE_ESCAPE_CHAR:
+		// \uXXXX or \u{XXXXXX}
+		if !strings.HasPrefix(data, `\u`) {
+			return 0, fmt.Errorf("invalid unicode escape: %s", data)
+		}
+		hex := strings.TrimSuffix(strings.TrimPrefix(data[2:], "{"), "}")
+		ch, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || ch > unicode.MaxRune {
+			return 0, fmt.Errorf("invalid unicode escape: %s", data)
+		}
+		return rune(ch), nil
+	case token.ESCAPE_CHAR:
+		// Unknown escapes are just the char itself (ex: \] or \-)
+		ch, _ := utf8.DecodeRuneInString(data[1:])
+		if unescaped, ok := charUnescapes[ch]; ok {
+			return unescaped, nil
+		}
+		return ch, nil
+	default:
+		ch, _ := utf8.DecodeRuneInString(data)
+		return ch, nil
+	}
+}
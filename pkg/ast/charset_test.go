@@ -0,0 +1,102 @@
+package ast_test
+
+import (
+	"testing"
+	"unicode"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCharSet(t *testing.T) {
+	set := ast.NewCharSet(
+		ast.CharRange{Lo: 'x', Hi: 'z'}, ast.CharRange{Lo: 'a', Hi: 'f'},
+		ast.CharRange{Lo: 'c', Hi: 'h'}, ast.CharRange{Lo: 'i', Hi: 'i'},
+	)
+	assert.Equal(t, ast.CharSet{{Lo: 'a', Hi: 'i'}, {Lo: 'x', Hi: 'z'}}, set)
+
+	assert.True(t, set.Contains('a'))
+	assert.True(t, set.Contains('i'))
+	assert.False(t, set.Contains('j'))
+	assert.False(t, set.Contains('0'))
+
+	assert.Equal(t, ast.CharSet{{Lo: 0, Hi: 'a' - 1}, {Lo: 'j', Hi: 'w'}, {Lo: '{', Hi: unicode.MaxRune}},
+		set.Complement())
+	assert.Equal(t, set, set.Complement().Complement())
+	assert.Equal(t, ast.CharSet{{Lo: 0, Hi: unicode.MaxRune}}, ast.CharSet{}.Complement())
+
+	assert.Equal(t, ast.CharSet{{Lo: '0', Hi: '9'}, {Lo: 'a', Hi: 'i'}, {Lo: 'x', Hi: 'z'}},
+		set.Union(ast.NewCharSet(ast.CharRange{Lo: '0', Hi: '9'})))
+}
+
+func TestCharSetString(t *testing.T) {
+	set := ast.NewCharSet(
+		ast.CharRange{Lo: 'a', Hi: 'z'}, ast.CharRange{Lo: '\t', Hi: '\n'}, ast.CharRange{Lo: ' ', Hi: ' '},
+		ast.CharRange{Lo: ']', Hi: ']'}, ast.CharRange{Lo: '-', Hi: '-'}, ast.CharRange{Lo: '\\', Hi: '\\'},
+		ast.CharRange{Lo: 0x7F, Hi: 0x7F}, ast.CharRange{Lo: 0x1F600, Hi: 0x1F64F},
+	)
+	assert.Equal(t, `\t\n \-\\\]a-z\u007F😀-🙏`, set.String())
+	assert.Equal(t, `\u{E0000}`, ast.NewCharSet(ast.CharRange{Lo: 0xE0000, Hi: 0xE0000}).String())
+}
+
+func basic(data string) *runtime.Token {
+	return &runtime.Token{Type: token.BASIC_CHAR, Data: data}
+}
+
+func escape(data string) *runtime.Token {
+	return &runtime.Token{Type: token.ESCAPE_CHAR, Data: data}
+}
+
+func unicodeEscape(data string) *runtime.Token {
+	return &runtime.Token{Type: token.UNICODE_ESCAPE_CHAR, Data: data}
+}
+
+func TestNewLexerCharClass(t *testing.T) {
+	class := ast.NewLexerCharClass([][]*runtime.Token{
+		{basic("a"), basic("z")},
+		{escape(`\n`)},
+		{escape(`\]`)},
+		{escape(`\-`)},
+		{unicodeEscape(`\u0041`), unicodeEscape(`\u{5A}`)},
+		{basic("😊")},
+	})
+	require.NoError(t, class.Err)
+	assert.Equal(t, ast.CharSet{
+		{Lo: '\n', Hi: '\n'}, {Lo: '-', Hi: '-'}, {Lo: 'A', Hi: 'Z'},
+		{Lo: ']', Hi: ']'}, {Lo: 'a', Hi: 'z'}, {Lo: '😊', Hi: '😊'},
+	}, class.Set)
+	assert.Equal(t, `[\n\-A-Z\]a-z😊]`, class.Text())
+
+	class = ast.NewLexerCharClass([][]*runtime.Token{{basic("z"), basic("a")}, {basic("0")}})
+	assert.EqualError(t, class.Err, "invalid char range: z-a")
+	assert.Equal(t, ast.CharSet{{Lo: '0', Hi: '0'}}, class.Set)
+
+	class = ast.NewLexerCharClass([][]*runtime.Token{{unicodeEscape(`\u{110000}`)}})
+	assert.EqualError(t, class.Err, `invalid unicode escape: \u{110000}`)
+
+	class = ast.NewLexerCharClass([][]*runtime.Token{{unicodeEscape(`x`)}})
+	assert.EqualError(t, class.Err, `invalid unicode escape: x`)
+}
+
+func TestNewLexerNestedNodeNegation(t *testing.T) {
+	tilde := &runtime.Token{Type: token.TILDE}
+	star := &runtime.Token{Type: token.STAR}
+	class := ast.NewLexerCharClass([][]*runtime.Token{{basic("a")}})
+
+	// Char classes are negated directly and the original is left untouched
+	node := ast.NewLexerNestedNode(class, tilde, star, nil)
+	require.IsType(t, &ast.LexerZeroOrMore{}, node)
+	negated := node.(*ast.LexerZeroOrMore).Node.(*ast.LexerCharClass)
+	assert.True(t, negated.Negated)
+	assert.False(t, class.Negated)
+	assert.Equal(t, "~[a]", negated.Text())
+	assert.False(t, negated.Chars().Contains('a'))
+	assert.True(t, negated.Chars().Contains('b'))
+
+	// Anything else is wrapped
+	lit := &ast.LexerToken{Token: &runtime.Token{Type: token.TOKEN_LIT, Data: "'ab'"}}
+	assert.Equal(t, &ast.LexerNot{Node: lit}, ast.NewLexerNestedNode(lit, tilde, nil, nil))
+}
@@ -0,0 +1,414 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// Diagnostic is a problem found in a grammar by Check
+type Diagnostic struct {
+	Span     Span
+	Severity runtime.Severity
+	Message  string
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s: %s", d.Span.Start, d.Severity, d.Message)
+}
+
+// Check looks for semantic problems in the grammar and returns them ordered
+// by file and position. Token references and literals are only checked when
+// the grammar has lexer rules, as otherwise the tokens are defined elsewhere
+func (t *TopLevel) Check() []*Diagnostic {
+	c := &checker{top: t, nullable: make(map[string]bool, len(t.ParserRules))}
+	c.duplicates()
+	c.grammarType()
+	c.nullableRules()
+
+	for _, rule := range t.ParserRules {
+		c.parserAlts(rule, rule.Rules)
+		c.labels(rule)
+	}
+	for _, rule := range t.LexerRules {
+		c.lexerAlts(rule, rule.Rules)
+	}
+	c.unreachable()
+	c.unusedFragments()
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Span, c.diags[j].Span
+		return a.File < b.File || (a.File == b.File && a.Start.Offset < b.Start.Offset)
+	})
+	return c.diags
+}
+
+type checker struct {
+	top      *TopLevel
+	diags    []*Diagnostic
+	nullable map[string]bool
+	// Lexer rules referenced by other lexer rules
+	lexerRefs map[string]bool
+}
+
+func (c *checker) report(span Span, severity runtime.Severity, format string, args ...interface{}) {
+	c.diags = append(c.diags, &Diagnostic{
+		Span: span, Severity: severity, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) duplicates() {
+	for _, rule := range c.top.ParserRules {
+		if first := c.top.ParserRulesMap[rule.Name]; first != rule {
+			c.report(rule.Span, runtime.SeverityError, "duplicate parser rule: %s (first defined at %s)",
+				rule.Name, first.Start)
+		}
+	}
+	for _, rule := range c.top.LexerRules {
+		if first := c.top.LexerRulesMap[rule.Name]; first != rule {
+			c.report(rule.Span, runtime.SeverityError, "duplicate lexer rule: %s (first defined at %s)",
+				rule.Name, first.Start)
+		}
+	}
+}
+
+// grammarType reports rules that don't belong in the type of grammar declared
+// by the header. Rules merged from other grammars are not checked
+func (c *checker) grammarType() {
+	switch c.top.Type() {
+	case ParserGrammar:
+		for _, rule := range c.top.LexerRules {
+			if rule.Grammar == "" {
+				c.report(rule.Span, runtime.SeverityError, "lexer rule in parser grammar: %s", rule.Name)
+			}
+		}
+	case LexerGrammar:
+		for _, rule := range c.top.ParserRules {
+			if rule.Grammar == "" {
+				c.report(rule.Span, runtime.SeverityError, "parser rule in lexer grammar: %s", rule.Name)
+			}
+		}
+	}
+}
+
+// *** Parser rules ***
+
+func (c *checker) parserAlts(rule *ParserRule, alts *ParserAlternatives) {
+	for i, alt := range alts.Rules {
+		for _, node := range alt {
+			c.parserNode(rule, node)
+		}
+		c.shadowed(rule, alts, i)
+	}
+}
+
+func (c *checker) parserNode(rule *ParserRule, node ParserNode) {
+	switch n := node.(type) {
+	case *ParserAlternatives:
+		c.parserAlts(rule, n)
+	case *ParserZeroOrMore:
+		c.emptyLoop(rule, n.Span, n.Node)
+		c.parserNode(rule, n.Node)
+	case *ParserOneOrMore:
+		c.emptyLoop(rule, n.Span, n.Node)
+		c.parserNode(rule, n.Node)
+	case *ParserZeroOrOne:
+		c.parserNode(rule, n.Node)
+	case *ParserLabel:
+		c.parserNode(rule, n.Node)
+	case *ParserAndPredicate:
+		if c.nullableNode(n.Node) {
+			c.report(n.Span, runtime.SeverityWarning, "and-predicate in %s always succeeds", rule.Name)
+		}
+		c.parserNode(rule, n.Node)
+	case *ParserNotPredicate:
+		if c.nullableNode(n.Node) {
+			c.report(n.Span, runtime.SeverityWarning, "not-predicate in %s never succeeds", rule.Name)
+		}
+		c.parserNode(rule, n.Node)
+	case *ParserRuleRef:
+		if _, ok := c.top.ParserRulesMap[n.Name]; !ok {
+			c.report(n.Span, runtime.SeverityError, "undefined parser rule: %s", n.Name)
+		}
+	case *ParserLexerRuleRef:
+		if len(c.top.LexerRules) == 0 || n.Name == "EOF" {
+			return
+		}
+		if lexRule, ok := c.top.LexerRulesMap[n.Name]; !ok {
+			c.report(n.Span, runtime.SeverityError, "undefined token: %s", n.Name)
+		} else if lexRule.Fragment {
+			c.report(n.Span, runtime.SeverityError, "fragment rule used as a token: %s", n.Name)
+		}
+	case *ParserToken:
+		if len(c.top.LexerRules) > 0 && !c.hasLiteralRule(n.Token.Data) {
+			c.report(n.Span, runtime.SeverityWarning,
+				"no lexer rule for literal %s, an implicit token will be used", n.Token.Data)
+		}
+	}
+}
+
+// emptyLoop reports a loop whose body can match without consuming any tokens.
+// Matching it again would never end, so the loop stops after such a match,
+// which is likely not what was meant
+func (c *checker) emptyLoop(rule *ParserRule, span Span, body ParserNode) {
+	if c.nullableNode(body) {
+		c.report(span, runtime.SeverityWarning, "loop in %s can match nothing, and stops when it does", rule.Name)
+	}
+}
+
+func (c *checker) hasLiteralRule(literal string) bool {
+	for _, rule := range c.top.LexerRules {
+		if lit, ok := rule.Literal(); ok && lit == literal {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowed reports an alternative that can never match because an earlier
+// alternative is tried first. Since choices are ordered, an earlier
+// alternative that always matches, or that is a prefix of this one (and so
+// matches first whenever this one would), hides it
+func (c *checker) shadowed(rule *ParserRule, alts *ParserAlternatives, alt int) {
+	for i := 0; i < alt; i++ {
+		if c.nullableSeq(alts.Rules[i]) || isPrefix(alts.Rules[i], alts.Rules[alt]) {
+			c.report(alts.Rules[alt][0].NodeSpan(), runtime.SeverityWarning,
+				"alternative %d of %s is shadowed by alternative %d", alt+1, rule.Name, i+1)
+			return
+		}
+	}
+}
+
+func isPrefix(prefix, seq []ParserNode) bool {
+	if len(prefix) > len(seq) {
+		return false
+	}
+	for i, node := range prefix {
+		if node.String(0) != seq[i].String(0) {
+			return false
+		}
+	}
+	return true
+}
+
+// labels reports alternative labels used more than once in a rule, rules
+// that label only some of their alternatives and element labels that are
+// used inconsistently
+func (c *checker) labels(rule *ParserRule) {
+	alts := rule.Rules
+	if alts.Labels != nil && len(alts.Rules) > 1 {
+		for i, alt := range alts.Rules {
+			if alts.Labels[i] == nil {
+				c.report(alt[0].NodeSpan(), runtime.SeverityError,
+					"alternative %d of %s has no label, but other alternatives do", i+1, rule.Name)
+			}
+		}
+	}
+
+	altLabels := map[string]*AltLabel{}
+	elemLabels := map[string]*ParserLabel{}
+	var visitAlts func(alts *ParserAlternatives)
+	var visit func(node ParserNode, seq map[string]*ParserLabel)
+	visitAlts = func(alts *ParserAlternatives) {
+		for i, alt := range alts.Rules {
+			if i < len(alts.Labels) && alts.Labels[i] != nil {
+				label := alts.Labels[i]
+				if first, ok := altLabels[label.Name]; ok {
+					c.report(label.Span, runtime.SeverityError, "duplicate alternative label in %s: %s "+
+						"(first used at %s)", rule.Name, label.Name, first.Start)
+				} else {
+					altLabels[label.Name] = label
+				}
+			}
+
+			seq := map[string]*ParserLabel{}
+			for _, node := range alt {
+				visit(node, seq)
+			}
+		}
+	}
+	visit = func(node ParserNode, seq map[string]*ParserLabel) {
+		switch n := node.(type) {
+		case *ParserAlternatives:
+			visitAlts(n)
+		case *ParserZeroOrMore:
+			visit(n.Node, seq)
+		case *ParserOneOrMore:
+			visit(n.Node, seq)
+		case *ParserZeroOrOne:
+			visit(n.Node, seq)
+		case *ParserAndPredicate:
+			visit(n.Node, seq)
+		case *ParserNotPredicate:
+			visit(n.Node, seq)
+		case *ParserLabel:
+			if first, ok := elemLabels[n.Name]; ok && first.List != n.List {
+				c.report(n.Span, runtime.SeverityError, "label %s of %s is used as both an element "+
+					"and a list label (first used at %s)", n.Name, rule.Name, first.Start)
+			} else if !ok {
+				elemLabels[n.Name] = n
+			}
+			// List labels collect every element they label, but a plain label
+			// can only name one element of a sequence
+			if first, ok := seq[n.Name]; ok && !n.List && !first.List {
+				c.report(n.Span, runtime.SeverityError, "duplicate label in %s: %s (first used at %s)",
+					rule.Name, n.Name, first.Start)
+			} else if !ok {
+				seq[n.Name] = n
+			}
+			visit(n.Node, seq)
+		}
+	}
+	visitAlts(alts)
+}
+
+// nullableRules finds the parser rules that can match without consuming any
+// tokens. Predicates never consume tokens, but unlike the other ways of
+// matching nothing they can fail, so they are not nullable here: an
+// alternative made of them doesn't always match
+func (c *checker) nullableRules() {
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range c.top.ParserRules {
+			if !c.nullable[rule.Name] && c.nullableNode(rule.Rules) {
+				c.nullable[rule.Name], changed = true, true
+			}
+		}
+	}
+}
+
+func (c *checker) nullableSeq(seq []ParserNode) bool {
+	for _, node := range seq {
+		if !c.nullableNode(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *checker) nullableNode(node ParserNode) bool {
+	switch n := node.(type) {
+	case *ParserAlternatives:
+		for _, alt := range n.Rules {
+			if c.nullableSeq(alt) {
+				return true
+			}
+		}
+		return false
+	case *ParserZeroOrMore, *ParserZeroOrOne:
+		return true
+	case *ParserOneOrMore:
+		return c.nullableNode(n.Node)
+	case *ParserLabel:
+		return c.nullableNode(n.Node)
+	case *ParserAndPredicate, *ParserNotPredicate:
+		return false
+	case *ParserRuleRef:
+		return c.nullable[n.Name]
+	default:
+		return false
+	}
+}
+
+// unreachable reports parser rules that can't be reached from the start rule
+// (the first parser rule)
+func (c *checker) unreachable() {
+	if len(c.top.ParserRules) == 0 {
+		return
+	}
+
+	reached := map[string]bool{}
+	var visit func(node ParserNode)
+	visit = func(node ParserNode) {
+		switch n := node.(type) {
+		case *ParserAlternatives:
+			for _, alt := range n.Rules {
+				for _, node := range alt {
+					visit(node)
+				}
+			}
+		case *ParserZeroOrMore:
+			visit(n.Node)
+		case *ParserOneOrMore:
+			visit(n.Node)
+		case *ParserZeroOrOne:
+			visit(n.Node)
+		case *ParserLabel:
+			visit(n.Node)
+		case *ParserAndPredicate:
+			visit(n.Node)
+		case *ParserNotPredicate:
+			visit(n.Node)
+		case *ParserRuleRef:
+			if rule, ok := c.top.ParserRulesMap[n.Name]; ok && !reached[n.Name] {
+				reached[n.Name] = true
+				visit(rule.Rules)
+			}
+		}
+	}
+	start := c.top.ParserRules[0]
+	reached[start.Name] = true
+	visit(start.Rules)
+
+	for _, rule := range c.top.ParserRules {
+		// Grammars are imported for the rules that are needed, so unused
+		// imported rules are expected
+		if !reached[rule.Name] && c.top.ParserRulesMap[rule.Name] == rule && rule.Grammar == "" {
+			c.report(rule.Span, runtime.SeverityWarning, "unreachable parser rule: %s (start rule is %s)",
+				rule.Name, start.Name)
+		}
+	}
+}
+
+// *** Lexer rules ***
+
+func (c *checker) lexerAlts(rule *LexerRule, alts *LexerAlternatives) {
+	for _, alt := range alts.Rules {
+		for _, node := range alt {
+			c.lexerNode(rule, node)
+		}
+	}
+}
+
+func (c *checker) lexerNode(rule *LexerRule, node LexerNode) {
+	switch n := node.(type) {
+	case *LexerAlternatives:
+		c.lexerAlts(rule, n)
+	case *LexerNot:
+		c.lexerNode(rule, n.Node)
+	case *LexerZeroOrMore:
+		c.lexerNode(rule, n.Node)
+	case *LexerOneOrMore:
+		c.lexerNode(rule, n.Node)
+	case *LexerZeroOrOne:
+		c.lexerNode(rule, n.Node)
+	case *LexerNonGreedyZeroOrMore:
+		c.lexerNode(rule, n.Node)
+	case *LexerNonGreedyOneOrMore:
+		c.lexerNode(rule, n.Node)
+	case *LexerNonGreedyZeroOrOne:
+		c.lexerNode(rule, n.Node)
+	case *LexerRuleRef:
+		if _, ok := c.top.LexerRulesMap[n.Name]; !ok {
+			c.report(n.Span, runtime.SeverityError, "undefined lexer rule: %s", n.Name)
+		} else if n.Name != rule.Name {
+			if c.lexerRefs == nil {
+				c.lexerRefs = make(map[string]bool, 8)
+			}
+			c.lexerRefs[n.Name] = true
+		}
+	case *LexerCharClass:
+		if n.Err != nil {
+			c.report(n.Span, runtime.SeverityError, "%s: %v", rule.Name, n.Err)
+		}
+	}
+}
+
+func (c *checker) unusedFragments() {
+	for _, rule := range c.top.LexerRules {
+		if rule.Fragment && !c.lexerRefs[rule.Name] && c.top.LexerRulesMap[rule.Name] == rule {
+			c.report(rule.Span, runtime.SeverityWarning, "unused fragment rule: %s", rule.Name)
+		}
+	}
+}
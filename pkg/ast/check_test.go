@@ -0,0 +1,138 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func check(t *testing.T, grammar string) []string {
+	lex := runtime.NewLexerFromString(grammar)
+	top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+
+	diags := []string{}
+	for _, diag := range top.Check() {
+		diags = append(diags, diag.Error())
+	}
+	return diags
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name, grammar string
+		diags         []string
+	}{
+		{
+			name:    "valid",
+			grammar: "a: b (',' b)* EOF;\nb: NAME | '(' a ')';\n\nNAME: LETTER+;\nfragment LETTER: [a-z];\nCOMMA: ',';\nLPAREN: '(';\nRPAREN: ')';",
+			diags:   []string{},
+		},
+		{
+			name:    "parser only",
+			grammar: "a: B 'c';",
+			diags:   []string{},
+		},
+		{
+			name:    "undefined",
+			grammar: "a: b C;\n\nD: E;",
+			diags: []string{
+				"1:4: error: undefined parser rule: b",
+				"1:6: error: undefined token: C",
+				"3:4: error: undefined lexer rule: E",
+			},
+		},
+		{
+			name:    "duplicates",
+			grammar: "a: B;\na: C;\n\nB: 'b';\nC: 'c';\nB: 'x';",
+			diags: []string{
+				"2:1: error: duplicate parser rule: a (first defined at 1:1)",
+				"6:1: error: duplicate lexer rule: B (first defined at 4:1)",
+			},
+		},
+		{
+			name:    "unreachable",
+			grammar: "a: b;\nb: 'x';\nc: a;",
+			diags:   []string{"3:1: warning: unreachable parser rule: c (start rule is a)"},
+		},
+		{
+			name:    "fragments",
+			grammar: "a: B E;\n\nB: 'b' C;\nfragment C: 'c';\nfragment D: 'd' D;\nfragment E: 'e';",
+			diags: []string{
+				"1:6: error: fragment rule used as a token: E",
+				"5:1: warning: unused fragment rule: D",
+				"6:1: warning: unused fragment rule: E",
+			},
+		},
+		{
+			name:    "implicit literal",
+			grammar: "a: B ';';\n\nB: 'b';",
+			diags:   []string{"1:6: warning: no lexer rule for literal ';', an implicit token will be used"},
+		},
+		{
+			name:    "shadowed",
+			grammar: "a: B | B C | (C | C) | D? | E;",
+			diags: []string{
+				"1:8: warning: alternative 2 of a is shadowed by alternative 1",
+				"1:19: warning: alternative 2 of a is shadowed by alternative 1",
+				"1:29: warning: alternative 5 of a is shadowed by alternative 4",
+			},
+		},
+		{
+			name:    "lexer rule in parser grammar",
+			grammar: "parser grammar p;\na: B;\nB: 'b';",
+			diags:   []string{"3:1: error: lexer rule in parser grammar: B"},
+		},
+		{
+			name:    "parser rule in lexer grammar",
+			grammar: "lexer grammar l;\na: B;\nB: 'b';",
+			diags:   []string{"2:1: error: parser rule in lexer grammar: a"},
+		},
+		{
+			name:    "shadowed by nullable rule",
+			grammar: "a: b | C;\nb: D*;",
+			diags:   []string{"1:8: warning: alternative 2 of a is shadowed by alternative 1"},
+		},
+		{
+			name:    "empty loops",
+			grammar: "a: b* C+ (E | D?)+ EOF;\nb: X?;",
+			diags: []string{
+				"1:4: warning: loop in a can match nothing, and stops when it does",
+				"1:10: warning: loop in a can match nothing, and stops when it does",
+			},
+		},
+		{
+			name:    "predicates",
+			grammar: "a: !B | C &D | &B? C | !D* E | (!B)+ | C;",
+			diags: []string{
+				"1:16: warning: and-predicate in a always succeeds",
+				"1:24: warning: not-predicate in a never succeeds",
+			},
+		},
+		{
+			name:    "labels",
+			grammar: "a: x=B y+=C (y+=D)* # One | x=C (D # Two | E # Three) # Four;",
+			diags:   []string{},
+		},
+		{
+			name:    "bad labels",
+			grammar: "a: x=B x=C # One | B (C # One | D) # Two | y=B y+=C # Three | C;",
+			diags: []string{
+				"1:8: error: duplicate label in a: x (first used at 1:4)",
+				"1:25: error: duplicate alternative label in a: One (first used at 1:12)",
+				"1:48: error: label y of a is used as both an element and a list label (first used at 1:44)",
+				"1:63: error: alternative 4 of a has no label, but other alternatives do",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.diags, check(t, test.grammar))
+		})
+	}
+}
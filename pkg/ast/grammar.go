@@ -0,0 +1,218 @@
+package ast
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// GrammarType is the kind of grammar declared by a grammar header
+type GrammarType int
+
+const (
+	// CombinedGrammar has both parser and lexer rules. It is also the type of
+	// a grammar without a header
+	CombinedGrammar GrammarType = iota
+	ParserGrammar
+	LexerGrammar
+)
+
+func (g GrammarType) String() string {
+	switch g {
+	case ParserGrammar:
+		return "parser grammar"
+	case LexerGrammar:
+		return "lexer grammar"
+	default:
+		return "grammar"
+	}
+}
+
+// GrammarHeader is the `parser grammar X;` style declaration at the start of
+// a grammar
+type GrammarHeader struct {
+	Span
+	Type GrammarType
+	Name string
+}
+
+// NewGrammarHeader creates a header from the optional 'parser' or 'lexer'
+// token preceding 'grammar' and the grammar name
+func NewGrammarHeader(span Span, type_, name *runtime.Token) *GrammarHeader {
+	header := &GrammarHeader{Span: span, Name: name.Data}
+	if type_ == nil {
+		return header
+	}
+	switch type_.Type {
+	case token.PARSER:
+		header.Type = ParserGrammar
+	case token.LEXER:
+		header.Type = LexerGrammar
+	default:
+		log.Panicf("Unknown token type: %d", type_.Type)
+	}
+	return header
+}
+
+func (g *GrammarHeader) String(indent int) string {
+	return fmt.Sprintf("%s└──%s: %s\n", strings.Repeat(" ", indent*spaces), g.Type, g.Name)
+}
+
+// Option is a single `name = value;` entry of an options block. The value is
+// kept as written, so literal values keep their quotes
+type Option struct {
+	Span
+	Name, Value string
+}
+
+func (o *Option) String(indent int) string {
+	return fmt.Sprintf("%s└──Option: %s = %s\n", strings.Repeat(" ", indent*spaces), o.Name, o.Value)
+}
+
+// Import is a grammar named by an import statement
+type Import struct {
+	Span
+	Name string
+}
+
+func (i *Import) String(indent int) string {
+	return fmt.Sprintf("%s└──Import: %s\n", strings.Repeat(" ", indent*spaces), i.Name)
+}
+
+// Type returns the type of the grammar given by its header
+func (t *TopLevel) Type() GrammarType {
+	if t.Header == nil {
+		return CombinedGrammar
+	}
+	return t.Header.Type
+}
+
+// Option returns the value of an option. When an option is given more than
+// once the last value is used
+func (t *TopLevel) Option(name string) (string, bool) {
+	if opt := t.findOption(name); opt != nil {
+		return opt.Value, true
+	}
+	return "", false
+}
+
+func (t *TopLevel) findOption(name string) *Option {
+	for i := len(t.Options) - 1; i >= 0; i-- {
+		if t.Options[i].Name == name {
+			return t.Options[i]
+		}
+	}
+	return nil
+}
+
+// *** Resolving ***
+
+// Resolve merges the grammars this grammar depends on into it. The rules of
+// imported grammars are added unless this grammar already has a rule of the
+// same name (earlier imports win over later ones) and the lexer rules of the
+// grammar named by the tokenVocab option are added the same way. load returns
+// the parsed (but unresolved) grammar with the given name, typically by
+// reading name.g4 from the directory of this grammar
+func (t *TopLevel) Resolve(load func(name string) (*TopLevel, error)) error {
+	r := &resolver{load: load, resolved: make(map[string]*TopLevel, 4)}
+	path := []string{}
+	if t.Header != nil {
+		path = append(path, t.Header.Name)
+	}
+	return r.resolve(t, path)
+}
+
+type resolver struct {
+	load     func(name string) (*TopLevel, error)
+	resolved map[string]*TopLevel
+}
+
+func (r *resolver) resolve(t *TopLevel, path []string) error {
+	for _, imp := range t.Imports {
+		other, err := r.get(imp.Name, path)
+		if err != nil {
+			return fmt.Errorf("%s: import %s: %w", spanPos(imp.Span), imp.Name, err)
+		}
+		t.merge(other, imp.Name, true)
+	}
+
+	vocab := t.findOption("tokenVocab")
+	if vocab == nil {
+		return nil
+	}
+	other, err := r.get(vocab.Value, path)
+	if err != nil {
+		return fmt.Errorf("%s: tokenVocab %s: %w", spanPos(vocab.Span), vocab.Value, err)
+	}
+	t.merge(other, vocab.Value, false)
+	return nil
+}
+
+// get loads and resolves a grammar once no matter how many grammars use it
+func (r *resolver) get(name string, path []string) (*TopLevel, error) {
+	for i, seen := range path {
+		if seen == name {
+			return nil, fmt.Errorf("import cycle: %s -> %s",
+				strings.Join(path[i:], " -> "), name)
+		}
+	}
+	if t, ok := r.resolved[name]; ok {
+		return t, nil
+	}
+
+	t, err := r.load(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.resolve(t, append(path[:len(path):len(path)], name)); err != nil {
+		return nil, err
+	}
+	r.resolved[name] = t
+	return t, nil
+}
+
+// merge adds the rules of another grammar that this grammar doesn't define.
+// Parser rules are only added when parser is true
+func (t *TopLevel) merge(other *TopLevel, grammar string, parser bool) {
+	if parser {
+		for _, rule := range other.ParserRules {
+			if _, ok := t.ParserRulesMap[rule.Name]; !ok {
+				imported := *rule
+				if imported.Grammar == "" {
+					imported.Grammar = grammar
+				}
+				t.addParserRule(&imported)
+			}
+		}
+	}
+	for _, rule := range other.LexerRules {
+		if _, ok := t.LexerRulesMap[rule.Name]; !ok {
+			imported := *rule
+			if imported.Grammar == "" {
+				imported.Grammar = grammar
+			}
+			t.addLexerRule(&imported)
+		}
+	}
+
+	for _, mode := range other.Modes {
+		found := false
+		for _, existing := range t.Modes {
+			found = found || existing == mode
+		}
+		if !found {
+			t.Modes = append(t.Modes, mode)
+		}
+	}
+}
+
+// spanPos returns the file and start position of a span for error messages
+func spanPos(span Span) string {
+	if span.File == "" {
+		return span.Start.String()
+	}
+	return span.File + ":" + span.Start.String()
+}
@@ -0,0 +1,152 @@
+package ast_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loader parses grammars from a map of name to source, keeping count of how
+// many times each is loaded
+type loader struct {
+	t        *testing.T
+	grammars map[string]string
+	loads    map[string]int
+}
+
+func newLoader(t *testing.T, grammars map[string]string) *loader {
+	return &loader{t: t, grammars: grammars, loads: make(map[string]int, len(grammars))}
+}
+
+func (l *loader) parse(name string) *ast.TopLevel {
+	p := runtime.NewParser(token.New(runtime.NewLexerFromString(l.grammars[name])))
+	p.SetFilename(name + ".g4")
+	top, err := parser.New(p).Parse()
+	require.NoError(l.t, err)
+	return top
+}
+
+func (l *loader) load(name string) (*ast.TopLevel, error) {
+	if _, ok := l.grammars[name]; !ok {
+		return nil, fmt.Errorf("no such grammar: %s", name)
+	}
+	l.loads[name]++
+	return l.parse(name), nil
+}
+
+func ruleNames(top *ast.TopLevel) (parserRules, lexerRules []string) {
+	for _, rule := range top.ParserRules {
+		parserRules = append(parserRules, rule.Name+"@"+rule.Grammar)
+	}
+	for _, rule := range top.LexerRules {
+		lexerRules = append(lexerRules, rule.Name+"@"+rule.Grammar)
+	}
+	return parserRules, lexerRules
+}
+
+func TestResolve(t *testing.T) {
+	l := newLoader(t, map[string]string{
+		"main": `grammar main;
+import exprs, common;
+start: expr EOF;
+atom: NUM;
+NUM: [0-9]+;`,
+		"exprs": `grammar exprs;
+import common;
+expr: atom ('+' atom)*;
+atom: ID;
+ID: [a-z]+;
+PLUS: '+';`,
+		"common": `grammar common;
+atom: STR;
+unused: ID;
+STR: '"' ~'"'* '"';
+WS: [ \t]+ -> skip;
+mode STRING;
+ESC: '\\' .;`,
+	})
+
+	top := l.parse("main")
+	require.NoError(t, top.Resolve(l.load))
+
+	// Own rules come first and override imported ones, then the rules of each
+	// import in order
+	parserRules, lexerRules := ruleNames(top)
+	assert.Equal(t, []string{"start@", "atom@", "expr@exprs", "unused@common"}, parserRules)
+	assert.Equal(t, []string{"NUM@", "ID@exprs", "PLUS@exprs", "STR@common", "WS@common", "ESC@common"}, lexerRules)
+	assert.Equal(t, []string{"STRING"}, top.Modes)
+	assert.Equal(t, "main.g4", top.ParserRulesMap["atom"].File)
+	assert.Equal(t, "exprs.g4", top.ParserRulesMap["expr"].File)
+
+	// Each grammar is only loaded once
+	assert.Equal(t, map[string]int{"exprs": 1, "common": 1}, l.loads)
+	assert.Empty(t, top.Check())
+}
+
+func TestResolveTokenVocab(t *testing.T) {
+	l := newLoader(t, map[string]string{
+		"p": `parser grammar p;
+options { tokenVocab = l; }
+a: B ';';`,
+		"l": `lexer grammar l;
+B: 'b';
+SEMI: ';';
+A: 'a';`,
+	})
+
+	top := l.parse("p")
+	require.NoError(t, top.Resolve(l.load))
+
+	// Only the lexer rules of the vocabulary are used
+	parserRules, lexerRules := ruleNames(top)
+	assert.Equal(t, []string{"a@"}, parserRules)
+	assert.Equal(t, []string{"B@l", "SEMI@l", "A@l"}, lexerRules)
+	assert.Empty(t, top.Check())
+
+	// Without resolving, tokens are assumed to be defined elsewhere
+	assert.Empty(t, l.parse("p").Check())
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		grammars map[string]string
+		err      string
+	}{
+		{
+			name:     "missing import",
+			grammars: map[string]string{"main": "grammar main;\nimport a, b;\nx: Y;", "a": "y: Z;"},
+			err:      "main.g4:2:11: import b: no such grammar: b",
+		},
+		{
+			name:     "missing vocab",
+			grammars: map[string]string{"main": "options { tokenVocab = lex; }\nx: Y;"},
+			err:      "main.g4:1:11: tokenVocab lex: no such grammar: lex",
+		},
+		{
+			name: "cycle",
+			grammars: map[string]string{
+				"main": "grammar main; import a; x: Y;",
+				"a":    "grammar a; import b; y: Z;",
+				"b":    "grammar b; import main; z: W;",
+			},
+			err: "main.g4:1:22: import a: a.g4:1:19: import b: b.g4:1:19: import main: " +
+				"import cycle: main -> a -> b -> main",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newLoader(t, test.grammars)
+			err := l.parse("main").Resolve(l.load)
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// *** Left recursion ***
+
+// A parse function is left recursive when it can call itself (directly or
+// through other functions) before matching any tokens. Each group of functions
+// that call each other this way gets a leader that every cycle through the
+// group passes through. The leader grows its result using the seed growing
+// algorithm in the runtime while the other functions in the group are not
+// memoized, as their results depend on how far the leader has grown
+
+// CallGraph is the parse functions of a parser (ex: one per parser rule) and
+// the calls between them. Functions are numbered from 0 in grammar order
+type CallGraph interface {
+	// Len returns the number of parse functions
+	Len() int
+	// Name returns the name of a function, used in errors
+	Name(fn int) string
+	// Nullable returns true if a function can succeed without matching any
+	// tokens, given the functions found to be nullable so far
+	Nullable(fn int, nullable []bool) bool
+	// LeftCalls returns the functions a function can call before matching any
+	// tokens
+	LeftCalls(fn int, nullable []bool) []int
+	// Preferred returns true if a function makes a better leader than the
+	// functions it returns false for (ex: a rule over one of its sub-rules)
+	Preferred(fn int) bool
+}
+
+// LeftRecursiveGroup is a group of functions that can call each other before
+// matching any tokens
+type LeftRecursiveGroup struct {
+	// Leader is the function that grows its result
+	Leader int
+	// Members are the functions of the group in grammar order, including the
+	// leader
+	Members []int
+}
+
+// LeftRecursion finds the left recursive groups of a call graph and the leader
+// of each. The leader is the first function in grammar order that every cycle
+// through the group passes through, preferring functions the graph prefers
+func LeftRecursion(g CallGraph) ([]*LeftRecursiveGroup, error) {
+	nullable := Nullable(g)
+	edges := make([][]int, g.Len())
+	for fn := range edges {
+		edges[fn] = g.LeftCalls(fn, nullable)
+	}
+
+	groups := []*LeftRecursiveGroup{}
+	for _, members := range stronglyConnected(edges) {
+		if len(members) == 1 && !calls(edges, members[0], members[0]) {
+			continue
+		}
+
+		leader := -1
+		for _, fn := range members {
+			if !acyclicWithout(members, edges, fn) {
+				continue
+			}
+			if leader < 0 || (!g.Preferred(leader) && g.Preferred(fn)) {
+				leader = fn
+			}
+		}
+		if leader < 0 {
+			names := make([]string, len(members))
+			for i, fn := range members {
+				names[i] = g.Name(fn)
+			}
+			return nil, fmt.Errorf("left recursion without a rule common to all cycles: %s",
+				strings.Join(names, ", "))
+		}
+		groups = append(groups, &LeftRecursiveGroup{Leader: leader, Members: members})
+	}
+	return groups, nil
+}
+
+// Nullable finds the functions of a call graph that can succeed without
+// matching any tokens
+func Nullable(g CallGraph) []bool {
+	nullable := make([]bool, g.Len())
+	for changed := true; changed; {
+		changed = false
+		for fn := range nullable {
+			if !nullable[fn] && g.Nullable(fn, nullable) {
+				nullable[fn], changed = true, true
+			}
+		}
+	}
+	return nullable
+}
+
+func calls(edges [][]int, from, to int) bool {
+	for _, callee := range edges[from] {
+		if callee == to {
+			return true
+		}
+	}
+	return false
+}
+
+// stronglyConnected groups functions that can all reach each other (Tarjan's
+// algorithm). Each group is sorted into grammar order
+func stronglyConnected(edges [][]int) [][]int {
+	index := make([]int, len(edges))
+	low := make([]int, len(edges))
+	onStack := make([]bool, len(edges))
+	stack := []int{}
+	groups := [][]int{}
+	next := 1 // 0 = not visited yet
+
+	var visit func(fn int)
+	visit = func(fn int) {
+		index[fn], low[fn] = next, next
+		next++
+		stack = append(stack, fn)
+		onStack[fn] = true
+
+		for _, callee := range edges[fn] {
+			if index[callee] == 0 {
+				visit(callee)
+				if low[callee] < low[fn] {
+					low[fn] = low[callee]
+				}
+			} else if onStack[callee] && index[callee] < low[fn] {
+				low[fn] = index[callee]
+			}
+		}
+
+		if low[fn] == index[fn] {
+			group := []int{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				group = append(group, top)
+				if top == fn {
+					break
+				}
+			}
+			sort.Ints(group)
+			groups = append(groups, group)
+		}
+	}
+
+	for fn := range edges {
+		if index[fn] == 0 {
+			visit(fn)
+		}
+	}
+	return groups
+}
+
+// acyclicWithout returns true if removing a function from a group leaves no
+// cycles
+func acyclicWithout(group []int, edges [][]int, removed int) bool {
+	inGroup := make(map[int]bool, len(group))
+	for _, fn := range group {
+		inGroup[fn] = fn != removed
+	}
+
+	// 1 = being visited, 2 = done
+	state := make(map[int]int, len(group))
+	var cyclic func(fn int) bool
+	cyclic = func(fn int) bool {
+		state[fn] = 1
+		for _, callee := range edges[fn] {
+			if !inGroup[callee] {
+				continue
+			}
+			if state[callee] == 1 || (state[callee] == 0 && cyclic(callee)) {
+				return true
+			}
+		}
+		state[fn] = 2
+		return false
+	}
+
+	for _, fn := range group {
+		if inGroup[fn] && state[fn] == 0 && cyclic(fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graph is a call graph given by the calls of each function. The calls of a
+// function are all left calls until the first one to a non-nullable function
+type graph struct {
+	names     []string
+	calls     [][]int
+	nullable  []bool
+	preferred []bool
+}
+
+func (g *graph) Len() int { return len(g.names) }
+
+func (g *graph) Name(fn int) string { return g.names[fn] }
+
+func (g *graph) Nullable(fn int, nullable []bool) bool {
+	if g.nullable[fn] {
+		return true
+	}
+	for _, callee := range g.calls[fn] {
+		if !nullable[callee] {
+			return false
+		}
+	}
+	return len(g.calls[fn]) > 0
+}
+
+func (g *graph) LeftCalls(fn int, nullable []bool) []int {
+	callees := []int{}
+	for _, callee := range g.calls[fn] {
+		callees = append(callees, callee)
+		if !nullable[callee] {
+			break
+		}
+	}
+	return callees
+}
+
+func (g *graph) Preferred(fn int) bool { return g.preferred == nil || g.preferred[fn] }
+
+func TestLeftRecursion(t *testing.T) {
+	tests := []struct {
+		name    string
+		graph   *graph
+		leaders []int
+		members [][]int
+	}{
+		{
+			name:  "none",
+			graph: &graph{names: []string{"a", "b"}, calls: [][]int{{1}, {}}, nullable: []bool{false, false}},
+		},
+		{
+			name:    "direct",
+			graph:   &graph{names: []string{"a"}, calls: [][]int{{0}}, nullable: []bool{false}},
+			leaders: []int{0},
+			members: [][]int{{0}},
+		},
+		{
+			name: "indirect in grammar order",
+			graph: &graph{
+				names: []string{"a", "b", "c"}, calls: [][]int{{1}, {2}, {0}},
+				nullable: []bool{false, false, false},
+			},
+			leaders: []int{0},
+			members: [][]int{{0, 1, 2}},
+		},
+		{
+			name: "preferred",
+			graph: &graph{
+				names: []string{"a", "b"}, calls: [][]int{{1}, {0}},
+				nullable: []bool{false, false}, preferred: []bool{false, true},
+			},
+			leaders: []int{1},
+			members: [][]int{{0, 1}},
+		},
+		{
+			name: "through nullable",
+			graph: &graph{
+				names: []string{"a", "b"}, calls: [][]int{{1, 0}, {}},
+				nullable: []bool{false, true},
+			},
+			leaders: []int{0},
+			members: [][]int{{0}},
+		},
+		{
+			name: "common to all cycles",
+			graph: &graph{
+				names: []string{"a", "b", "c"}, calls: [][]int{{1, 2}, {0}, {0}},
+				nullable: []bool{false, true, false},
+			},
+			leaders: []int{0},
+			members: [][]int{{0, 1, 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups, err := ast.LeftRecursion(test.graph)
+			require.NoError(t, err)
+
+			leaders, members := []int{}, [][]int{}
+			for _, group := range groups {
+				leaders = append(leaders, group.Leader)
+				members = append(members, group.Members)
+			}
+			if test.leaders == nil {
+				test.leaders, test.members = []int{}, [][]int{}
+			}
+			assert.Equal(t, test.leaders, leaders)
+			assert.Equal(t, test.members, members)
+		})
+	}
+}
+
+func TestLeftRecursionNoLeader(t *testing.T) {
+	// Each of the three is avoided by one of the cycles through the others
+	g := &graph{
+		names: []string{"a", "b", "c"}, calls: [][]int{{1, 2}, {2, 0}, {0, 1}},
+		nullable: []bool{true, true, true},
+	}
+	_, err := ast.LeftRecursion(g)
+	require.Error(t, err)
+	assert.Equal(t, "left recursion without a rule common to all cycles: a, b, c", err.Error())
+}
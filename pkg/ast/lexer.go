@@ -1,65 +1,480 @@
 package ast
 
-import runtime "github.com/nu11ptr/parsegen/runtime/go"
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
 
 type LexerRule struct {
+	Span
+	// Grammar is the name of the grammar the rule was imported from (or the
+	// tokenVocab grammar) or empty if it was defined in this grammar
+	Grammar  string
 	Fragment bool
 	Name     string
-	Rules    LexerAlternatives
+	Mode     string // Empty for the default mode
+	Rules    *LexerAlternatives
+	Actions  []*LexerAction
+}
+
+func (l *LexerRule) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	if l.Fragment {
+		buff.WriteString(fmt.Sprintf("└──Fragment LexerRule: %s\n", l.Name))
+	} else {
+		buff.WriteString(fmt.Sprintf("└──LexerRule: %s\n", l.Name))
+	}
+	if l.Mode != "" {
+		buff.WriteString(strings.Repeat(" ", (indent+1)*spaces))
+		buff.WriteString(fmt.Sprintf("└──Mode: %s\n", l.Mode))
+	}
+	buff.WriteString(l.Rules.String(indent + 1))
+
+	if len(l.Actions) > 0 {
+		buff.WriteString(strings.Repeat(" ", (indent+1)*spaces))
+		buff.WriteString("└──Actions:\n")
+		for _, action := range l.Actions {
+			buff.WriteString(action.String(indent + 2))
+		}
+	}
+	return buff.String()
+}
+
+// Literal returns the quoted literal the rule matches if it is a non-fragment
+// rule consisting of nothing other than that literal (and possibly actions)
+func (l *LexerRule) Literal() (string, bool) {
+	if l.Fragment || len(l.Rules.Rules) != 1 || len(l.Rules.Rules[0]) != 1 {
+		return "", false
+	}
+	tok, ok := l.Rules.Rules[0][0].(*LexerToken)
+	if !ok {
+		return "", false
+	}
+	return tok.Token.Data, true
+}
+
+// HasAction returns true if the rule has an action of the given type
+func (l *LexerRule) HasAction(type_ LexerActionType) bool {
+	for _, action := range l.Actions {
+		if action.Type == type_ {
+			return true
+		}
+	}
+	return false
+}
+
+type LexerActionType int
+
+const (
+	SkipAction LexerActionType = iota
+	PushModeAction
+	PopModeAction
+	ChannelAction
+)
+
+type LexerAction struct {
+	Span
+	Type    LexerActionType
+	Mode    string // Only used by PushModeAction
+	Channel string // Only used by ChannelAction
+}
+
+func (l *LexerAction) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	switch l.Type {
+	case SkipAction:
+		buff.WriteString("└──Skip\n")
+	case PushModeAction:
+		buff.WriteString(fmt.Sprintf("└──PushMode: %s\n", l.Mode))
+	case PopModeAction:
+		buff.WriteString("└──PopMode\n")
+	case ChannelAction:
+		buff.WriteString(fmt.Sprintf("└──Channel: %s\n", l.Channel))
+	}
+	return buff.String()
 }
 
 type LexerNode interface {
 	LexerNode()
+	NodeSpan() Span
+	String(int) string
+}
+
+// NewLexerNestedNode negates a node if a tilde token is given and then wraps
+// it in a suffix node if a suffix token is given. Char classes are negated
+// directly, everything else is wrapped in a LexerNot. A question mark token
+// following the suffix makes it non-greedy
+func NewLexerNestedNode(node LexerNode, tilde, suffix, nonGreedy *runtime.Token) LexerNode {
+	span := node.NodeSpan()
+	if tilde != nil {
+		span.Start = TokenPos(tilde)
+		if class, ok := node.(*LexerCharClass); ok {
+			negated := *class
+			negated.Span = span
+			negated.Negated = !negated.Negated
+			node = &negated
+		} else {
+			node = &LexerNot{Span: span, Node: node}
+		}
+	}
+	if suffix == nil {
+		return node
+	}
+	span.End = tokenEnd(suffix)
+
+	if nonGreedy != nil {
+		span.End = tokenEnd(nonGreedy)
+		switch suffix.Type {
+		case token.PLUS:
+			return &LexerNonGreedyOneOrMore{Span: span, Node: node}
+		case token.STAR:
+			return &LexerNonGreedyZeroOrMore{Span: span, Node: node}
+		case token.QUEST_MARK:
+			return &LexerNonGreedyZeroOrOne{Span: span, Node: node}
+		default:
+			log.Panicf("Unknown token type: %d", suffix.Type)
+			return nil
+		}
+	}
+
+	switch suffix.Type {
+	case token.PLUS:
+		return &LexerOneOrMore{Span: span, Node: node}
+	case token.STAR:
+		return &LexerZeroOrMore{Span: span, Node: node}
+	case token.QUEST_MARK:
+		return &LexerZeroOrOne{Span: span, Node: node}
+	default:
+		log.Panicf("Unknown token type: %d", suffix.Type)
+		return nil
+	}
 }
 
 type LexerAlternatives struct {
-	Rules []LexerNode
+	Span
+	Rules [][]LexerNode
+}
+
+// NewLexerAlternatives creates alternatives spanning all their nodes
+func NewLexerAlternatives(alts [][]LexerNode) *LexerAlternatives {
+	span := Span{}
+	for _, alt := range alts {
+		for _, node := range alt {
+			span = span.Join(node.NodeSpan())
+		}
+	}
+	return &LexerAlternatives{Span: span, Rules: alts}
+}
+
+func (l *LexerAlternatives) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──Alternatives:\n")
+
+	for i, alt := range l.Rules {
+		buff.WriteString(strings.Repeat(" ", (indent+1)*spaces))
+		buff.WriteString(fmt.Sprintf("└──Alternative %d:\n", i))
+		for _, rule := range alt {
+			buff.WriteString(rule.String(indent + 2))
+		}
+	}
+	return buff.String()
 }
 
 func (l *LexerAlternatives) LexerNode() {}
 
 type LexerNot struct {
+	Span
 	Node LexerNode
 }
 
+func (l *LexerNot) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──Not:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
 func (l *LexerNot) LexerNode() {}
 
 type LexerZeroOrMore struct {
+	Span
 	Node LexerNode
 }
 
+func (l *LexerZeroOrMore) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──ZeroOrMore:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
 func (l *LexerZeroOrMore) LexerNode() {}
 
 type LexerOneOrMore struct {
+	Span
 	Node LexerNode
 }
 
+func (l *LexerOneOrMore) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──OneOrMore:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
 func (l *LexerOneOrMore) LexerNode() {}
 
 type LexerZeroOrOne struct {
+	Span
 	Node LexerNode
 }
 
+func (l *LexerZeroOrOne) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──ZeroOrOne:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
 func (l *LexerZeroOrOne) LexerNode() {}
 
+// LexerNonGreedyZeroOrMore is `x*?`, which matches as few of a node as
+// possible while still letting the rest of the rule match
+type LexerNonGreedyZeroOrMore struct {
+	Span
+	Node LexerNode
+}
+
+func (l *LexerNonGreedyZeroOrMore) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──NonGreedyZeroOrMore:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
+func (l *LexerNonGreedyZeroOrMore) LexerNode() {}
+
+// LexerNonGreedyOneOrMore is `x+?`, the non-greedy form of `x+`
+type LexerNonGreedyOneOrMore struct {
+	Span
+	Node LexerNode
+}
+
+func (l *LexerNonGreedyOneOrMore) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──NonGreedyOneOrMore:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
+func (l *LexerNonGreedyOneOrMore) LexerNode() {}
+
+// LexerNonGreedyZeroOrOne is `x??`, which only matches the node when the rest
+// of the rule can't match without it
+type LexerNonGreedyZeroOrOne struct {
+	Span
+	Node LexerNode
+}
+
+func (l *LexerNonGreedyZeroOrOne) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──NonGreedyZeroOrOne:\n")
+	buff.WriteString(l.Node.String(indent + 1))
+	return buff.String()
+}
+
+func (l *LexerNonGreedyZeroOrOne) LexerNode() {}
+
+// IsNonGreedy returns true if a node is a non-greedy suffix
+func IsNonGreedy(node LexerNode) bool {
+	switch node.(type) {
+	case *LexerNonGreedyZeroOrMore, *LexerNonGreedyOneOrMore, *LexerNonGreedyZeroOrOne:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasNonGreedy returns true if a sequence has a non-greedy element, either
+// directly, inside of a group or inside of a referenced rule (ex: a fragment)
+// that isn't recursive. Groups with a suffix are not searched as what follows
+// them isn't known
+func HasNonGreedy(seq []LexerNode, rules map[string]*LexerRule) bool {
+	for _, node := range seq {
+		if IsNonGreedy(node) || NonGreedyGroup(node, rules) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// NonGreedyGroup returns the alternatives of a group or of the rule a
+// reference refers to when they have a non-greedy element, otherwise nil. The
+// rest of the sequence must be matched as part of each alternative so that
+// the non-greedy element knows what follows it. References to recursive rules
+// are never returned, as there would be no end to it
+func NonGreedyGroup(node LexerNode, rules map[string]*LexerRule) *LexerAlternatives {
+	var alts *LexerAlternatives
+	switch n := node.(type) {
+	case *LexerAlternatives:
+		alts = n
+	case *LexerRuleRef:
+		rule, ok := rules[n.Name]
+		if !ok || reachesRule(rule.Rules, n.Name, rules, map[string]bool{}) {
+			return nil
+		}
+		alts = rule.Rules
+	default:
+		return nil
+	}
+
+	for _, alt := range alts.Rules {
+		if HasNonGreedy(alt, rules) {
+			return alts
+		}
+	}
+	return nil
+}
+
+// reachesRule returns true if a node references a rule, either directly or
+// through other rules
+func reachesRule(node LexerNode, name string, rules map[string]*LexerRule, seen map[string]bool) bool {
+	found := false
+	WalkLexerNode(node, func(node LexerNode) bool {
+		ref, ok := node.(*LexerRuleRef)
+		if !ok || found {
+			return !found
+		}
+		if ref.Name == name {
+			found = true
+		} else if rule, ok := rules[ref.Name]; ok && !seen[ref.Name] {
+			seen[ref.Name] = true
+			found = reachesRule(rule.Rules, name, rules, seen)
+		}
+		return false
+	})
+	return found
+}
+
 type LexerRuleRef struct {
+	Span
 	Name string
 }
 
+func (l *LexerRuleRef) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString(fmt.Sprintf("└──LexerRuleRef: %s\n", l.Name))
+	return buff.String()
+}
+
 func (l *LexerRuleRef) LexerNode() {}
 
 type LexerToken struct {
+	Span
 	Token *runtime.Token
 }
 
+func (l *LexerToken) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──Token Literal:\n")
+	if l.Token.Data != "" {
+		buff.WriteString(strings.Repeat(" ", (indent+1)*spaces))
+		buff.WriteString(fmt.Sprintf("└──Data: %s\n", l.Token.Data))
+	}
+	return buff.String()
+}
+
 func (l *LexerToken) LexerNode() {}
 
-type LexerAnyChar struct{}
+type LexerAnyChar struct {
+	Span
+}
+
+func (l *LexerAnyChar) String(indent int) string {
+	return strings.Repeat(" ", indent*spaces) + "└──AnyChar\n"
+}
 
 func (l *LexerAnyChar) LexerNode() {}
 
+// LexerCharClass is a bracketed set of chars, which can be negated with ~
 type LexerCharClass struct {
-	CharData string // TODO
+	Span
+	Negated bool
+	Set     CharSet
+	// Err is set when the class could not be decoded (ex: a reversed range).
+	// The set then only has the entries that could be decoded
+	Err error
+}
+
+// NewLexerCharClass creates a char class from the char tokens between the
+// brackets. Each entry is either a single char or the two ends of a range
+func NewLexerCharClass(chars [][]*runtime.Token) *LexerCharClass {
+	class := &LexerCharClass{}
+	ranges := make([]CharRange, 0, len(chars))
+
+	for _, entry := range chars {
+		var r CharRange
+		var err error
+		if r.Lo, err = decodeChar(entry[0]); err != nil {
+			class.Err = err
+			continue
+		}
+		r.Hi = r.Lo
+		if len(entry) > 1 {
+			if r.Hi, err = decodeChar(entry[1]); err != nil {
+				class.Err = err
+				continue
+			}
+			if r.Hi < r.Lo {
+				class.Err = fmt.Errorf("invalid char range: %s-%s", entry[0].Data, entry[1].Data)
+				continue
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	class.Set = NewCharSet(ranges...)
+	return class
+}
+
+// Chars returns the set of chars matched by the class, taking negation into
+// account
+func (l *LexerCharClass) Chars() CharSet {
+	if l.Negated {
+		return l.Set.Complement()
+	}
+	return l.Set
+}
+
+// Text returns the class in grammar syntax
+func (l *LexerCharClass) Text() string {
+	if l.Negated {
+		return "~[" + l.Set.String() + "]"
+	}
+	return "[" + l.Set.String() + "]"
+}
+
+func (l *LexerCharClass) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString(fmt.Sprintf("└──CharClass: %s\n", l.Text()))
+	return buff.String()
 }
 
 func (l *LexerCharClass) LexerNode() {}
@@ -0,0 +1,152 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Unquote decodes a single quoted grammar literal (including any escapes) into
+// the string it represents
+func Unquote(lit string) (string, error) {
+	if len(lit) < 2 || lit[0] != '\'' || lit[len(lit)-1] != '\'' {
+		return "", fmt.Errorf("invalid literal: %s", lit)
+	}
+	lit = lit[1 : len(lit)-1]
+
+	buff := strings.Builder{}
+	for i := 0; i < len(lit); i++ {
+		if lit[i] != '\\' {
+			buff.WriteByte(lit[i])
+			continue
+		}
+		i++
+		if i == len(lit) {
+			return "", fmt.Errorf("invalid trailing escape in literal: '%s'", lit)
+		}
+
+		switch lit[i] {
+		case 'n':
+			buff.WriteByte('\n')
+		case 'r':
+			buff.WriteByte('\r')
+		case 't':
+			buff.WriteByte('\t')
+		case 'b':
+			buff.WriteByte('\b')
+		case 'f':
+			buff.WriteByte('\f')
+		case 'u':
+			var hex string
+			if i+1 < len(lit) && lit[i+1] == '{' {
+				end := strings.IndexByte(lit[i:], '}')
+				if end < 0 {
+					return "", fmt.Errorf("unterminated unicode escape in literal: '%s'", lit)
+				}
+				hex, i = lit[i+2:i+end], i+end
+			} else {
+				if i+4 >= len(lit) {
+					return "", fmt.Errorf("short unicode escape in literal: '%s'", lit)
+				}
+				hex, i = lit[i+1:i+5], i+4
+			}
+			ch, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape in literal: '%s'", lit)
+			}
+			buff.WriteRune(rune(ch))
+		default:
+			buff.WriteByte(lit[i])
+		}
+	}
+	return buff.String(), nil
+}
+
+// Names for literal characters that have no lexer rule of their own
+var charNames = map[rune]string{
+	'!':  "BANG",
+	'"':  "DQUOTE",
+	'#':  "POUND",
+	'$':  "DOLLAR",
+	'%':  "PERCENT",
+	'&':  "AMP",
+	'\'': "QUOTE",
+	'(':  "LPAREN",
+	')':  "RPAREN",
+	'*':  "STAR",
+	'+':  "PLUS",
+	',':  "COMMA",
+	'-':  "DASH",
+	'.':  "DOT",
+	'/':  "SLASH",
+	':':  "COLON",
+	';':  "SEMI",
+	'<':  "LT",
+	'=':  "EQUALS",
+	'>':  "GT",
+	'?':  "QUEST_MARK",
+	'@':  "AT",
+	'[':  "LBRACK",
+	'\\': "BACKSLASH",
+	']':  "RBRACK",
+	'^':  "CARET",
+	'_':  "UNDERSCORE",
+	'`':  "BACKTICK",
+	'{':  "LBRACE",
+	'|':  "PIPE",
+	'}':  "RBRACE",
+	'~':  "TILDE",
+}
+
+// Names for multi character literals that read better than their parts
+var seqNames = map[string]string{
+	"->": "RARROW",
+	"<-": "LARROW",
+}
+
+// LiteralName derives a token name for an unquoted literal that has no lexer
+// rule of its own. Keywords are upper cased ("pushMode" becomes PUSH_MODE) and
+// punctuation is named character by character ("+=" becomes PLUS_EQUALS).
+// An empty string is returned if no sensible name can be derived
+func LiteralName(lit string) string {
+	if name, ok := seqNames[lit]; ok {
+		return name
+	}
+	if isIdent(lit) {
+		return upperSnake(lit)
+	}
+
+	parts := []string{}
+	for _, ch := range lit {
+		name, ok := charNames[ch]
+		if !ok {
+			return ""
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, "_")
+}
+
+func isIdent(str string) bool {
+	for i, ch := range str {
+		if ch != '_' && !unicode.IsLetter(ch) && (i == 0 || !unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return str != ""
+}
+
+// upperSnake converts a camel case name to upper snake case
+func upperSnake(name string) string {
+	buff := strings.Builder{}
+	prevLower := false
+	for _, ch := range name {
+		if unicode.IsUpper(ch) && prevLower {
+			buff.WriteRune('_')
+		}
+		prevLower = unicode.IsLower(ch) || unicode.IsDigit(ch)
+		buff.WriteRune(unicode.ToUpper(ch))
+	}
+	return buff.String()
+}
@@ -10,37 +10,82 @@ import (
 )
 
 type TopLevel struct {
+	Span
+	// Header is the grammar declaration or nil if there is none
+	Header  *GrammarHeader
+	Options []*Option
+	Imports []*Import
+
 	ParserRulesMap map[string]*ParserRule
 	LexerRulesMap  map[string]*LexerRule
 
 	ParserRules []*ParserRule
 	LexerRules  []*LexerRule
+
+	// Modes are the lexer modes declared by the grammar in order. The default
+	// mode is implied and not included
+	Modes []string
 }
 
-func NewTopLevel(rules []*ParserRule) *TopLevel {
+func NewTopLevel(parserRules []*ParserRule, lexerRules []*LexerRule) *TopLevel {
 	topLevel := &TopLevel{
 		ParserRulesMap: make(map[string]*ParserRule, 16),
 		LexerRulesMap:  make(map[string]*LexerRule, 16),
 	}
-	for _, rule := range rules {
-		topLevel.ParserRulesMap[rule.Name] = rule
-		topLevel.ParserRules = append(topLevel.ParserRules, rule)
+	for _, rule := range parserRules {
+		topLevel.addParserRule(rule)
+	}
+	for _, rule := range lexerRules {
+		topLevel.addLexerRule(rule)
 	}
 	return topLevel
 }
 
+// The maps hold the first definition of each rule. Check reports any
+// duplicates
+
+func (t *TopLevel) addParserRule(rule *ParserRule) {
+	if _, ok := t.ParserRulesMap[rule.Name]; !ok {
+		t.ParserRulesMap[rule.Name] = rule
+	}
+	t.ParserRules = append(t.ParserRules, rule)
+}
+
+func (t *TopLevel) addLexerRule(rule *LexerRule) {
+	if _, ok := t.LexerRulesMap[rule.Name]; !ok {
+		t.LexerRulesMap[rule.Name] = rule
+	}
+	t.LexerRules = append(t.LexerRules, rule)
+}
+
 func (t *TopLevel) String() string {
 	buff := strings.Builder{}
 	buff.WriteString("TopLevel:\n")
+	if t.Header != nil {
+		buff.WriteString(t.Header.String(1))
+	}
+	for _, opt := range t.Options {
+		buff.WriteString(opt.String(1))
+	}
+	for _, imp := range t.Imports {
+		buff.WriteString(imp.String(1))
+	}
 	for _, rule := range t.ParserRules {
 		buff.WriteString(rule.String(1))
 	}
+	for _, rule := range t.LexerRules {
+		buff.WriteString(rule.String(1))
+	}
 	return buff.String()
 }
 
 type ParserRule struct {
-	Name  string
-	Rules *ParserAlternatives
+	Span
+	// Grammar is the name of the grammar the rule was imported from or empty
+	// if it was defined in this grammar
+	Grammar string
+	Name    string
+	Rules   *ParserAlternatives
 }
 
 func (p *ParserRule) String(indent int) string {
@@ -53,6 +98,7 @@ func (p *ParserRule) String(indent int) string {
 
 type ParserNode interface {
 	ParserNode()
+	NodeSpan() Span
 	String(int) string
 }
 
@@ -60,13 +106,16 @@ func NewNestedNode(node ParserNode, suffix *runtime.Token) ParserNode {
 	if suffix == nil {
 		return node
 	}
+	span := node.NodeSpan()
+	span.End = tokenEnd(suffix)
+
 	switch suffix.Type {
 	case token.PLUS:
-		return &ParserOneOrMore{Node: node}
+		return &ParserOneOrMore{Span: span, Node: node}
 	case token.STAR:
-		return &ParserZeroOrMore{Node: node}
+		return &ParserZeroOrMore{Span: span, Node: node}
 	case token.QUEST_MARK:
-		return &ParserZeroOrOne{Node: node}
+		return &ParserZeroOrOne{Span: span, Node: node}
 	default:
 		log.Panicf("Unknown token type: %d", suffix.Type)
 		return nil
@@ -74,7 +123,42 @@ func NewNestedNode(node ParserNode, suffix *runtime.Token) ParserNode {
 }
 
 type ParserAlternatives struct {
+	Span
 	Rules [][]ParserNode
+	// Labels holds the `# Name` label of each alternative (nil when not
+	// labeled) or is nil when no alternative is labeled
+	Labels []*AltLabel
+}
+
+// NewParserAlternatives creates alternatives spanning all their nodes and
+// labels. labels may be nil or have a (possibly nil) label per alternative
+func NewParserAlternatives(alts [][]ParserNode, labels []*AltLabel) *ParserAlternatives {
+	span := Span{}
+	for i, alt := range alts {
+		for _, node := range alt {
+			span = span.Join(node.NodeSpan())
+		}
+		if i < len(labels) && labels[i] != nil {
+			span = span.Join(labels[i].Span)
+		}
+	}
+
+	var altLabels []*AltLabel
+	for _, label := range labels {
+		if label != nil {
+			altLabels = labels
+			break
+		}
+	}
+	return &ParserAlternatives{Span: span, Rules: alts, Labels: altLabels}
+}
+
+// Label returns the label of an alternative or an empty string if it has none
+func (p *ParserAlternatives) Label(alt int) string {
+	if alt < len(p.Labels) && p.Labels[alt] != nil {
+		return p.Labels[alt].Name
+	}
+	return ""
 }
 
 func (p *ParserAlternatives) String(indent int) string {
@@ -84,7 +168,11 @@ func (p *ParserAlternatives) String(indent int) string {
 
 	for i, alt := range p.Rules {
 		buff.WriteString(strings.Repeat(" ", (indent+1)*spaces))
-		buff.WriteString(fmt.Sprintf("└──Alternative %d:\n", i))
+		if label := p.Label(i); label != "" {
+			buff.WriteString(fmt.Sprintf("└──Alternative %d: # %s\n", i, label))
+		} else {
+			buff.WriteString(fmt.Sprintf("└──Alternative %d:\n", i))
+		}
 		for _, rule := range alt {
 			buff.WriteString(rule.String(indent + 2))
 		}
@@ -94,7 +182,101 @@ func (p *ParserAlternatives) String(indent int) string {
 
 func (p *ParserAlternatives) ParserNode() {}
 
+// AltLabel is the `# Name` label that ends an alternative
+type AltLabel struct {
+	Span
+	Name string
+}
+
+// ParserLabel is an element labeled with `name=` or, when List is set, with
+// `name+=` to collect every match into a list
+type ParserLabel struct {
+	Span
+	Name string
+	List bool
+	Node ParserNode
+}
+
+// NewLabeledNode applies a label parsed without a node to a node. The node is
+// returned as is when there is no label
+func NewLabeledNode(label *ParserLabel, node ParserNode) ParserNode {
+	if label == nil {
+		return node
+	}
+	// Copy so the memoized label is left untouched
+	labeled := *label
+	labeled.Span = labeled.Span.Join(node.NodeSpan())
+	labeled.Node = node
+	return &labeled
+}
+
+func (p *ParserLabel) String(indent int) string {
+	op := "="
+	if p.List {
+		op = "+="
+	}
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString(fmt.Sprintf("└──Label: %s%s\n", p.Name, op))
+	buff.WriteString(p.Node.String(indent + 1))
+	return buff.String()
+}
+
+func (p *ParserLabel) ParserNode() {}
+
+// NewPredicateNode creates an and-predicate (&) or not-predicate (!) from its
+// operator token
+func NewPredicateNode(op *runtime.Token, node ParserNode) ParserNode {
+	span := node.NodeSpan()
+	span.Start = TokenPos(op)
+
+	switch op.Type {
+	case token.AMP:
+		return &ParserAndPredicate{Span: span, Node: node}
+	case token.BANG:
+		return &ParserNotPredicate{Span: span, Node: node}
+	default:
+		log.Panicf("Unknown token type: %d", op.Type)
+		return nil
+	}
+}
+
+// ParserAndPredicate succeeds if its node matches, but never consumes any
+// tokens
+type ParserAndPredicate struct {
+	Span
+	Node ParserNode
+}
+
+func (p *ParserAndPredicate) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──AndPredicate:\n")
+	buff.WriteString(p.Node.String(indent + 1))
+	return buff.String()
+}
+
+func (p *ParserAndPredicate) ParserNode() {}
+
+// ParserNotPredicate succeeds if its node does not match, and never consumes
+// any tokens
+type ParserNotPredicate struct {
+	Span
+	Node ParserNode
+}
+
+func (p *ParserNotPredicate) String(indent int) string {
+	buff := strings.Builder{}
+	buff.WriteString(strings.Repeat(" ", indent*spaces))
+	buff.WriteString("└──NotPredicate:\n")
+	buff.WriteString(p.Node.String(indent + 1))
+	return buff.String()
+}
+
+func (p *ParserNotPredicate) ParserNode() {}
+
 type ParserZeroOrMore struct {
+	Span
 	Node ParserNode
 }
 
@@ -109,6 +291,7 @@ func (p *ParserZeroOrMore) String(indent int) string {
 func (p *ParserZeroOrMore) ParserNode() {}
 
 type ParserOneOrMore struct {
+	Span
 	Node ParserNode
 }
 
@@ -123,6 +306,7 @@ func (p *ParserOneOrMore) String(indent int) string {
 func (p *ParserOneOrMore) ParserNode() {}
 
 type ParserZeroOrOne struct {
+	Span
 	Node ParserNode
 }
 
@@ -137,6 +321,7 @@ func (p *ParserZeroOrOne) String(indent int) string {
 func (p *ParserZeroOrOne) ParserNode() {}
 
 type ParserRuleRef struct {
+	Span
 	Name string
 }
 
@@ -150,6 +335,7 @@ func (p *ParserRuleRef) String(indent int) string {
 func (p *ParserRuleRef) ParserNode() {}
 
 type ParserLexerRuleRef struct {
+	Span
 	Name string
 }
 
@@ -163,6 +349,7 @@ func (p *ParserLexerRuleRef) String(indent int) string {
 func (p *ParserLexerRuleRef) ParserNode() {}
 
 type ParserToken struct {
+	Span
 	Token *runtime.Token
 }
 
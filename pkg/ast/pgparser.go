@@ -13,6 +13,7 @@ func parseString(str string) string {
 }
 
 type Body struct {
+	Span
 	Parser     string
 	CodeBlocks *CodeBlocks
 }
@@ -36,6 +37,7 @@ func (b *Body) print(print *print) {
 }
 
 type CodeBlocks struct {
+	Span
 	Language string
 	Blocks   []*CodeBlock
 }
@@ -61,7 +63,49 @@ func (c *CodeBlocks) print(print *print) {
 	print.PopIndent()
 }
 
+// CodeBlockKind is what a code block is used for. The kind of a rule's block
+// is given by the suffix of its name (ex: "expr.pred")
+type CodeBlockKind int
+
+const (
+	// ActionBlock gives the result type and computes the result of a rule,
+	// sub-rule or alternative
+	ActionBlock CodeBlockKind = iota
+	// PredicateBlock is a Go boolean expression that must be true for a rule,
+	// sub-rule or alternative to be tried
+	PredicateBlock
+	// InitBlock runs when a rule or sub-rule is entered
+	InitBlock
+	// AfterBlock runs when a rule or sub-rule returns
+	AfterBlock
+	// MembersBlock adds fields and methods to the generated parser
+	MembersBlock
+)
+
+var blockSuffixes = map[string]CodeBlockKind{
+	"pred": PredicateBlock, "init": InitBlock, "after": AfterBlock,
+}
+
+func (c CodeBlockKind) String() string {
+	switch c {
+	case PredicateBlock:
+		return "pred"
+	case InitBlock:
+		return "init"
+	case AfterBlock:
+		return "after"
+	case MembersBlock:
+		return "members"
+	default:
+		return "action"
+	}
+}
+
 type CodeBlock struct {
+	Span
+	Kind CodeBlockKind
+	// Rule is the name of the rule, sub-rule or alternative the block is for
+	// without the kind suffix. It is empty for members
 	Rule string
 	Type string
 	Code string
@@ -74,7 +118,31 @@ func NewCodeBlock(rule string, type_ *runtime.Token, code string) *CodeBlock {
 		t = strings.TrimSpace(type_.Data[2:])
 	}
 	c := strings.TrimSpace(code[2 : len(code)-2])
-	return &CodeBlock{Rule: rule, Type: t, Code: c}
+	block := &CodeBlock{Rule: rule, Type: t, Code: c}
+
+	if i := strings.LastIndexByte(rule, '.'); i >= 0 {
+		if kind, ok := blockSuffixes[rule[i+1:]]; ok {
+			block.Kind, block.Rule = kind, rule[:i]
+		}
+	}
+	return block
+}
+
+// NewMembersBlock creates the block that adds members to the parser
+func NewMembersBlock(code string) *CodeBlock {
+	return &CodeBlock{Kind: MembersBlock, Code: strings.TrimSpace(code[2 : len(code)-2])}
+}
+
+// Name returns the name the block was declared with (ex: "expr.pred")
+func (c *CodeBlock) Name() string {
+	switch c.Kind {
+	case ActionBlock:
+		return c.Rule
+	case MembersBlock:
+		return "members"
+	default:
+		return c.Rule + "." + c.Kind.String()
+	}
 }
 
 func (c *CodeBlock) String() string {
@@ -86,7 +154,12 @@ func (c *CodeBlock) String() string {
 func (c *CodeBlock) print(print *print) {
 	print.WriteString("Code Block")
 	print.PushIndent()
-	print.WriteStringPair("Rule", c.Rule)
+	if c.Kind != ActionBlock {
+		print.WriteStringPair("Kind", c.Kind.String())
+	}
+	if c.Rule != "" {
+		print.WriteStringPair("Rule", c.Rule)
+	}
 	if c.Type != "" {
 		print.WriteStringPair("Type", c.Type)
 	}
@@ -0,0 +1,88 @@
+package ast
+
+import (
+	"fmt"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// Pos is a location in the grammar source. The zero value means the location
+// is unknown
+type Pos struct {
+	Offset   int // Byte offset from the start of the file
+	Row, Col int32
+}
+
+// TokenPos returns the location of the start of a token
+func TokenPos(tok *runtime.Token) Pos {
+	return Pos{Offset: tok.StartOffset, Row: tok.StartRow, Col: tok.StartCol}
+}
+
+// tokenEnd returns the location of the last char of a token
+func tokenEnd(tok *runtime.Token) Pos {
+	return Pos{Offset: tok.EndOffset, Row: tok.EndRow, Col: tok.EndCol}
+}
+
+// IsValid returns true if the location is known
+func (p Pos) IsValid() bool {
+	return p.Row > 0
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Row, p.Col)
+}
+
+// Span is the part of a grammar file a node was parsed from. Like a token, End
+// is the row/col of the last char of the node, but its offset is just past it
+// so that the source sliced from Start.Offset to End.Offset is the node text.
+// Nodes embed a Span so their location is available as node.Start/node.End
+type Span struct {
+	File       string
+	Start, End Pos
+}
+
+// TokenSpan returns the span from the start of the first token to the end of
+// the last one (which may be the same token)
+func TokenSpan(file string, first, last *runtime.Token) Span {
+	return Span{File: file, Start: TokenPos(first), End: tokenEnd(last)}
+}
+
+// FileSpan returns the span of a whole file given its EOF token
+func FileSpan(file string, eof *runtime.Token) Span {
+	return Span{File: file, Start: Pos{Row: 1, Col: 1}, End: tokenEnd(eof)}
+}
+
+// IsValid returns true if the span is known
+func (s Span) IsValid() bool {
+	return s.Start.IsValid()
+}
+
+// Join returns the span covering both spans. An unknown span is ignored
+func (s Span) Join(other Span) Span {
+	switch {
+	case !s.IsValid():
+		return other
+	case !other.IsValid():
+		return s
+	}
+	if other.Start.Offset < s.Start.Offset {
+		s.Start = other.Start
+	}
+	if other.End.Offset > s.End.Offset {
+		s.End = other.End
+	}
+	return s
+}
+
+// NodeSpan returns the span itself. It allows every node that embeds a Span to
+// report it through the ParserNode and LexerNode interfaces
+func (s Span) NodeSpan() Span {
+	return s
+}
+
+func (s Span) String() string {
+	if s.File == "" {
+		return fmt.Sprintf("%s-%s", s.Start, s.End)
+	}
+	return fmt.Sprintf("%s:%s-%s", s.File, s.Start, s.End)
+}
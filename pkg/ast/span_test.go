@@ -0,0 +1,76 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const spanGrammar = `a: b;
+
+b
+  : (C | 'd')+ EOF;
+
+fragment C: ~[a-z]* 'c' . -> skip;`
+
+func TestSpans(t *testing.T) {
+	p := runtime.NewParser(token.New(runtime.NewLexerFromString(spanGrammar)))
+	p.SetFilename("span.g4")
+	top, err := parser.New(p).Parse()
+	require.NoError(t, err)
+
+	text := func(span ast.Span) string {
+		assert.Equal(t, "span.g4", span.File)
+		return spanGrammar[span.Start.Offset:span.End.Offset]
+	}
+
+	assert.Equal(t, spanGrammar, text(top.Span))
+
+	a := top.ParserRules[0]
+	assert.Equal(t, "a: b;", text(a.Span))
+	assert.Equal(t, ast.Pos{Offset: 0, Row: 1, Col: 1}, a.Start)
+	assert.Equal(t, ast.Pos{Offset: 5, Row: 1, Col: 5}, a.End)
+	assert.Equal(t, "b", text(a.Rules.Rules[0][0].NodeSpan()))
+
+	b := top.ParserRules[1]
+	assert.Equal(t, "b\n  : (C | 'd')+ EOF;", text(b.Span))
+	assert.Equal(t, "(C | 'd')+ EOF", text(b.Rules.Span))
+	plus := b.Rules.Rules[0][0].(*ast.ParserOneOrMore)
+	assert.Equal(t, "(C | 'd')+", text(plus.Span))
+	assert.Equal(t, "(C | 'd')", text(plus.Node.NodeSpan()))
+	alts := plus.Node.(*ast.ParserAlternatives)
+	assert.Equal(t, "C", text(alts.Rules[0][0].NodeSpan()))
+	assert.Equal(t, "'d'", text(alts.Rules[1][0].NodeSpan()))
+	assert.Equal(t, ast.Pos{Offset: 18, Row: 4, Col: 10}, alts.Rules[1][0].NodeSpan().Start)
+	assert.Equal(t, "EOF", text(b.Rules.Rules[0][1].NodeSpan()))
+
+	c := top.LexerRules[0]
+	assert.Equal(t, "fragment C: ~[a-z]* 'c' . -> skip;", text(c.Span))
+	assert.Equal(t, "~[a-z]* 'c' .", text(c.Rules.Span))
+	assert.Equal(t, "~[a-z]*", text(c.Rules.Rules[0][0].NodeSpan()))
+	assert.Equal(t, "~[a-z]", text(c.Rules.Rules[0][0].(*ast.LexerZeroOrMore).Node.NodeSpan()))
+	assert.Equal(t, "'c'", text(c.Rules.Rules[0][1].NodeSpan()))
+	assert.Equal(t, ".", text(c.Rules.Rules[0][2].NodeSpan()))
+	assert.Equal(t, "skip", text(c.Actions[0].Span))
+}
+
+func TestSpanJoin(t *testing.T) {
+	first := ast.Span{Start: ast.Pos{Offset: 0, Row: 1, Col: 1}, End: ast.Pos{Offset: 2, Row: 1, Col: 2}}
+	second := ast.Span{Start: ast.Pos{Offset: 4, Row: 2, Col: 1}, End: ast.Pos{Offset: 6, Row: 2, Col: 2}}
+
+	joined := ast.Span{Start: first.Start, End: second.End}
+	assert.Equal(t, joined, first.Join(second))
+	assert.Equal(t, joined, second.Join(first))
+	assert.Equal(t, first, first.Join(ast.Span{}))
+	assert.Equal(t, first, ast.Span{}.Join(first))
+
+	assert.False(t, ast.Span{}.IsValid())
+	assert.Equal(t, "1:1-2:2", joined.String())
+	joined.File = "a.g4"
+	assert.Equal(t, "a.g4:1:1-2:2", joined.String())
+}
@@ -0,0 +1,447 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// *** Matching ***
+
+// negation is what a `~` matches: any char not in a set, or any char the
+// input doesn't start a literal of more than one char at
+type negation struct {
+	set    CharSet
+	unless string
+}
+
+// LexerMatcher matches lexer rules against the input of a lexer the same way
+// generated tokenizers do: alternatives are tried in order, loops match as
+// many times as possible without ever giving any back, and non-greedy
+// elements match as little as possible before what follows them. Each rule
+// must be added before it is matched
+type LexerMatcher struct {
+	rules map[string]*LexerRule
+
+	// Decoded literals and negations of the rules added
+	lits map[*LexerToken]string
+	nots map[*LexerNot]negation
+	// Groups and rule references with a non-greedy element and their
+	// alternatives. See NonGreedyGroup
+	groups map[LexerNode]*LexerAlternatives
+}
+
+// NewLexerMatcher creates a matcher for lexer rules, which are referenced by
+// name from the given map
+func NewLexerMatcher(rules map[string]*LexerRule) *LexerMatcher {
+	return &LexerMatcher{
+		rules:  rules,
+		lits:   make(map[*LexerToken]string, 16),
+		nots:   make(map[*LexerNot]negation, 4),
+		groups: make(map[LexerNode]*LexerAlternatives, 4),
+	}
+}
+
+// AddRule checks the nodes of a rule so it can be matched, decoding its
+// literals and negations and finding its non-greedy groups along the way
+func (m *LexerMatcher) AddRule(rule *LexerRule) error {
+	var err error
+	WalkLexerNode(rule.Rules, func(node LexerNode) bool {
+		switch n := node.(type) {
+		case *LexerToken:
+			var lit string
+			if lit, err = Unquote(n.Token.Data); err == nil && lit == "" {
+				err = errors.New("empty literal")
+			}
+			m.lits[n] = lit
+		case *LexerCharClass:
+			err = n.Err
+		case *LexerRuleRef:
+			if _, ok := m.rules[n.Name]; !ok {
+				err = fmt.Errorf("undefined lexer rule: %s", n.Name)
+			}
+		case *LexerNot:
+			err = m.addNot(n)
+		}
+		if alts := NonGreedyGroup(node, m.rules); alts != nil {
+			m.groups[node] = alts
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (m *LexerMatcher) addNot(n *LexerNot) error {
+	if tok, ok := n.Node.(*LexerToken); ok {
+		lit, err := Unquote(tok.Token.Data)
+		if err != nil {
+			return err
+		}
+		if utf8.RuneCountInString(lit) > 1 {
+			m.nots[n] = negation{unless: lit}
+			return nil
+		}
+	}
+	set, err := CharSetOf(n.Node, m.rules)
+	if err != nil {
+		return fmt.Errorf("unable to negate: %w", err)
+	}
+	m.nots[n] = negation{set: set}
+	return nil
+}
+
+// Match matches a rule at the current position of a lexer. Nothing is
+// consumed when it doesn't match
+func (m *LexerMatcher) Match(lex *runtime.Lexer, rule *LexerRule) bool {
+	return (&lexMatch{m: m, lex: lex}).alts(rule.Rules.Rules)
+}
+
+// MatchesAll returns true if a rule matches all of a string
+func (m *LexerMatcher) MatchesAll(rule *LexerRule, str string) bool {
+	lex := runtime.NewLexerFromString(str)
+	return m.Match(lex, rule) && lex.CurrChar() == runtime.EOFChar
+}
+
+// lexMatch is a match of lexer rules against the input of one lexer
+type lexMatch struct {
+	m   *LexerMatcher
+	lex *runtime.Lexer
+}
+
+// alts matches the first alternative that matches
+func (l *lexMatch) alts(alts [][]LexerNode) bool {
+	for _, alt := range alts {
+		if l.seq(alt) {
+			return true
+		}
+	}
+	return false
+}
+
+// seq matches a sequence, giving back anything matched when it fails
+func (l *lexMatch) seq(seq []LexerNode) bool {
+	pos := l.lex.Pos()
+	fail := func() bool {
+		l.lex.SetPos(pos)
+		return false
+	}
+
+	for n, node := range seq {
+		rest := seq[n+1:]
+		if IsNonGreedy(node) {
+			return l.nonGreedy(node, rest) || fail()
+		}
+		// The rest is matched as part of each alternative so that the
+		// non-greedy element knows what follows it
+		if alts, ok := l.m.groups[node]; ok {
+			for _, alt := range alts.Rules {
+				if l.seq(append(append([]LexerNode{}, alt...), rest...)) {
+					return true
+				}
+			}
+			return fail()
+		}
+
+		switch n := node.(type) {
+		case *LexerZeroOrOne:
+			l.node(n.Node)
+		case *LexerZeroOrMore:
+			l.loop(n.Node)
+		case *LexerOneOrMore:
+			if !l.node(n.Node) {
+				return fail()
+			}
+			l.loop(n.Node)
+		default:
+			if !l.node(node) {
+				return fail()
+			}
+		}
+	}
+	return true
+}
+
+// loop matches a node as many times as possible
+func (l *lexMatch) loop(node LexerNode) {
+	for {
+		// Stop as soon as no progress is made, otherwise this would never end
+		start := l.lex.Offset()
+		if !l.node(node) || l.lex.Offset() == start {
+			return
+		}
+	}
+}
+
+// nonGreedy matches a non-greedy node followed by the rest of its sequence.
+// The rest is tried before each further match of the node, so the node
+// matches as little as possible
+func (l *lexMatch) nonGreedy(node LexerNode, rest []LexerNode) bool {
+	var inner LexerNode
+	switch n := node.(type) {
+	case *LexerNonGreedyZeroOrMore:
+		inner = n.Node
+	case *LexerNonGreedyOneOrMore:
+		inner = n.Node
+		if !l.node(inner) {
+			return false
+		}
+	case *LexerNonGreedyZeroOrOne:
+		if len(rest) == 0 {
+			return true
+		}
+		return l.seq(rest) || l.node(n.Node) && l.seq(rest)
+	}
+	if len(rest) == 0 {
+		return true
+	}
+
+	for !l.seq(rest) {
+		start := l.lex.Offset()
+		if !l.node(inner) || l.lex.Offset() == start {
+			return false
+		}
+	}
+	return true
+}
+
+// node matches a single occurrence of a node. It never consumes input when it
+// doesn't match
+func (l *lexMatch) node(node LexerNode) bool {
+	switch n := node.(type) {
+	case *LexerToken:
+		lit := l.m.lits[n]
+		if ch, size := utf8.DecodeRuneInString(lit); size == len(lit) {
+			return l.lex.MatchChar(ch)
+		}
+		return l.lex.MatchSeq(lit)
+	case *LexerCharClass:
+		ch := l.lex.CurrChar()
+		if ch == runtime.EOFChar || n.Set.Contains(ch) == n.Negated {
+			return false
+		}
+		l.lex.NextChar()
+		return true
+	case *LexerAnyChar:
+		return l.lex.MatchAnyChar()
+	case *LexerNot:
+		not := l.m.nots[n]
+		if not.unless != "" {
+			return l.lex.MatchCharUnlessSeq(not.unless)
+		}
+		ch := l.lex.CurrChar()
+		if ch == runtime.EOFChar || not.set.Contains(ch) {
+			return false
+		}
+		l.lex.NextChar()
+		return true
+	case *LexerRuleRef:
+		return l.alts(l.m.rules[n.Name].Rules.Rules)
+	case *LexerAlternatives:
+		return l.alts(n.Rules)
+	default:
+		return l.seq([]LexerNode{node})
+	}
+}
+
+// *** Keywords ***
+
+// defaultMode is the name of the mode lexer rules are in unless declared
+// otherwise
+const defaultMode = "DEFAULT_MODE"
+
+func ruleMode(rule *LexerRule) string {
+	if rule.Mode == "" {
+		return defaultMode
+	}
+	return rule.Mode
+}
+
+// Keywords finds the literal rules of the tokens given (in order of priority)
+// that are also matched by another rule in the same mode (ex: 'if' and an
+// identifier rule). Tokenizers match the other rule and look its text up in
+// a keyword map instead of matching keywords themselves, so a literal is only
+// a keyword if the other rule matches all of it when matched like any token.
+// Literals with actions are left to compete with the other rules as the
+// keyword lookup has no way to run the actions. It returns the keyword rules
+// and the rules matching at least one keyword
+func (m *LexerMatcher) Keywords(tokens []*LexerRule) (keywords, hasKeys map[*LexerRule]bool) {
+	keywords, hasKeys = map[*LexerRule]bool{}, map[*LexerRule]bool{}
+	for _, tok := range tokens {
+		quoted, ok := tok.Literal()
+		if !ok || len(tok.Actions) > 0 {
+			continue
+		}
+		lit, err := Unquote(quoted)
+		if err != nil {
+			continue
+		}
+
+		for _, other := range tokens {
+			if _, ok := other.Literal(); ok || other.HasAction(SkipAction) || ruleMode(other) != ruleMode(tok) {
+				continue
+			}
+			if m.MatchesAll(other, lit) {
+				keywords[tok], hasKeys[other] = true, true
+			}
+		}
+	}
+	return keywords, hasKeys
+}
+
+// *** Implicit tokens ***
+
+// ImplicitTokens returns rules for the literals used by parser rules that
+// aren't matched by a lexer rule of their own, in order of first use. Each is
+// named after its literal (see LiteralName) and located at the literal that
+// caused it
+func (t *TopLevel) ImplicitTokens() ([]*LexerRule, error) {
+	literals := make(map[string]bool, 16)
+	for _, rule := range t.LexerRules {
+		if lit, ok := rule.Literal(); ok && !rule.Fragment {
+			literals[lit] = true
+		}
+	}
+
+	implicit := []*LexerRule{}
+	names := make(map[string]string, 16)
+	newToken := func(n *ParserToken) error {
+		lit, err := Unquote(n.Token.Data)
+		if err != nil {
+			return err
+		}
+		name := LiteralName(lit)
+		if name == "" {
+			return fmt.Errorf("unable to name token for literal: %s", n.Token.Data)
+		}
+		if _, ok := t.LexerRulesMap[name]; ok {
+			return fmt.Errorf("implicit token for literal %s conflicts with lexer rule: %s",
+				n.Token.Data, name)
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("implicit tokens for literals %s and %s have the same name: %s",
+				other, n.Token.Data, name)
+		}
+		names[name] = n.Token.Data
+
+		tok := &LexerToken{Span: n.Span, Token: n.Token}
+		implicit = append(implicit, &LexerRule{
+			Span: n.Span, Name: name, Rules: NewLexerAlternatives([][]LexerNode{{tok}}),
+		})
+		return nil
+	}
+
+	for _, rule := range t.ParserRules {
+		var err error
+		WalkParserNode(rule.Rules, func(node ParserNode) bool {
+			if n, ok := node.(*ParserToken); ok && err == nil && !literals[n.Token.Data] {
+				literals[n.Token.Data] = true
+				err = newToken(n)
+			}
+			return err == nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+	}
+	return implicit, nil
+}
+
+// *** Char sets ***
+
+// CharSetOf returns the set of chars matched by a node that always matches
+// exactly one char (ex: the node of a `~`)
+func CharSetOf(node LexerNode, rules map[string]*LexerRule) (CharSet, error) {
+	return charSetOf(node, rules, 0)
+}
+
+func charSetOf(node LexerNode, rules map[string]*LexerRule, depth int) (CharSet, error) {
+	if depth > 32 {
+		return nil, errors.New("recursive rule can not be used as a char set")
+	}
+
+	switch n := node.(type) {
+	case *LexerCharClass:
+		return n.Chars(), n.Err
+	case *LexerToken:
+		lit, err := Unquote(n.Token.Data)
+		if err != nil {
+			return nil, err
+		}
+		if utf8.RuneCountInString(lit) != 1 {
+			return nil, fmt.Errorf("literal is not a single char: %s", n.Token.Data)
+		}
+		ch, _ := utf8.DecodeRuneInString(lit)
+		return CharSet{{Lo: ch, Hi: ch}}, nil
+	case *LexerRuleRef:
+		rule, ok := rules[n.Name]
+		if !ok {
+			return nil, fmt.Errorf("undefined lexer rule: %s", n.Name)
+		}
+		return charSetOf(rule.Rules, rules, depth+1)
+	case *LexerAlternatives:
+		set := CharSet{}
+		for _, alt := range n.Rules {
+			if len(alt) != 1 {
+				return nil, errors.New("sequence can not be used as a char set")
+			}
+			altSet, err := charSetOf(alt[0], rules, depth)
+			if err != nil {
+				return nil, err
+			}
+			set = set.Union(altSet)
+		}
+		return set, nil
+	default:
+		return nil, fmt.Errorf("not a char set: %s", LexerNodeText(node))
+	}
+}
+
+// *** Grammar text ***
+
+// LexerNodeText returns the grammar text of a lexer node
+func LexerNodeText(node LexerNode) string {
+	switch n := node.(type) {
+	case *LexerAlternatives:
+		return "(" + LexerAltsText(n.Rules) + ")"
+	case *LexerZeroOrMore:
+		return LexerNodeText(n.Node) + "*"
+	case *LexerOneOrMore:
+		return LexerNodeText(n.Node) + "+"
+	case *LexerZeroOrOne:
+		return LexerNodeText(n.Node) + "?"
+	case *LexerNonGreedyZeroOrMore:
+		return LexerNodeText(n.Node) + "*?"
+	case *LexerNonGreedyOneOrMore:
+		return LexerNodeText(n.Node) + "+?"
+	case *LexerNonGreedyZeroOrOne:
+		return LexerNodeText(n.Node) + "??"
+	case *LexerNot:
+		return "~" + LexerNodeText(n.Node)
+	case *LexerRuleRef:
+		return n.Name
+	case *LexerToken:
+		return n.Token.Data
+	case *LexerAnyChar:
+		return "."
+	case *LexerCharClass:
+		return n.Text()
+	default:
+		return fmt.Sprintf("%T", node)
+	}
+}
+
+// LexerAltsText returns the grammar text of lexer alternatives
+func LexerAltsText(alts [][]LexerNode) string {
+	texts := make([]string, 0, len(alts))
+	for _, alt := range alts {
+		nodes := make([]string, 0, len(alt))
+		for _, node := range alt {
+			nodes = append(nodes, LexerNodeText(node))
+		}
+		texts = append(texts, strings.Join(nodes, " "))
+	}
+	return strings.Join(texts, " | ")
+}
@@ -0,0 +1,127 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMatcher(t *testing.T, grammar string) (*ast.TopLevel, *ast.LexerMatcher) {
+	lex := runtime.NewLexerFromString(grammar)
+	top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+
+	m := ast.NewLexerMatcher(top.LexerRulesMap)
+	for _, rule := range top.LexerRules {
+		require.NoError(t, m.AddRule(rule))
+	}
+	return top, m
+}
+
+func TestLexerMatcher(t *testing.T) {
+	top, m := newMatcher(t, "A: [a-z]* 'x';\nB: 'b' ~'b'+;\nC: '<' .*? '>';\nD: 'd' E 'y';\nfragment E: 'e'+? 'e';")
+	rules := top.LexerRulesMap
+
+	tests := []struct {
+		rule, input string
+		matches     bool
+	}{
+		// Loops never give back what they matched
+		{"A", "ax", false},
+		{"A", "x", false},
+		{"B", "bac", true},
+		{"B", "bb", false},
+		{"C", "<a>b>", false},
+		{"C", "<a>", true},
+		// Non-greedy elements of fragments know what follows them
+		{"D", "deey", true},
+		{"D", "dey", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.matches, m.MatchesAll(rules[test.rule], test.input), "%s %q", test.rule, test.input)
+	}
+
+	lex := runtime.NewLexerFromString("<a>b>")
+	require.True(t, m.Match(lex, rules["C"]))
+	assert.Equal(t, 3, lex.Offset())
+	assert.False(t, m.Match(lex, rules["A"]))
+	assert.Equal(t, 3, lex.Offset())
+}
+
+func TestLexerMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name, grammar, err string
+	}{
+		{"undefined rule", "A: B;", "undefined lexer rule: B"},
+		{"bad negation", "A: ~('a'*);", "unable to negate: not a char set: 'a'*"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := runtime.NewLexerFromString(test.grammar)
+			top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+			require.NoError(t, err)
+
+			err = ast.NewLexerMatcher(top.LexerRulesMap).AddRule(top.LexerRules[0])
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	top, m := newMatcher(t, "KW: 'ifx';\nIF: 'if';\nELSE: 'else' -> pushMode(X);\nID: [a-z]* 'x';\n"+
+		"WS: [a-z]+ -> skip;\nWORD: [a-z]+;\nmode X;\nX: [a-z]+;")
+	rules := top.LexerRulesMap
+
+	keywords, hasKeys := m.Keywords(top.LexerRules)
+	// ID never matches all of "ifx" as [a-z]* takes the x, skipped rules and
+	// rules of other modes are never looked up and ELSE has an action to run
+	assert.Equal(t, map[*ast.LexerRule]bool{rules["KW"]: true, rules["IF"]: true}, keywords)
+	assert.Equal(t, map[*ast.LexerRule]bool{rules["WORD"]: true}, hasKeys)
+}
+
+func TestImplicitTokens(t *testing.T) {
+	lex := runtime.NewLexerFromString("a: '[' NAME (',' NAME)* ']' 'end' ',' '->' ';';\n\nNAME: [a-z]+;\nSEMI: ';';")
+	top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+
+	rules, err := top.ImplicitTokens()
+	require.NoError(t, err)
+	names := []string{}
+	for _, rule := range rules {
+		lit, ok := rule.Literal()
+		require.True(t, ok)
+		names = append(names, rule.Name+" "+lit)
+	}
+	assert.Equal(t, []string{"LBRACK '['", "COMMA ','", "RBRACK ']'", "END 'end'", "RARROW '->'"}, names)
+	// Each is located at the literal that caused it
+	assert.Equal(t, "1:4", rules[0].Start.String())
+}
+
+func TestImplicitTokensErrors(t *testing.T) {
+	tests := []struct {
+		name, grammar, err string
+	}{
+		{"unnamed", "a: '€';\n\nB: 'b';", "a: unable to name token for literal: '€'"},
+		{"lexer rule", "a: 'end';\n\nEND: 'e' 'nd';", "a: implicit token for literal 'end' conflicts with lexer rule: END"},
+		{"same name", "a: 'end' b;\nb: 'End';\n\nB: 'b';", "b: implicit tokens for literals 'end' and 'End' have the same name: END"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := runtime.NewLexerFromString(test.grammar)
+			top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+			require.NoError(t, err)
+
+			_, err = top.ImplicitTokens()
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
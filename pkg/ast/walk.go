@@ -0,0 +1,63 @@
+package ast
+
+// WalkParserNode calls fn for a parser node and then each of the nodes below
+// it in depth first order. The nodes below a node are skipped when fn returns
+// false for it. Rule references are not followed
+func WalkParserNode(node ParserNode, fn func(ParserNode) bool) {
+	if !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *ParserAlternatives:
+		for _, alt := range n.Rules {
+			for _, node := range alt {
+				WalkParserNode(node, fn)
+			}
+		}
+	case *ParserZeroOrMore:
+		WalkParserNode(n.Node, fn)
+	case *ParserOneOrMore:
+		WalkParserNode(n.Node, fn)
+	case *ParserZeroOrOne:
+		WalkParserNode(n.Node, fn)
+	case *ParserLabel:
+		WalkParserNode(n.Node, fn)
+	case *ParserAndPredicate:
+		WalkParserNode(n.Node, fn)
+	case *ParserNotPredicate:
+		WalkParserNode(n.Node, fn)
+	}
+}
+
+// WalkLexerNode calls fn for a lexer node and then each of the nodes below it
+// in depth first order. The nodes below a node are skipped when fn returns
+// false for it. Rule references are not followed
+func WalkLexerNode(node LexerNode, fn func(LexerNode) bool) {
+	if !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *LexerAlternatives:
+		for _, alt := range n.Rules {
+			for _, node := range alt {
+				WalkLexerNode(node, fn)
+			}
+		}
+	case *LexerNot:
+		WalkLexerNode(n.Node, fn)
+	case *LexerZeroOrMore:
+		WalkLexerNode(n.Node, fn)
+	case *LexerOneOrMore:
+		WalkLexerNode(n.Node, fn)
+	case *LexerZeroOrOne:
+		WalkLexerNode(n.Node, fn)
+	case *LexerNonGreedyZeroOrMore:
+		WalkLexerNode(n.Node, fn)
+	case *LexerNonGreedyOneOrMore:
+		WalkLexerNode(n.Node, fn)
+	case *LexerNonGreedyZeroOrOne:
+		WalkLexerNode(n.Node, fn)
+	}
+}
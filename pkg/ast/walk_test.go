@@ -0,0 +1,43 @@
+package ast_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	lex := runtime.NewLexerFromString("a: x=b (',' &C)* | !D+;\nB: ~'x' ('y'*? | [z])+;")
+	top, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+
+	nodes := []string{}
+	ast.WalkParserNode(top.ParserRules[0].Rules, func(node ast.ParserNode) bool {
+		nodes = append(nodes, fmt.Sprintf("%T", node))
+		// Skips what is below the not-predicate
+		_, ok := node.(*ast.ParserNotPredicate)
+		return !ok
+	})
+	assert.Equal(t, []string{
+		"*ast.ParserAlternatives", "*ast.ParserLabel", "*ast.ParserRuleRef", "*ast.ParserZeroOrMore",
+		"*ast.ParserAlternatives", "*ast.ParserToken", "*ast.ParserAndPredicate",
+		"*ast.ParserLexerRuleRef", "*ast.ParserNotPredicate",
+	}, nodes)
+
+	nodes = []string{}
+	ast.WalkLexerNode(top.LexerRules[0].Rules, func(node ast.LexerNode) bool {
+		nodes = append(nodes, fmt.Sprintf("%T", node))
+		return true
+	})
+	assert.Equal(t, []string{
+		"*ast.LexerAlternatives", "*ast.LexerNot", "*ast.LexerToken", "*ast.LexerOneOrMore",
+		"*ast.LexerAlternatives", "*ast.LexerNonGreedyZeroOrMore", "*ast.LexerToken",
+		"*ast.LexerCharClass",
+	}, nodes)
+}
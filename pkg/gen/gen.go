@@ -0,0 +1,103 @@
+// Package gen generates Go source code for parsers and tokenizers from a
+// parsed grammar
+package gen
+
+import (
+	"fmt"
+	"go/format"
+	"path"
+	"strings"
+)
+
+const runtimeImport = "github.com/nu11ptr/parsegen/runtime/go"
+
+// Options controls details of the generated Go code
+type Options struct {
+	// Package is the name of the Go package the code is generated into
+	Package string
+	// TokenImport is the import path of the package containing the token type
+	// constants. If empty, they are expected to be in the generated package
+	TokenImport string
+	// Imports are any additional import paths required by the code blocks
+	Imports []string
+	// Combined is true when the tokenizer and parser are generated into the
+	// same package. Their constructors are then named NewTokenizer and
+	// NewParser instead of New so they don't collide
+	Combined bool
+	// Lossless keeps the text of every token. Skipped tokens get token types
+	// and are kept as trivia of the token after them, and parsers with
+	// generated result types get a ParseCST method returning a concrete syntax
+	// tree that covers the whole input
+	Lossless bool
+}
+
+func (o *Options) constructor(suffix string) string {
+	if o.Combined {
+		return "New" + suffix
+	}
+	return "New"
+}
+
+func (o *Options) tokenPrefix() string {
+	if o.TokenImport == "" {
+		return ""
+	}
+	return path.Base(o.TokenImport) + "."
+}
+
+// writer accumulates generated source code line by line
+type writer struct {
+	buff  strings.Builder
+	depth int
+}
+
+func (w *writer) Line(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	if strings.HasPrefix(line, "}") && w.depth > 0 {
+		w.depth--
+	}
+	// gofmt rewrites unindented comments like doc comments (ex: '' becomes a
+	// curly quote), so comments are indented to their block depth up front
+	if strings.HasPrefix(line, "//") {
+		w.buff.WriteString(strings.Repeat("\t", w.depth))
+	}
+	w.buff.WriteString(line)
+	w.buff.WriteByte('\n')
+	if strings.HasSuffix(line, "{") {
+		w.depth++
+	}
+}
+
+func (w *writer) Blank() {
+	w.buff.WriteByte('\n')
+}
+
+func (w *writer) Header(source, pkg string, imports []string) {
+	if source != "" {
+		w.Line("// Code generated by parsegen from %s. DO NOT EDIT.", source)
+	} else {
+		w.Line("// Code generated by parsegen. DO NOT EDIT.")
+	}
+	w.Blank()
+	w.Line("package %s", pkg)
+	w.Blank()
+	w.Line("import (")
+	for _, imp := range imports {
+		if imp == runtimeImport {
+			w.Line("runtime %q", imp)
+		} else {
+			w.Line("%q", imp)
+		}
+	}
+	w.Line(")")
+}
+
+// Format runs the accumulated source through gofmt
+func (w *writer) Format() ([]byte, error) {
+	src := []byte(w.buff.String())
+	out, err := format.Source(src)
+	if err != nil {
+		return src, fmt.Errorf("unable to format generated code: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package gen
+
+import "github.com/nu11ptr/parsegen/pkg/ast"
+
+// *** Left recursion ***
+
+// analyzeLeftRecursion finds the units that can match nothing and the leader
+// of each left recursive group of units. See ast.LeftRecursion
+func (g *parserGen) analyzeLeftRecursion() error {
+	graph := &unitGraph{units: g.units, index: make(map[*unit]int, len(g.units))}
+	for i, u := range g.units {
+		graph.index[u] = i
+	}
+
+	for i, nullable := range ast.Nullable(graph) {
+		g.units[i].nullable = nullable
+	}
+
+	groups, err := ast.LeftRecursion(graph)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		for _, i := range group.Members {
+			g.units[i].unmemoized = i != group.Leader
+		}
+		g.units[group.Leader].leader = true
+	}
+	return nil
+}
+
+// unitGraph is the call graph of the units of a parser
+type unitGraph struct {
+	units []*unit
+	index map[*unit]int
+}
+
+func (g *unitGraph) Len() int {
+	return len(g.units)
+}
+
+func (g *unitGraph) Name(fn int) string {
+	return g.units[fn].name
+}
+
+func (g *unitGraph) Nullable(fn int, nullable []bool) bool {
+	for _, seq := range g.units[fn].seqs {
+		if g.nullableSeq(seq, nullable) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *unitGraph) nullableSeq(seq []*element, nullable []bool) bool {
+	for _, elem := range seq {
+		if !g.nullableElem(elem, nullable) {
+			return false
+		}
+	}
+	return true
+}
+
+// Predicates can fail, but never consume any tokens, so what follows them is
+// still called at the same position
+func (g *unitGraph) nullableElem(elem *element, nullable []bool) bool {
+	return elem.pred != 0 || !elem.canFail() || (elem.callee != nil && nullable[g.index[elem.callee]])
+}
+
+func (g *unitGraph) LeftCalls(fn int, nullable []bool) []int {
+	callees := []int{}
+	for _, seq := range g.units[fn].seqs {
+		for _, elem := range seq {
+			if elem.callee != nil {
+				callees = append(callees, g.index[elem.callee])
+			}
+			if !g.nullableElem(elem, nullable) {
+				break
+			}
+		}
+	}
+	return callees
+}
+
+// Preferred makes parser rules leaders over their sub-rules for readability
+func (g *unitGraph) Preferred(fn int) bool {
+	return !g.units[fn].sub
+}
@@ -0,0 +1,1061 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+)
+
+// lexToken is a lexer rule that produces tokens (or is skipped)
+type lexToken struct {
+	rule     *ast.LexerRule
+	literal  string // Unquoted literal for literal only rules
+	keyword  bool   // Literal matched by another rule - found via keyword map
+	hasKeys  bool   // Rule matches at least one keyword
+	implicit bool   // Literal from a parser rule without a lexer rule
+}
+
+func (l *lexToken) skip() bool {
+	return l.rule.HasAction(ast.SkipAction)
+}
+
+// typed returns true if the token has a token type. Skipped tokens only have
+// one when they are kept as trivia
+func (g *lexerGen) typed(tok *lexToken) bool {
+	return !tok.skip() || g.opts.Lossless
+}
+
+var literalEscaper = strings.NewReplacer(
+	`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`,
+)
+
+// name returns the name of the token used in syntax errors. Literal tokens
+// are named by their quoted literal
+func (l *lexToken) name() string {
+	if l.literal != "" {
+		return "'" + literalEscaper.Replace(l.literal) + "'"
+	}
+	return l.rule.Name
+}
+
+// defaultMode is the name of the mode lexer rules are in unless declared
+// otherwise
+const defaultMode = "DEFAULT_MODE"
+
+type lexerGen struct {
+	top    *ast.TopLevel
+	opts   *Options
+	rules  map[string]*ast.LexerRule
+	tokens []*lexToken
+	// modes are the names of all modes starting with the default mode. It is
+	// empty if the grammar doesn't use modes at all
+	modes []string
+	// channels are the names of the channels used other than the predefined
+	// ones, in order of first use
+	channels []string
+	// matcher matches the rules the way the generated tokenizer will, to find
+	// the keywords
+	matcher *ast.LexerMatcher
+
+	nullable map[string]bool
+}
+
+// ruleMode returns the name of the mode a rule is in
+func ruleMode(rule *ast.LexerRule) string {
+	if rule.Mode == "" {
+		return defaultMode
+	}
+	return rule.Mode
+}
+
+// GenerateTokenizer generates the Go source code for a tokenizer for the lexer
+// rules of a grammar. Any literals used by the parser rules that do not have
+// a lexer rule of their own are given an implicit token. At each position the
+// longest match of any rule is taken, with earlier rules winning ties, except
+// that literal rules also matched by another rule (keywords) always win
+func GenerateTokenizer(top *ast.TopLevel, opts *Options) ([]byte, error) {
+	g := &lexerGen{
+		top:      top,
+		opts:     opts,
+		rules:    make(map[string]*ast.LexerRule, len(top.LexerRules)),
+		nullable: make(map[string]bool, len(top.LexerRules)),
+	}
+	g.matcher = ast.NewLexerMatcher(g.rules)
+	if err := g.analyze(); err != nil {
+		return nil, err
+	}
+	return g.generate()
+}
+
+// *** Analysis ***
+
+func (g *lexerGen) analyze() error {
+	for _, rule := range g.top.LexerRules {
+		if _, ok := g.rules[rule.Name]; ok {
+			return fmt.Errorf("duplicate lexer rule: %s", rule.Name)
+		}
+		g.rules[rule.Name] = rule
+	}
+	if err := g.analyzeModes(); err != nil {
+		return err
+	}
+	if err := g.analyzeChannels(); err != nil {
+		return err
+	}
+
+	for _, rule := range g.top.LexerRules {
+		if err := g.matcher.AddRule(rule); err != nil {
+			return fmt.Errorf("%s: %w", rule.Name, err)
+		}
+		if rule.Fragment {
+			continue
+		}
+
+		tok := &lexToken{rule: rule}
+		if lit, ok := rule.Literal(); ok {
+			unquoted, err := ast.Unquote(lit)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rule.Name, err)
+			}
+			tok.literal = unquoted
+		}
+		g.tokens = append(g.tokens, tok)
+	}
+
+	if err := g.implicitTokens(); err != nil {
+		return err
+	}
+	if len(g.tokens) == 0 {
+		return errors.New("grammar has no lexer rules")
+	}
+	g.findKeywords()
+	return nil
+}
+
+// analyzeModes validates the declared modes and the mode actions that use them
+func (g *lexerGen) analyzeModes() error {
+	modes := map[string]bool{defaultMode: true}
+	usesModes := len(g.top.Modes) > 0
+	g.modes = []string{defaultMode}
+
+	for _, mode := range g.top.Modes {
+		if modes[mode] {
+			return fmt.Errorf("duplicate mode: %s", mode)
+		}
+		if _, ok := g.rules[mode]; ok {
+			return fmt.Errorf("mode conflicts with lexer rule: %s", mode)
+		}
+		modes[mode] = true
+		g.modes = append(g.modes, mode)
+	}
+
+	for _, rule := range g.top.LexerRules {
+		if !modes[ruleMode(rule)] {
+			return fmt.Errorf("%s: undefined mode: %s", rule.Name, rule.Mode)
+		}
+		for _, action := range rule.Actions {
+			if action.Type == ast.SkipAction || action.Type == ast.ChannelAction {
+				continue
+			}
+			if rule.Fragment {
+				return fmt.Errorf("%s: fragment rules can not change modes", rule.Name)
+			}
+			if action.Type == ast.PushModeAction && !modes[action.Mode] {
+				return fmt.Errorf("%s: undefined mode: %s", rule.Name, action.Mode)
+			}
+			usesModes = true
+		}
+	}
+
+	if !usesModes {
+		g.modes = nil
+	}
+	return nil
+}
+
+// predefinedChannels maps the channels every grammar has to their runtime
+// constants
+var predefinedChannels = map[string]string{
+	"DEFAULT_TOKEN_CHANNEL": "runtime.DefaultChannel",
+	"HIDDEN":                "runtime.HiddenChannel",
+}
+
+func (g *lexerGen) analyzeChannels() error {
+	channels := map[string]bool{}
+	for _, mode := range g.top.Modes {
+		channels[mode] = false
+	}
+
+	for _, rule := range g.top.LexerRules {
+		for _, action := range rule.Actions {
+			if action.Type != ast.ChannelAction {
+				continue
+			}
+			if rule.Fragment || rule.HasAction(ast.SkipAction) {
+				return fmt.Errorf("%s: skipped and fragment rules can not have a channel", rule.Name)
+			}
+			name := action.Channel
+			if _, ok := predefinedChannels[name]; ok || channels[name] {
+				continue
+			}
+			if _, ok := channels[name]; ok {
+				return fmt.Errorf("channel conflicts with mode: %s", name)
+			}
+			if _, ok := g.rules[name]; ok {
+				return fmt.Errorf("channel conflicts with lexer rule: %s", name)
+			}
+			channels[name] = true
+			g.channels = append(g.channels, name)
+		}
+	}
+	return nil
+}
+
+func channelExpr(name string) string {
+	if expr, ok := predefinedChannels[name]; ok {
+		return expr
+	}
+	return name
+}
+
+// implicitTokens adds tokens for literals used by parser rules that aren't
+// matched by a lexer rule of their own. See ast.TopLevel.ImplicitTokens
+func (g *lexerGen) implicitTokens() error {
+	rules, err := g.top.ImplicitTokens()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := g.matcher.AddRule(rule); err != nil {
+			return fmt.Errorf("%s: %w", rule.Name, err)
+		}
+		quoted, _ := rule.Literal()
+		lit, _ := ast.Unquote(quoted)
+		g.rules[rule.Name] = rule
+		g.tokens = append(g.tokens, &lexToken{rule: rule, literal: lit, implicit: true})
+	}
+	return nil
+}
+
+// findKeywords finds the literal tokens looked up in the keyword map of the
+// tokenizer instead of being matched. See ast.LexerMatcher.Keywords
+func (g *lexerGen) findKeywords() {
+	rules := make([]*ast.LexerRule, len(g.tokens))
+	for i, tok := range g.tokens {
+		rules[i] = tok.rule
+	}
+	keywords, hasKeys := g.matcher.Keywords(rules)
+	for _, tok := range g.tokens {
+		tok.keyword, tok.hasKeys = keywords[tok.rule], hasKeys[tok.rule]
+	}
+}
+
+// greedyOf returns the greedy form of a non-greedy node or the node itself
+// otherwise. Both forms can match the same input, only the preferred match
+// differs
+func greedyOf(node ast.LexerNode) ast.LexerNode {
+	switch n := node.(type) {
+	case *ast.LexerNonGreedyZeroOrMore:
+		return &ast.LexerZeroOrMore{Span: n.Span, Node: n.Node}
+	case *ast.LexerNonGreedyOneOrMore:
+		return &ast.LexerOneOrMore{Span: n.Span, Node: n.Node}
+	case *ast.LexerNonGreedyZeroOrOne:
+		return &ast.LexerZeroOrOne{Span: n.Span, Node: n.Node}
+	default:
+		return node
+	}
+}
+
+// isNullable returns true if the node can match without consuming any input
+func (g *lexerGen) isNullable(node ast.LexerNode) bool {
+	switch n := greedyOf(node).(type) {
+	case *ast.LexerAlternatives:
+		for _, alt := range n.Rules {
+			nullable := true
+			for _, node := range alt {
+				nullable = nullable && g.isNullable(node)
+			}
+			if nullable {
+				return true
+			}
+		}
+		return false
+	case *ast.LexerZeroOrOne, *ast.LexerZeroOrMore:
+		return true
+	case *ast.LexerOneOrMore:
+		return g.isNullable(n.Node)
+	case *ast.LexerRuleRef:
+		nullable, ok := g.nullable[n.Name]
+		if !ok {
+			rule, ok := g.rules[n.Name]
+			if !ok {
+				return false
+			}
+			// Assume not nullable while recursing to break any cycles
+			g.nullable[n.Name] = false
+			nullable = g.isNullable(rule.Rules)
+			g.nullable[n.Name] = nullable
+		}
+		return nullable
+	default:
+		return false
+	}
+}
+
+// references returns the names of all lexer rules referenced by another rule
+func (g *lexerGen) references() map[string]bool {
+	refs := make(map[string]bool, len(g.rules))
+	for _, rule := range g.top.LexerRules {
+		ast.WalkLexerNode(rule.Rules, func(node ast.LexerNode) bool {
+			if ref, ok := node.(*ast.LexerRuleRef); ok {
+				refs[ref.Name] = true
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// *** Generation ***
+
+// matchFunc is a function that matches a lexer rule or part of one
+type matchFunc struct {
+	name string // ex: "matchRuleName" or "matchRuleNameSub1"
+	text string
+	alts [][]ast.LexerNode
+}
+
+// ruleFuncs generates all the match functions needed for a single lexer rule
+type ruleFuncs struct {
+	g     *lexerGen
+	rule  *ast.LexerRule
+	funcs []*matchFunc
+	exprs map[ast.LexerNode]string
+}
+
+func matchFuncName(rule *ast.LexerRule) string {
+	return "match" + pascalCase(rule.Name)
+}
+
+func (r *ruleFuncs) newFunc(alts [][]ast.LexerNode) *matchFunc {
+	name := matchFuncName(r.rule)
+	if len(r.funcs) > 0 {
+		name = fmt.Sprintf("%sSub%d", name, len(r.funcs))
+	}
+	f := &matchFunc{name: name, text: ast.LexerAltsText(alts), alts: alts}
+	r.funcs = append(r.funcs, f)
+	return f
+}
+
+func (r *ruleFuncs) call(f *matchFunc) string {
+	return fmt.Sprintf("t.%s()", f.name)
+}
+
+func (r *ruleFuncs) generate(w *writer) error {
+	w.Blank()
+	w.Line("// *** %s ***", r.rule.Name)
+
+	r.newFunc(r.rule.Rules.Rules)
+	// Generating a function can add more functions to the list
+	for i := 0; i < len(r.funcs); i++ {
+		if err := r.function(w, r.funcs[i]); err != nil {
+			return fmt.Errorf("%s: %w", r.rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *ruleFuncs) function(w *writer, f *matchFunc) error {
+	w.Blank()
+	if f == r.funcs[0] {
+		w.Line("// %s matches the %s lexer rule", f.name, r.rule.Name)
+	} else {
+		w.Line("// %s matches part of the %s lexer rule", f.name, r.rule.Name)
+	}
+	w.Line("func (t *Tokenizer) %s() bool {", f.name)
+	defer w.Line("}")
+
+	if len(f.alts) == 1 && (len(f.alts[0]) > 1 || isSuffixed(f.alts[0][0])) {
+		return r.sequence(w, f.alts[0])
+	}
+
+	exprs := make([]string, 0, len(f.alts))
+	for _, alt := range f.alts {
+		var expr string
+		var err error
+		if len(alt) == 1 {
+			expr, err = r.expr(alt[0])
+		} else {
+			expr = r.call(r.newFunc([][]ast.LexerNode{alt}))
+		}
+		if err != nil {
+			return err
+		}
+		exprs = append(exprs, expr)
+	}
+	w.Line("// %s", f.text)
+	w.Line("return %s", orJoin(exprs))
+	return nil
+}
+
+func (r *ruleFuncs) sequence(w *writer, seq []ast.LexerNode) error {
+	// Anything matched before a failure must be given back. Non-greedy
+	// elements followed by anything can fail after matching, even when first
+	restore := false
+	for i, node := range seq {
+		switch node.(type) {
+		case *ast.LexerZeroOrOne, *ast.LexerZeroOrMore:
+		case *ast.LexerNonGreedyZeroOrMore, *ast.LexerNonGreedyZeroOrOne:
+		default:
+			restore = restore || i > 0
+		}
+		if ast.IsNonGreedy(node) {
+			restore = restore || i < len(seq)-1
+		} else if ast.HasNonGreedy([]ast.LexerNode{node}, r.g.rules) {
+			restore = true
+		}
+	}
+	if restore {
+		w.Line("pos := t.lex.Pos()")
+		w.Blank()
+	}
+
+	fail := func(i int) {
+		if i > 0 {
+			w.Line("t.lex.SetPos(pos)")
+		}
+		w.Line("return false")
+	}
+
+	for i, node := range seq {
+		if ast.IsNonGreedy(node) {
+			return r.nonGreedy(w, node, seq[i+1:], i == 0)
+		}
+		if alts := ast.NonGreedyGroup(node, r.g.rules); alts != nil {
+			return r.expand(w, node, alts, seq[i+1:])
+		}
+		w.Line("// %s", ast.LexerNodeText(node))
+
+		switch n := node.(type) {
+		case *ast.LexerZeroOrOne:
+			expr, err := r.expr(n.Node)
+			if err != nil {
+				return err
+			}
+			if strings.Contains(expr, "||") {
+				w.Line("_ = %s", expr)
+			} else {
+				w.Line("%s", expr)
+			}
+		case *ast.LexerZeroOrMore:
+			if err := r.loop(w, n.Node); err != nil {
+				return err
+			}
+		case *ast.LexerOneOrMore:
+			expr, err := r.expr(n.Node)
+			if err != nil {
+				return err
+			}
+			w.Line("if !%s {", paren(expr))
+			fail(i)
+			w.Line("}")
+			if err := r.loop(w, n.Node); err != nil {
+				return err
+			}
+		default:
+			expr, err := r.expr(node)
+			if err != nil {
+				return err
+			}
+			w.Line("if !%s {", paren(expr))
+			fail(i)
+			w.Line("}")
+		}
+		w.Blank()
+	}
+
+	w.Line("return true")
+	return nil
+}
+
+// loop matches a node as many times as possible
+func (r *ruleFuncs) loop(w *writer, node ast.LexerNode) error {
+	expr, err := r.expr(node)
+	if err != nil {
+		return err
+	}
+
+	if !r.g.isNullable(node) {
+		w.Line("for %s {", expr)
+		w.Line("}")
+		return nil
+	}
+
+	// Stop as soon as no progress is made, otherwise this would never end
+	w.Line("for {")
+	w.Line("start := t.lex.Offset()")
+	w.Line("if !%s || t.lex.Offset() == start {", paren(expr))
+	w.Line("break")
+	w.Line("}")
+	w.Line("}")
+	return nil
+}
+
+// nonGreedy matches a non-greedy node followed by the rest of its sequence.
+// The rest is tried before each further match of the node, so the node
+// matches as little as possible. Nothing following means nothing needs to be
+// matched beyond the minimum
+func (r *ruleFuncs) nonGreedy(w *writer, node ast.LexerNode, rest []ast.LexerNode, first bool) error {
+	w.Line("// %s", ast.LexerAltsText([][]ast.LexerNode{append([]ast.LexerNode{node}, rest...)}))
+
+	fail := func() {
+		w.Line("t.lex.SetPos(pos)")
+		w.Line("return false")
+	}
+
+	var inner ast.LexerNode
+	switch n := node.(type) {
+	case *ast.LexerNonGreedyZeroOrMore:
+		inner = n.Node
+	case *ast.LexerNonGreedyOneOrMore:
+		inner = n.Node
+	case *ast.LexerNonGreedyZeroOrOne:
+		inner = n.Node
+	}
+	expr, err := r.expr(inner)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := node.(*ast.LexerNonGreedyOneOrMore); ok {
+		w.Line("if !%s {", paren(expr))
+		if first {
+			w.Line("return false")
+		} else {
+			fail()
+		}
+		w.Line("}")
+	}
+	if len(rest) == 0 {
+		w.Line("return true")
+		return nil
+	}
+
+	restExpr, err := r.rest(rest)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := node.(*ast.LexerNonGreedyZeroOrOne); ok {
+		w.Line("if %s || %s && %s {", paren(restExpr), paren(expr), paren(restExpr))
+		w.Line("return true")
+		w.Line("}")
+		fail()
+		return nil
+	}
+
+	w.Line("for !%s {", paren(restExpr))
+	if r.g.isNullable(inner) {
+		// Stop as soon as no progress is made, otherwise this would never end
+		w.Line("start := t.lex.Offset()")
+		w.Line("if !%s || t.lex.Offset() == start {", paren(expr))
+	} else {
+		w.Line("if !%s {", paren(expr))
+	}
+	fail()
+	w.Line("}")
+	w.Line("}")
+	w.Line("return true")
+	return nil
+}
+
+// expand matches a group or rule reference containing a non-greedy element
+// followed by the rest of its sequence. The rest is matched as part of each
+// alternative so that the non-greedy element knows what follows it
+func (r *ruleFuncs) expand(w *writer, node ast.LexerNode, alts *ast.LexerAlternatives, rest []ast.LexerNode) error {
+	w.Line("// %s", ast.LexerAltsText([][]ast.LexerNode{append([]ast.LexerNode{node}, rest...)}))
+
+	exprs := make([]string, 0, len(alts.Rules))
+	for _, alt := range alts.Rules {
+		seq := append(append([]ast.LexerNode{}, alt...), rest...)
+		exprs = append(exprs, r.call(r.newFunc([][]ast.LexerNode{seq})))
+	}
+	w.Line("if %s {", orJoin(exprs))
+	w.Line("return true")
+	w.Line("}")
+	w.Line("t.lex.SetPos(pos)")
+	w.Line("return false")
+	return nil
+}
+
+// rest returns an expression matching the remainder of a sequence
+func (r *ruleFuncs) rest(seq []ast.LexerNode) (string, error) {
+	if len(seq) == 1 {
+		return r.expr(seq[0])
+	}
+	return r.call(r.newFunc([][]ast.LexerNode{seq})), nil
+}
+
+func isSuffixed(node ast.LexerNode) bool {
+	switch node.(type) {
+	case *ast.LexerZeroOrOne, *ast.LexerZeroOrMore, *ast.LexerOneOrMore:
+		return true
+	default:
+		return ast.IsNonGreedy(node)
+	}
+}
+
+func paren(expr string) string {
+	if strings.Contains(expr, "||") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+// expr returns a boolean Go expression that matches a single occurrence of the
+// given node. It never consumes input when it doesn't match
+func (r *ruleFuncs) expr(node ast.LexerNode) (string, error) {
+	// The same node can be needed more than once (ex: X+ is X X*)
+	if expr, ok := r.exprs[node]; ok {
+		return expr, nil
+	}
+	expr, err := r.newExpr(node)
+	if err != nil {
+		return "", err
+	}
+	r.exprs[node] = expr
+	return expr, nil
+}
+
+func (r *ruleFuncs) newExpr(node ast.LexerNode) (string, error) {
+	switch n := node.(type) {
+	case *ast.LexerToken:
+		lit, err := ast.Unquote(n.Token.Data)
+		if err != nil {
+			return "", err
+		}
+		switch utf8.RuneCountInString(lit) {
+		case 0:
+			return "", errors.New("empty literal")
+		case 1:
+			ch, _ := utf8.DecodeRuneInString(lit)
+			return fmt.Sprintf("t.lex.MatchChar(%s)", strconv.QuoteRune(ch)), nil
+		default:
+			return fmt.Sprintf("t.lex.MatchSeq(%s)", strconv.Quote(lit)), nil
+		}
+	case *ast.LexerCharClass:
+		if n.Err != nil {
+			return "", n.Err
+		}
+		if n.Negated {
+			return notSetExpr(n.Set), nil
+		}
+		return setExpr(n.Set), nil
+	case *ast.LexerAnyChar:
+		return "t.lex.MatchAnyChar()", nil
+	case *ast.LexerRuleRef:
+		rule, ok := r.g.rules[n.Name]
+		if !ok {
+			return "", fmt.Errorf("undefined lexer rule: %s", n.Name)
+		}
+		return fmt.Sprintf("t.%s()", matchFuncName(rule)), nil
+	case *ast.LexerNot:
+		if tok, ok := n.Node.(*ast.LexerToken); ok {
+			lit, err := ast.Unquote(tok.Token.Data)
+			if err != nil {
+				return "", err
+			}
+			if utf8.RuneCountInString(lit) > 1 {
+				return fmt.Sprintf("t.lex.MatchCharUnlessSeq(%s)", strconv.Quote(lit)), nil
+			}
+		}
+		set, err := ast.CharSetOf(n.Node, r.g.rules)
+		if err != nil {
+			return "", fmt.Errorf("unable to negate: %w", err)
+		}
+		return notSetExpr(set), nil
+	case *ast.LexerAlternatives:
+		if len(n.Rules) == 1 && len(n.Rules[0]) == 1 {
+			return r.expr(n.Rules[0][0])
+		}
+		return r.call(r.newFunc(n.Rules)), nil
+	default:
+		return r.call(r.newFunc([][]ast.LexerNode{{node}})), nil
+	}
+}
+
+// orJoin joins expressions with ||, wrapping long lines
+func orJoin(exprs []string) string {
+	if len(strings.Join(exprs, " || ")) > 80 {
+		return strings.Join(exprs, " ||\n")
+	}
+	return strings.Join(exprs, " || ")
+}
+
+// splitSet splits a char set into ranges and single chars. Very small ranges
+// are considered to be single chars as they read better that way
+func splitSet(set ast.CharSet) (ranges ast.CharSet, singles []rune) {
+	for _, r := range set {
+		if r.Hi-r.Lo < 3 {
+			for ch := r.Lo; ch <= r.Hi; ch++ {
+				singles = append(singles, ch)
+			}
+		} else {
+			ranges = append(ranges, r)
+		}
+	}
+	return
+}
+
+func setExpr(set ast.CharSet) string {
+	exprs := []string{}
+	ranges, singles := splitSet(set)
+	for _, r := range ranges {
+		exprs = append(exprs, fmt.Sprintf("t.lex.MatchCharInRange(%s, %s)",
+			strconv.QuoteRune(r.Lo), strconv.QuoteRune(r.Hi)))
+	}
+
+	switch len(singles) {
+	case 0:
+	case 1:
+		exprs = append(exprs, fmt.Sprintf("t.lex.MatchChar(%s)", strconv.QuoteRune(singles[0])))
+	default:
+		exprs = append(exprs, fmt.Sprintf("t.lex.MatchCharInSeq(%s)", strconv.Quote(string(singles))))
+	}
+	return orJoin(exprs)
+}
+
+func notSetExpr(set ast.CharSet) string {
+	ranges, singles := splitSet(set)
+
+	switch {
+	case len(ranges) == 0 && len(singles) == 1:
+		return fmt.Sprintf("t.lex.MatchCharExcept(%s)", strconv.QuoteRune(singles[0]))
+	case len(ranges) == 0:
+		return fmt.Sprintf("t.lex.MatchCharExceptInSeq(%s)", strconv.Quote(string(singles)))
+	case len(set) == 1:
+		return fmt.Sprintf("t.lex.MatchCharExceptInRange(%s, %s)",
+			strconv.QuoteRune(set[0].Lo), strconv.QuoteRune(set[0].Hi))
+	default:
+		bounds := make([]string, 0, len(set)*2)
+		for _, r := range set {
+			bounds = append(bounds, strconv.QuoteRune(r.Lo), strconv.QuoteRune(r.Hi))
+		}
+		return fmt.Sprintf("t.lex.MatchCharExceptInRanges(%s)", strings.Join(bounds, ", "))
+	}
+}
+
+func (g *lexerGen) generate() ([]byte, error) {
+	w := new(writer)
+	w.Header("", g.opts.Package, []string{runtimeImport})
+	w.Blank()
+
+	w.Line("const (")
+	first := true
+	for _, tok := range g.tokens {
+		if !g.typed(tok) {
+			continue
+		}
+		if first {
+			w.Line("%s runtime.TokenType = iota + runtime.EOF + 1", tok.rule.Name)
+			first = false
+		} else {
+			w.Line("%s", tok.rule.Name)
+		}
+	}
+	w.Line(")")
+	w.Blank()
+
+	matchers := []*lexToken{}
+	keywords := []*lexToken{}
+	for _, tok := range g.tokens {
+		if tok.keyword {
+			keywords = append(keywords, tok)
+		} else {
+			matchers = append(matchers, tok)
+		}
+	}
+
+	if g.modes != nil {
+		w.Line("const (")
+		for i, mode := range g.modes {
+			if i == 0 {
+				w.Line("%s runtime.Mode = iota", mode)
+			} else {
+				w.Line("%s", mode)
+			}
+		}
+		w.Line(")")
+		w.Blank()
+	}
+
+	if g.channels != nil {
+		w.Line("const (")
+		for i, channel := range g.channels {
+			if i == 0 {
+				w.Line("%s runtime.Channel = iota + runtime.HiddenChannel + 1", channel)
+			} else {
+				w.Line("%s", channel)
+			}
+		}
+		w.Line(")")
+		w.Blank()
+	}
+
+	w.Line("var tokenNames = map[runtime.TokenType]string{")
+	if g.opts.Lossless {
+		w.Line("runtime.BOM: \"BOM\",")
+	}
+	for _, tok := range g.tokens {
+		if g.typed(tok) {
+			w.Line("%s: %s,", tok.rule.Name, strconv.Quote(tok.name()))
+		}
+	}
+	w.Line("}")
+	w.Blank()
+
+	if len(keywords) > 0 {
+		w.Line("var keywords = map[string]runtime.TokenType{")
+		for _, tok := range keywords {
+			w.Line("%s: %s,", strconv.Quote(tok.literal), tok.rule.Name)
+		}
+		w.Line("}")
+		w.Blank()
+	}
+
+	w.Line("// Tokenizer splits its input into tokens by taking the longest match of")
+	if g.modes == nil {
+		w.Line("// any lexer rule at each position")
+		w.Line("type Tokenizer struct {")
+		w.Line("lex *runtime.Lexer")
+		w.Line("matchers []func() bool")
+	} else {
+		w.Line("// any lexer rule of the current mode at each position")
+		w.Line("type Tokenizer struct {")
+		w.Line("lex *runtime.Lexer")
+		w.Line("matchers [][]func() bool")
+	}
+	w.Line("}")
+	w.Blank()
+
+	name := g.opts.constructor("Tokenizer")
+	w.Line("// %s creates a new tokenizer that reads characters from the given lexer", name)
+	w.Line("func %s(lex *runtime.Lexer) *Tokenizer {", name)
+	if g.opts.Lossless {
+		w.Line("lex.KeepBOM()")
+	}
+	w.Line("t := &Tokenizer{lex: lex}")
+	if g.modes == nil {
+		w.Line("t.matchers = []func() bool{")
+		for _, tok := range matchers {
+			w.Line("t.%s,", matchFuncName(tok.rule))
+		}
+		w.Line("}")
+	} else {
+		w.Line("t.matchers = [][]func() bool{")
+		for _, mode := range g.modes {
+			w.Line("%s: {", mode)
+			for _, tok := range modeTokens(matchers, mode) {
+				w.Line("t.%s,", matchFuncName(tok.rule))
+			}
+			w.Line("},")
+		}
+		w.Line("}")
+	}
+	w.Line("return t")
+	w.Line("}")
+	w.Blank()
+
+	w.Line("// TokenName returns the name of a token type for use in syntax errors")
+	w.Line("func (t *Tokenizer) TokenName(tt runtime.TokenType) string {")
+	w.Line("return tokenNames[tt]")
+	w.Line("}")
+	w.Blank()
+
+	if g.modes == nil {
+		g.nextToken(w, matchers)
+	} else {
+		g.nextModeToken(w, matchers)
+	}
+
+	// Keywords are only matched directly when referenced by another rule
+	keywordRules := make(map[string]bool, len(keywords))
+	for _, tok := range keywords {
+		keywordRules[tok.rule.Name] = true
+	}
+	refs := g.references()
+
+	rules := append([]*ast.LexerRule{}, g.top.LexerRules...)
+	for _, tok := range g.tokens {
+		if tok.implicit {
+			rules = append(rules, tok.rule)
+		}
+	}
+	for _, rule := range rules {
+		if keywordRules[rule.Name] && !refs[rule.Name] {
+			continue
+		}
+		funcs := &ruleFuncs{g: g, rule: rule, exprs: make(map[ast.LexerNode]string, 8)}
+		if err := funcs.generate(w); err != nil {
+			return nil, err
+		}
+	}
+	return w.Format()
+}
+
+// modeTokens returns the tokens in the given mode
+func modeTokens(tokens []*lexToken, mode string) []*lexToken {
+	inMode := []*lexToken{}
+	for _, tok := range tokens {
+		if ruleMode(tok.rule) == mode {
+			inMode = append(inMode, tok)
+		}
+	}
+	return inMode
+}
+
+func (g *lexerGen) nextTokenDoc(w *writer) {
+	if g.opts.Lossless {
+		w.Line("// NextToken matches the next token in the input, keeping any skipped as trivia")
+	} else {
+		w.Line("// NextToken matches the next token in the input, discarding any skipped")
+	}
+}
+
+func (g *lexerGen) nextToken(w *writer, matchers []*lexToken) {
+	g.nextTokenDoc(w)
+	w.Line("func (t *Tokenizer) NextToken(tok *runtime.Token) {")
+	w.Line("for {")
+	w.Line("switch t.lex.LongestMatch(t.matchers) {")
+	g.matchCases(w, matchers, "continue", "return")
+	w.Line("}")
+	w.Line("return")
+	w.Line("}")
+	w.Line("}")
+}
+
+// nextModeToken generates NextToken for grammars with modes. Each mode gets
+// its own function that only tries the rules of that mode
+func (g *lexerGen) nextModeToken(w *writer, matchers []*lexToken) {
+	g.nextTokenDoc(w)
+	w.Line("func (t *Tokenizer) NextToken(tok *runtime.Token) {")
+	w.Line("for {")
+	w.Line("var skipped bool")
+	w.Line("switch t.lex.CurrentMode() {")
+	for _, mode := range g.modes[1:] {
+		w.Line("case %s:", mode)
+		w.Line("skipped = t.%s(tok)", modeFuncName(mode))
+	}
+	w.Line("default:")
+	w.Line("skipped = t.%s(tok)", modeFuncName(defaultMode))
+	w.Line("}")
+	w.Line("if !skipped {")
+	w.Line("return")
+	w.Line("}")
+	w.Line("}")
+	w.Line("}")
+
+	for _, mode := range g.modes {
+		name := modeFuncName(mode)
+		w.Blank()
+		w.Line("// %s matches the next token in the %s mode and", name, mode)
+		w.Line("// returns true if it was skipped")
+		w.Line("func (t *Tokenizer) %s(tok *runtime.Token) bool {", name)
+		w.Line("switch t.lex.LongestMatch(t.matchers[%s]) {", mode)
+		g.matchCases(w, modeTokens(matchers, mode), "return true", "return false")
+		w.Line("}")
+		w.Line("return false")
+		w.Line("}")
+	}
+}
+
+func modeFuncName(mode string) string {
+	return "next" + pascalCase(mode) + "Token"
+}
+
+// matchCases generates the cases for each matcher index returned by
+// LongestMatch. skipStmt ends the case of a skipped token and exitStmt
+// exits early when a mode can't be popped
+func (g *lexerGen) matchCases(w *writer, matchers []*lexToken, skipStmt, exitStmt string) {
+	skips, names := []string{}, []string{}
+	for i, tok := range matchers {
+		if tok.skip() && len(tok.rule.Actions) == 1 && !g.opts.Lossless {
+			skips = append(skips, strconv.Itoa(i))
+			names = append(names, tok.rule.Name)
+			continue
+		}
+
+		w.Line("case %d: // %s", i, tok.rule.Name)
+		if tok.skip() {
+			g.modeActions(w, tok, exitStmt)
+			if g.opts.Lossless {
+				w.Line("t.lex.BuildTrivia(%s)", tok.rule.Name)
+			} else {
+				w.Line("t.lex.DiscardTokenData()")
+			}
+			w.Line("%s", skipStmt)
+			continue
+		}
+
+		if tok.literal != "" && !g.opts.Lossless {
+			w.Line("t.lex.BuildToken(%s, tok)", tok.rule.Name)
+		} else {
+			w.Line("t.lex.BuildTokenData(%s, tok)", tok.rule.Name)
+		}
+		if tok.hasKeys && g.opts.Lossless {
+			w.Line("if tt, ok := keywords[tok.Data]; ok {")
+			w.Line("tok.Type = tt")
+			w.Line("}")
+		} else if tok.hasKeys {
+			w.Line("if tt, ok := keywords[tok.Data]; ok {")
+			w.Line("tok.Type, tok.Data = tt, \"\"")
+			w.Line("}")
+		}
+		g.modeActions(w, tok, "")
+	}
+	if len(skips) > 0 {
+		w.Line("case %s: // %s", strings.Join(skips, ", "), strings.Join(names, ", "))
+		w.Line("t.lex.DiscardTokenData()")
+		w.Line("%s", skipStmt)
+	}
+
+	w.Line("default:")
+	w.Line("if t.lex.CurrChar() == runtime.EOFChar {")
+	w.Line("t.lex.BuildToken(runtime.EOF, tok)")
+	w.Line("} else {")
+	w.Line("t.lex.BuildIllegalToken(tok)")
+	w.Line("}")
+}
+
+// modeActions generates the channel and mode changes of a token. A failed pop
+// makes the token illegal, which also puts it back on the default channel. For
+// skipped tokens (exitStmt given), the illegal token is built from the skipped
+// text and exitStmt is used to return it
+func (g *lexerGen) modeActions(w *writer, tok *lexToken, exitStmt string) {
+	for _, action := range tok.rule.Actions {
+		if action.Type == ast.ChannelAction {
+			w.Line("tok.Channel = %s", channelExpr(action.Channel))
+		}
+	}
+	for _, action := range tok.rule.Actions {
+		switch action.Type {
+		case ast.PushModeAction:
+			w.Line("t.lex.PushMode(%s)", action.Mode)
+		case ast.PopModeAction:
+			w.Line("if t.lex.PopMode() != nil {")
+			w.Line("// No mode to return to, so the token is not valid here")
+			if exitStmt != "" {
+				w.Line("t.lex.BuildToken(runtime.ILLEGAL, tok)")
+			}
+			w.Line("t.lex.MakeIllegal(tok, runtime.ModeStackUnderflow)")
+			if exitStmt != "" {
+				w.Line("%s", exitStmt)
+			}
+			w.Line("}")
+		}
+	}
+}
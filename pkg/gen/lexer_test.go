@@ -0,0 +1,223 @@
+package gen_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The pg tokenizer is itself generated, so generating it again must
+// reproduce it exactly
+func TestGenerateTokenizer(t *testing.T) {
+	const output = "../pgtoken/pg_tokenizer.go"
+
+	code, err := gen.GenerateTokenizer(loadGrammar(t, "../../grammars/pg_lexer.g4"), &gen.Options{Package: "pgtoken"})
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile(output, code, 0644))
+	}
+	expected, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(code))
+}
+
+// The ANTLR tokenizer uses a mode for char classes
+func TestGenerateTokenizerModes(t *testing.T) {
+	const output = "../token/tokenizer.go"
+
+	code, err := gen.GenerateTokenizer(loadGrammar(t, "../../grammars/antlr_lexer.g4"), &gen.Options{Package: "token"})
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile(output, code, 0644))
+	}
+	expected, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(code))
+}
+
+func TestGenerateTokenizerImplicit(t *testing.T) {
+	top := parseGrammar(t, "list: '[' NAME (',' NAME)* ']' 'end';\n\nNAME: [a-z]+;")
+
+	code, err := gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+
+	src := string(code)
+	assert.Contains(t, src, "NAME runtime.TokenType = iota + runtime.EOF + 1\n\tLBRACK\n\tCOMMA\n\tRBRACK\n\tEND\n")
+	assert.Contains(t, src, `"end": END,`)
+	assert.Contains(t, src, "return t.lex.MatchChar(',')")
+	assert.NotContains(t, src, "matchEnd")
+}
+
+// A literal is only a keyword if the other rule matches all of it the way
+// the tokenizer would. ID never matches "ifx" as [a-z]* takes the x
+func TestGenerateTokenizerKeywords(t *testing.T) {
+	top := parseGrammar(t, "s: KW EOF;\n\nKW: 'ifx';\nIF: 'if';\nID: [a-z]* 'x';\nWORD: [a-z]+;")
+
+	code, err := gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+
+	src := string(code)
+	assert.Contains(t, src, "var keywords = map[string]runtime.TokenType{\n\t\"ifx\": KW,\n\t\"if\":  IF,\n}")
+	assert.Contains(t, src, "t.matchers = []func() bool{\n\t\tt.matchId,\n\t\tt.matchWord,\n\t}")
+	// Only WORD looks its matches up
+	assert.Equal(t, 1, strings.Count(src, "keywords[tok.Data]"))
+
+	top = parseGrammar(t, "s: KW EOF;\n\nKW: 'ifx';\nID: [a-z]* 'x';")
+	code, err = gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(code), "keywords")
+}
+
+// Non-greedy elements try the rest of their sequence before each further
+// match, and a group containing one is matched together with what follows it
+func TestGenerateTokenizerNonGreedy(t *testing.T) {
+	top := parseGrammar(t, "COMMENT: '/*' .*? '*/';\nA: 'a' ('x' .*? | 'y') 'z';\n"+
+		"B: 'b' 'c'?? 'c';\nC: 'c' 'd'+?;")
+
+	code, err := gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+
+	src := string(code)
+	assert.Contains(t, src, "// .*? '*/'\n\tfor !t.lex.MatchSeq(\"*/\") {\n"+
+		"\t\tif !t.lex.MatchAnyChar() {\n\t\t\tt.lex.SetPos(pos)\n\t\t\treturn false\n\t\t}\n\t}\n\treturn true\n")
+	assert.Contains(t, src, "// ('x' .*? | 'y') 'z'\n\tif t.matchASub1() || t.matchASub2() {\n")
+	assert.Contains(t, src, "// .*? 'z'\n\tfor !t.lex.MatchChar('z') {\n")
+	assert.Contains(t, src, "if t.lex.MatchChar('c') || t.lex.MatchChar('c') && t.lex.MatchChar('c') {\n")
+
+	// Nothing follows, so only the minimum is matched
+	assert.Contains(t, src, "// 'd'+?\n\tif !t.lex.MatchChar('d') {\n\t\tt.lex.SetPos(pos)\n\t\treturn false\n\t}\n\treturn true\n")
+}
+
+// A referenced rule with a non-greedy element is matched together with what
+// follows the reference, like a group, unless it is recursive
+func TestGenerateTokenizerNonGreedyFragment(t *testing.T) {
+	top := parseGrammar(t, "TAG: '<' BODY '>';\nLIST: '[' ITEMS ']';\n"+
+		"fragment BODY: .*?;\nfragment ITEMS: 'a' | 'b' ITEMS .*?;")
+
+	code, err := gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+
+	src := string(code)
+	assert.Contains(t, src, "// BODY '>'\n\tif t.matchTagSub1() {\n")
+	assert.Contains(t, src, "// .*? '>'\n\tfor !t.lex.MatchChar('>') {\n")
+	assert.Contains(t, src, "// ITEMS\n\tif !t.matchItems() {\n")
+}
+
+// Lossless tokenizers keep the text of every token
+func TestGenerateTokenizerLossless(t *testing.T) {
+	top := parseGrammar(t, "IF: 'if';\nNAME: [a-z]+;\nSEMI: ';';\nWS: ' '+ -> skip;\nNL: '\\n' -> skip;")
+
+	code, err := gen.GenerateTokenizer(top, &gen.Options{Package: "x", Lossless: true})
+	require.NoError(t, err)
+
+	src := string(code)
+	assert.Contains(t, src, "\tWS\n\tNL\n)")
+	assert.Contains(t, src, "case 1: // SEMI\n\t\t\tt.lex.BuildTokenData(SEMI, tok)\n")
+	assert.Contains(t, src, "if tt, ok := keywords[tok.Data]; ok {\n\t\t\t\ttok.Type = tt\n\t\t\t}\n")
+	// Each skipped token is kept as trivia with its own type
+	assert.Contains(t, src, "case 2: // WS\n\t\t\tt.lex.BuildTrivia(WS)\n\t\t\tcontinue\n"+
+		"\t\tcase 3: // NL\n\t\t\tt.lex.BuildTrivia(NL)\n\t\t\tcontinue\n")
+	// So is a byte order mark at the start of the input
+	assert.Contains(t, src, "func New(lex *runtime.Lexer) *Tokenizer {\n\tlex.KeepBOM()\n")
+	assert.Contains(t, src, "runtime.BOM: \"BOM\",\n")
+
+	code, err = gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(code), "BOM")
+}
+
+func TestGenerateTokenizerChannels(t *testing.T) {
+	top := parseGrammar(t, "A: 'a';\nDOC: '/**' .*? '*/' -> channel(DOCS);\n"+
+		"COMMENT: '//' ~[\\n]* -> channel(HIDDEN);\nB: 'b' -> channel(DOCS), popMode;")
+
+	code, err := gen.GenerateTokenizer(top, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+
+	src := string(code)
+	assert.Contains(t, src, "const (\n\tDOCS runtime.Channel = iota + runtime.HiddenChannel + 1\n)")
+	assert.Contains(t, src, "t.lex.BuildTokenData(DOC, tok)\n\t\ttok.Channel = DOCS\n")
+	assert.Contains(t, src, "t.lex.BuildTokenData(COMMENT, tok)\n\t\ttok.Channel = runtime.HiddenChannel\n")
+	// A mode stack underflow turns the token into an ILLEGAL token on the default
+	// channel, even when its rule sets another channel
+	assert.Contains(t, src, "tok.Channel = DOCS\n\t\tif t.lex.PopMode() != nil {\n"+
+		"\t\t\t// No mode to return to, so the token is not valid here\n"+
+		"\t\t\tt.lex.MakeIllegal(tok, runtime.ModeStackUnderflow)\n\t\t}\n")
+}
+
+func TestGenerateTokenizerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		err     string
+	}{
+		{
+			name:    "undefined rule",
+			grammar: "A: B;",
+			err:     "A: undefined lexer rule: B",
+		},
+		{
+			name:    "duplicate rule",
+			grammar: "A: 'a';\nA: 'b';",
+			err:     "duplicate lexer rule: A",
+		},
+		{
+			name:    "bad negation",
+			grammar: "A: ~('a'*);",
+			err:     "A: unable to negate: not a char set: 'a'*",
+		},
+		{
+			name:    "bad char class",
+			grammar: "A: [z-a];",
+			err:     "A: invalid char range: z-a",
+		},
+		{
+			name:    "undefined mode",
+			grammar: "A: 'a' -> pushMode(B);",
+			err:     "A: undefined mode: B",
+		},
+		{
+			name:    "duplicate mode",
+			grammar: "mode B;\nA: 'a';\nmode B;",
+			err:     "duplicate mode: B",
+		},
+		{
+			name:    "mode conflict",
+			grammar: "A: 'a';\nmode A;\nB: 'b';",
+			err:     "mode conflicts with lexer rule: A",
+		},
+		{
+			name:    "fragment mode change",
+			grammar: "A: B;\nfragment B: 'b' -> popMode;",
+			err:     "B: fragment rules can not change modes",
+		},
+		{
+			name:    "skipped channel",
+			grammar: "A: 'a' -> skip, channel(HIDDEN);",
+			err:     "A: skipped and fragment rules can not have a channel",
+		},
+		{
+			name:    "channel conflict",
+			grammar: "A: 'a' -> channel(B);\nB: 'b';",
+			err:     "channel conflicts with lexer rule: B",
+		},
+		{
+			name:    "implicit token conflict",
+			grammar: "a: 'end';\n\nEND: 'e' 'nd';",
+			err:     "a: implicit token for literal 'end' conflicts with lexer rule: END",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := gen.GenerateTokenizer(parseGrammar(t, test.grammar), &gen.Options{Package: "x"})
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
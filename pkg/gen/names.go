@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// pascalCase converts a snake case name (either upper or lower) to pascal case
+func pascalCase(name string) string {
+	buff := strings.Builder{}
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(strings.ToLower(part))
+		runes[0] = unicode.ToUpper(runes[0])
+		buff.WriteString(string(runes))
+	}
+	return buff.String()
+}
+
+// camelCase converts a snake case name (either upper or lower) to camel case
+func camelCase(name string) string {
+	runes := []rune(pascalCase(name))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+	return string(runes)
+}
+
+func upperFirst(ident string) string {
+	runes := []rune(ident)
+	if len(runes) == 0 {
+		return ""
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// reservedIdents are the identifiers generated parser code declares itself
+// (and the runtime package), which the variables of elements must not shadow
+var reservedIdents = map[string]bool{
+	"err":     true,
+	"loopPos": true,
+	"memo":    true,
+	"ok":      true,
+	"oldPos":  true,
+	"p":       true,
+	"pos":     true,
+	"result":  true,
+	"runtime": true,
+}
+
+// safeIdent makes sure the identifier given doesn't collide with a Go keyword
+// or a reserved identifier
+func safeIdent(ident string) string {
+	if token.IsKeyword(ident) || reservedIdents[ident] {
+		return ident + "_"
+	}
+	return ident
+}
@@ -0,0 +1,1586 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+const tokenType = "*runtime.Token"
+
+// unit is anything that gets its own parse function: a parser rule or one of
+// the sub-rules extracted from it for a nested group or alternative
+type unit struct {
+	name  string // ex: "rule_body" or "rule_body.sub1"
+	ident string // ex: "ruleBody" or "ruleBodySub1"
+	text  string // grammar text of the body, used for comments
+	typ   string // Go return type of the parse function
+	sub   bool
+	rule  *unit // Parser rule this unit was extracted from (itself for rules)
+	subs  []*unit
+	alts  [][]ast.ParserNode
+	// Label of each alternative (empty when not labeled) or nil if none are
+	labels []string
+	// Set for a sub-rule extracted from a labeled alternative
+	label string
+
+	// Set for rules without action code blocks (and their sub-rules), whose
+	// result types are generated with exported names and fields
+	exported bool
+	// Name of the generated struct type, if any
+	structName string
+	// Set if the result type is a generated interface, implemented by the
+	// types of the sub-rules of each alternative
+	iface bool
+	// Unit whose interface the type of this unit implements, if any
+	implements *unit
+
+	// Element sequence per alternative. Units with more than one alternative
+	// always have exactly one element per alternative
+	seqs   [][]*element
+	fields []*element // Struct fields for sub-rules with a generated type
+	// List labels collected by the unit, also struct fields when it has a
+	// generated type
+	lists []*listLabel
+
+	// Set for units that can succeed without consuming any tokens
+	nullable bool
+	// Set for left recursive units. See analyzeLeftRecursion
+	leader     bool
+	unmemoized bool
+}
+
+func (u *unit) memoFunc() string {
+	return "memoParse" + u.funcSuffix()
+}
+
+// parseFunc returns the name of the function that parses the unit body. The
+// body of a left recursion leader only parses a single pass, so it is
+// unexported and its exported function grows the result instead
+func (u *unit) parseFunc() string {
+	if u.sub || u.leader {
+		return "parse" + u.funcSuffix()
+	}
+	return u.exportedFunc()
+}
+
+func (u *unit) exportedFunc() string {
+	return "Parse" + u.funcSuffix()
+}
+
+func (u *unit) funcSuffix() string {
+	return upperFirst(u.ident)
+}
+
+// varName returns the name of a variable holding the result of the unit
+func (u *unit) varName() string {
+	return safeIdent(u.ident)
+}
+
+func (u *unit) structType() bool {
+	return u.structName != ""
+}
+
+// field returns the name of the struct field for an element or list name
+func (u *unit) field(name string) string {
+	if u.exported {
+		return upperFirst(name)
+	}
+	if token.IsKeyword(name) {
+		return name + "_"
+	}
+	return name
+}
+
+// what describes what a unit parses for the doc comments of generated types
+func (u *unit) what() string {
+	switch {
+	case !u.sub:
+		return fmt.Sprintf("the \"%s\" parser rule", u.name)
+	case u.label != "":
+		return fmt.Sprintf("the %s alternative of the \"%s\" parser rule", u.label, u.rule.name)
+	default:
+		return fmt.Sprintf("a sub-rule of the \"%s\" parser rule", u.rule.name)
+	}
+}
+
+// marker is the name of the method that marks the types implementing the
+// generated interface of a unit
+func (u *unit) marker() string {
+	return "is" + u.typ
+}
+
+func (u *unit) altLabel(alt int) string {
+	if alt < len(u.labels) {
+		return u.labels[alt]
+	}
+	return ""
+}
+
+// listLabel is a `name+=` label. Every element it labels is added to the same
+// list, including those of sub-rules with a generated type, which hand theirs
+// up to the sequence that uses them
+type listLabel struct {
+	name string
+	typ  string // Go type of the list
+}
+
+// element is a single (possibly suffixed) rule, sub-rule or token reference
+// in a sequence
+type element struct {
+	text    string // grammar text, used for comments
+	suffix  rune   // One of: 0, '?', '*', '+'
+	name    string // variable name of a single match
+	typ     string // Go type of a single match
+	tokType string // Go token type expression when a token reference
+	callee  *unit  // Unit parsed when a rule or sub-rule reference
+	label   string // User label, if any
+	list    bool   // Set if label is a list label
+	pred    rune   // One of: 0, '&', '!' - predicates only test for a match
+}
+
+func (e *element) many() bool {
+	return e.suffix == '*' || e.suffix == '+'
+}
+
+func (e *element) canFail() bool {
+	return e.suffix == 0 || e.suffix == '+'
+}
+
+// fieldName returns the name of the element before it is made safe to use as
+// a variable, from which the name of its struct field is derived
+func (e *element) fieldName() string {
+	if e.label != "" && !e.list {
+		return e.label
+	}
+	if e.many() {
+		return e.name + "s"
+	}
+	return e.name
+}
+
+func (e *element) varName() string {
+	return safeIdent(e.fieldName())
+}
+
+// itemName returns the name of the variable holding a single match of an
+// element that matches many
+func (e *element) itemName() string {
+	return safeIdent(e.name)
+}
+
+func (e *element) varType() string {
+	if e.many() {
+		return "[]" + e.typ
+	}
+	return e.typ
+}
+
+// lists returns the list labels the element adds to
+func (e *element) lists() []*listLabel {
+	if e.pred != 0 {
+		return nil
+	}
+	if e.list {
+		return []*listLabel{{name: e.label, typ: "[]" + e.typ}}
+	}
+	if e.callee != nil && e.callee.sub && e.callee.structType() {
+		return e.callee.lists
+	}
+	return nil
+}
+
+// matchExpr returns an expression that tries to match a single occurrence of
+// this element, evaluating to nil on failure without consuming any input
+func (e *element) matchExpr() string {
+	if e.callee != nil {
+		return fmt.Sprintf("p.%s()", e.callee.memoFunc())
+	}
+	return fmt.Sprintf("p.p.TryMatchToken(%s)", e.tokType)
+}
+
+type parserGen struct {
+	top    *ast.TopLevel
+	body   *ast.Body
+	opts   *Options
+	blocks map[string]*ast.CodeBlock
+	used   map[string]bool
+	// types are the names of generated types and what they were generated for
+	types map[string]string
+
+	literals map[string]string // Quoted literal -> token name
+	rules    map[string]*unit
+	units    []*unit
+}
+
+// GenerateParser generates the Go source code for a memoizing (packrat)
+// recursive descent parser for the parser rules of a grammar. The code blocks
+// of the body provide the result type and action code for each rule. Rules
+// without any action code blocks get generated result types instead: a struct
+// with a field per element or, for rules with labeled alternatives, an
+// interface implemented by a struct per alternative
+func GenerateParser(top *ast.TopLevel, body *ast.Body, opts *Options) ([]byte, error) {
+	if body.CodeBlocks.Language != "go" {
+		return nil, fmt.Errorf("unsupported code block language: %s", body.CodeBlocks.Language)
+	}
+	if len(top.ParserRules) == 0 {
+		return nil, errors.New("grammar has no parser rules")
+	}
+
+	g := &parserGen{
+		top:      top,
+		body:     body,
+		opts:     opts,
+		blocks:   make(map[string]*ast.CodeBlock, len(body.CodeBlocks.Blocks)),
+		used:     make(map[string]bool, len(body.CodeBlocks.Blocks)),
+		types:    make(map[string]string, 16),
+		literals: make(map[string]string, 16),
+		rules:    make(map[string]*unit, len(top.ParserRules)),
+	}
+	for _, block := range body.CodeBlocks.Blocks {
+		name := block.Name()
+		if _, ok := g.blocks[name]; ok {
+			return nil, fmt.Errorf("duplicate code block for rule: %s", name)
+		}
+		if block.Kind != ast.ActionBlock && block.Type != "" {
+			return nil, fmt.Errorf("only action code blocks have a result type: %s", name)
+		}
+		g.blocks[name] = block
+	}
+	for _, rule := range top.LexerRules {
+		if lit, ok := rule.Literal(); ok {
+			g.literals[lit] = rule.Name
+		}
+		g.types[rule.Name] = "token " + rule.Name
+	}
+	for _, name := range []string{
+		"Parser", "Tokenizer", "New", "NewParser", "NewTokenizer",
+		"Node", "Visitor", "BaseVisitor", "Walk", "Listener", "BaseListener", "WalkListener",
+	} {
+		g.types[name] = name
+	}
+
+	if err := g.analyze(); err != nil {
+		return nil, err
+	}
+	return g.generate()
+}
+
+// *** Analysis ***
+
+func (g *parserGen) analyze() error {
+	// First pass to establish all rule types since they can be referenced
+	// before they are defined
+	for _, rule := range g.top.ParserRules {
+		if _, ok := g.rules[rule.Name]; ok {
+			return fmt.Errorf("duplicate parser rule: %s", rule.Name)
+		}
+		u := &unit{
+			name:   rule.Name,
+			ident:  camelCase(rule.Name),
+			text:   altsText(rule.Rules.Rules),
+			alts:   rule.Rules.Rules,
+			labels: altLabels(rule.Rules),
+		}
+		u.rule = u
+		typ, err := g.unitType(u)
+		if err != nil {
+			return err
+		}
+		if typ == "" {
+			if g.hasActions(u) {
+				return fmt.Errorf("no code block with a result type for rule: %s", rule.Name)
+			}
+			u.exported = true
+			if err := g.autoType(u, pascalCase(rule.Name), "rule "+rule.Name); err != nil {
+				return err
+			}
+		} else {
+			u.typ = typ
+		}
+		g.rules[rule.Name] = u
+	}
+
+	for _, rule := range g.top.ParserRules {
+		u := g.rules[rule.Name]
+		if err := g.analyzeUnit(u); err != nil {
+			return err
+		}
+		g.units = append(g.units, u)
+		g.units = append(g.units, u.subs...)
+	}
+
+	return g.analyzeLeftRecursion()
+}
+
+// unitType finds the result type declared by the code blocks of a unit. The
+// unit block takes precedence over the blocks of its alternatives
+func (g *parserGen) unitType(u *unit) (string, error) {
+	if block := g.blocks[u.name]; block != nil && block.Type != "" {
+		return block.Type, checkResultType(u.name, block.Type)
+	}
+
+	typ := ""
+	for i := range u.alts {
+		for _, kind := range []ast.CodeBlockKind{ast.ActionBlock, ast.PredicateBlock} {
+			name, block := g.altBlock(u, i, kind)
+			other := blockName(altName(u, i), kind)
+			if block != nil && name != other && g.blocks[other] != nil {
+				return "", fmt.Errorf("code blocks %s and %s are for the same alternative", other, name)
+			}
+		}
+
+		_, block := g.altBlock(u, i, ast.ActionBlock)
+		if block == nil || block.Type == "" {
+			continue
+		}
+		if typ != "" && typ != block.Type {
+			return "", fmt.Errorf("conflicting result types for %s: %s and %s",
+				u.name, typ, block.Type)
+		}
+		typ = block.Type
+	}
+	if typ != "" {
+		return typ, checkResultType(u.name, typ)
+	}
+	return typ, nil
+}
+
+// basicTypes are the predeclared types that can't be nil
+var basicTypes = map[string]bool{
+	"bool": true, "byte": true, "complex64": true, "complex128": true,
+	"float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+}
+
+// checkResultType returns an error if a result type can't be nil, as parse
+// functions return nil when they fail. Named types are assumed to be nilable
+// (ex: interfaces), since only the Go compiler knows what they are
+func checkResultType(name, typ string) error {
+	expr, err := goparser.ParseExpr(typ)
+	if err != nil {
+		return fmt.Errorf("invalid result type for %s: %s", name, typ)
+	}
+	nilable := true
+	switch t := expr.(type) {
+	case *goast.Ident:
+		nilable = !basicTypes[t.Name]
+	case *goast.ArrayType:
+		nilable = t.Len == nil
+	case *goast.StructType:
+		nilable = false
+	}
+	if !nilable {
+		return fmt.Errorf("result type for %s must be a pointer, slice, map, channel, "+
+			"function or interface, since failed parses return nil: %s", name, typ)
+	}
+	return nil
+}
+
+// hasActions returns true if there are any action code blocks for a unit or
+// its alternatives
+func (g *parserGen) hasActions(u *unit) bool {
+	if g.blocks[u.name] != nil {
+		return true
+	}
+	for i := range u.alts {
+		if _, block := g.altBlock(u, i, ast.ActionBlock); block != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// autoType sets the result type of a unit to a generated type with the given
+// name. Units with labeled alternatives get an interface, everything else
+// gets a struct
+func (g *parserGen) autoType(u *unit, name, what string) error {
+	if prev, ok := g.types[name]; ok {
+		return fmt.Errorf("generated type %s for %s conflicts with %s", name, what, prev)
+	}
+	g.types[name] = what
+
+	if len(u.alts) > 1 && u.labels != nil {
+		u.typ, u.iface = name, true
+	} else {
+		u.typ, u.structName = "*"+name, name
+	}
+	return nil
+}
+
+func altName(u *unit, alt int) string {
+	return fmt.Sprintf("%s.alt%d", u.name, alt+1)
+}
+
+// labelName is the code block name of a labeled alternative. Labels are unique
+// within a rule, so they are named after the rule
+func labelName(u *unit, label string) string {
+	return u.rule.name + "." + label
+}
+
+// blockName is the name of the code block of a kind for a rule, sub-rule or
+// alternative (ex: "expr.alt1.pred")
+func blockName(name string, kind ast.CodeBlockKind) string {
+	if kind == ast.ActionBlock {
+		return name
+	}
+	return name + "." + kind.String()
+}
+
+// altBlock finds a code block of an alternative, named either after its label
+// or its number
+func (g *parserGen) altBlock(u *unit, alt int, kind ast.CodeBlockKind) (string, *ast.CodeBlock) {
+	if label := u.altLabel(alt); label != "" {
+		name := blockName(labelName(u, label), kind)
+		if block := g.blocks[name]; block != nil {
+			return name, block
+		}
+	}
+	name := blockName(altName(u, alt), kind)
+	return name, g.blocks[name]
+}
+
+// hook returns the code of a block of a unit, marking it as used. Since a unit
+// with a single alternative is that alternative, its predicate can also be
+// given for the alternative
+func (g *parserGen) hook(u *unit, kind ast.CodeBlockKind) string {
+	name := blockName(u.name, kind)
+	block := g.blocks[name]
+	if block == nil && kind == ast.PredicateBlock && len(u.seqs) == 1 {
+		name, block = g.altBlock(u, 0, kind)
+	}
+	if block == nil {
+		return ""
+	}
+	g.used[name] = true
+	return block.Code
+}
+
+func altLabels(alts *ast.ParserAlternatives) []string {
+	if alts.Labels == nil {
+		return nil
+	}
+	labels := make([]string, len(alts.Rules))
+	for i := range labels {
+		labels[i] = alts.Label(i)
+	}
+	return labels
+}
+
+// newSub extracts a new sub-rule from the rule the given unit belongs to. A
+// sub-rule for a labeled alternative is named after the label and runs the
+// code block of the label itself, so the code can use the element labels
+func (g *parserGen) newSub(parent *unit, alts [][]ast.ParserNode, labels []string, label string) (*unit, error) {
+	rule := parent.rule
+	num := len(rule.subs) + 1
+	u := &unit{
+		name:     fmt.Sprintf("%s.sub%d", rule.name, num),
+		ident:    fmt.Sprintf("%sSub%d", rule.ident, num),
+		text:     altsText(alts),
+		sub:      true,
+		rule:     rule,
+		alts:     alts,
+		labels:   labels,
+		label:    label,
+		exported: parent.exported,
+	}
+	if label != "" {
+		u.name = labelName(parent, label)
+		u.ident = rule.ident + upperFirst(label)
+	}
+	rule.subs = append(rule.subs, u)
+
+	typ, err := g.unitType(u)
+	if err != nil {
+		return nil, err
+	}
+	if typ == "" && label != "" && g.blocks[u.name] != nil {
+		typ = parent.typ
+	}
+	switch {
+	case typ != "":
+		u.typ = typ
+	case u.exported:
+		what := "sub-rule " + u.name
+		if label != "" {
+			what = "alternative " + u.name
+		}
+		if err := g.autoType(u, upperFirst(u.ident), what); err != nil {
+			return nil, err
+		}
+	default:
+		u.typ, u.structName = "*"+u.ident, u.ident
+	}
+
+	if err := g.analyzeUnit(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (g *parserGen) analyzeUnit(u *unit) error {
+	if len(u.alts) == 1 {
+		seq, err := g.sequence(u, u.alts[0])
+		if err != nil {
+			return err
+		}
+		u.seqs = [][]*element{seq}
+	} else {
+		// Each alternative must be tried as a single element, so anything more
+		// involved than a single required reference becomes a sub-rule. Each
+		// alternative of a generated interface has its own type, so it always
+		// becomes a sub-rule
+		for i, alt := range u.alts {
+			nodes := alt
+			if len(alt) != 1 || !isSingle(alt[0]) || u.iface {
+				sub, err := g.newSub(u, [][]ast.ParserNode{alt}, nil, u.altLabel(i))
+				if err != nil {
+					return err
+				}
+				if u.iface {
+					sub.implements = u
+				}
+				nodes = []ast.ParserNode{&subRuleRef{unit: sub}}
+			}
+
+			seq, err := g.sequence(u, nodes)
+			if err != nil {
+				return err
+			}
+			u.seqs = append(u.seqs, seq)
+		}
+	}
+
+	// The alternatives of an interface keep their lists to themselves
+	lists := make(map[string]*listLabel, 4)
+	for _, seq := range u.seqs {
+		if u.iface {
+			break
+		}
+		for _, elem := range seq {
+			for _, list := range elem.lists() {
+				prev, ok := lists[list.name]
+				if !ok {
+					lists[list.name] = list
+					u.lists = append(u.lists, list)
+				} else if prev.typ != list.typ {
+					return fmt.Errorf("%s: conflicting types for list label %s: %s and %s",
+						u.name, list.name, prev.typ, list.typ)
+				}
+			}
+		}
+	}
+
+	if u.structType() {
+		seen := make(map[string]*element, 8)
+		for _, seq := range u.seqs {
+			for _, elem := range seq {
+				if elem.pred != 0 {
+					continue
+				}
+				field := u.field(elem.fieldName())
+				prev, ok := seen[field]
+				if !ok {
+					if _, ok := lists[elem.varName()]; ok {
+						return fmt.Errorf("%s: %s is both a list label and an element",
+							u.name, elem.varName())
+					}
+					seen[field] = elem
+					u.fields = append(u.fields, elem)
+				} else if prev.varName() != elem.varName() {
+					return fmt.Errorf("%s: %s and %s have the same field name: %s", u.name,
+						prev.varName(), elem.varName(), field)
+				} else if prev.varType() != elem.varType() {
+					return fmt.Errorf("%s: conflicting types for %s: %s and %s", u.name,
+						elem.varName(), prev.varType(), elem.varType())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// subRuleRef is a placeholder node for an alternative extracted to a sub-rule
+type subRuleRef struct {
+	ast.Span
+	unit *unit
+}
+
+func (s *subRuleRef) ParserNode() {}
+
+func (s *subRuleRef) String(indent int) string { return s.unit.text }
+
+func isSingle(node ast.ParserNode) bool {
+	switch n := node.(type) {
+	case *ast.ParserRuleRef, *ast.ParserLexerRuleRef, *ast.ParserToken, *ast.ParserAlternatives:
+		return true
+	case *ast.ParserLabel:
+		// Lists are collected by sequences
+		return !n.List && isSingle(n.Node)
+	default:
+		return false
+	}
+}
+
+func (g *parserGen) sequence(u *unit, nodes []ast.ParserNode) ([]*element, error) {
+	seq := make([]*element, 0, len(nodes))
+	for _, node := range nodes {
+		elem, err := g.element(u, node)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, elem)
+	}
+
+	// Labels are chosen by the user, so generated names make way for them
+	counts := make(map[string]int, len(nodes))
+	lists := make(map[string]bool, 4)
+	for _, elem := range seq {
+		for _, list := range elem.lists() {
+			counts[list.name], lists[list.name] = 1, true
+		}
+	}
+	for _, elem := range seq {
+		if elem.label == "" || elem.list {
+			continue
+		}
+		if counts[elem.label] > 0 {
+			return nil, fmt.Errorf("%s: duplicate label: %s", u.name, elem.label)
+		}
+		counts[elem.label] = 1
+		if elem.many() && elem.name == elem.label {
+			// Keep the single match from hiding the list in the match loop
+			elem.name += "Item"
+		}
+	}
+
+	for _, elem := range seq {
+		if (elem.label != "" && !elem.list) || elem.pred != 0 {
+			continue
+		}
+		// Same reference more than once in a sequence needs unique names
+		counts[elem.varName()]++
+		if count := counts[elem.varName()]; count > 1 {
+			elem.name = fmt.Sprintf("%s%d", elem.name, count)
+		}
+	}
+	return seq, nil
+}
+
+func (g *parserGen) element(u *unit, node ast.ParserNode) (*element, error) {
+	switch n := node.(type) {
+	case *ast.ParserAndPredicate:
+		return g.predicate(u, node, '&', n.Node)
+	case *ast.ParserNotPredicate:
+		return g.predicate(u, node, '!', n.Node)
+	}
+
+	elem := &element{text: nodeText(node)}
+
+	if n, ok := node.(*ast.ParserLabel); ok {
+		if safeIdent(n.Name) != n.Name {
+			// Labels are used as is, since code blocks refer to them by name
+			return nil, &ast.Diagnostic{Span: n.Span, Severity: runtime.SeverityError, Message: fmt.Sprintf(
+				"%s: label %s is a Go keyword or used by the generated code", u.name, n.Name)}
+		}
+		elem.label, elem.list, node = n.Name, n.List, n.Node
+	}
+
+	switch n := node.(type) {
+	case *ast.ParserZeroOrMore:
+		elem.suffix, node = '*', n.Node
+	case *ast.ParserOneOrMore:
+		elem.suffix, node = '+', n.Node
+	case *ast.ParserZeroOrOne:
+		elem.suffix, node = '?', n.Node
+	}
+
+	switch n := node.(type) {
+	case *ast.ParserRuleRef:
+		callee, ok := g.rules[n.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: undefined parser rule: %s", u.name, n.Name)
+		}
+		elem.name, elem.typ, elem.callee = callee.ident, callee.typ, callee
+	case *ast.ParserLexerRuleRef:
+		elem.name, elem.typ, elem.tokType = g.tokenElem(n.Name)
+	case *ast.ParserToken:
+		name, ok := g.literals[n.Token.Data]
+		if !ok {
+			lit, err := ast.Unquote(n.Token.Data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", u.name, err)
+			}
+			if name = ast.LiteralName(lit); name == "" {
+				return nil, fmt.Errorf("%s: unable to name token for literal: %s",
+					u.name, n.Token.Data)
+			}
+		}
+		elem.name, elem.typ, elem.tokType = g.tokenElem(name)
+	case *subRuleRef:
+		elem.name, elem.typ, elem.callee = n.unit.ident, n.unit.typ, n.unit
+	case *ast.ParserAlternatives:
+		sub, err := g.newSub(u, n.Rules, altLabels(n), "")
+		if err != nil {
+			return nil, err
+		}
+		elem.name, elem.typ, elem.callee = sub.ident, sub.typ, sub
+	default:
+		// Suffix applied directly to a suffixed node - treat it as a group
+		sub, err := g.newSub(u, [][]ast.ParserNode{{node}}, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		elem.name, elem.typ, elem.callee = sub.ident, sub.typ, sub
+	}
+	return elem, nil
+}
+
+// predicate creates an element that tests for a match of its operand without
+// consuming any tokens. Anything more involved than a single reference is
+// tested through a sub-rule
+func (g *parserGen) predicate(u *unit, node ast.ParserNode, pred rune, operand ast.ParserNode) (*element, error) {
+	if !isSingle(operand) {
+		operand = &ast.ParserAlternatives{
+			Span: operand.NodeSpan(), Rules: [][]ast.ParserNode{{operand}},
+		}
+	}
+	elem, err := g.element(u, operand)
+	if err != nil {
+		return nil, err
+	}
+	elem.text, elem.pred = nodeText(node), pred
+	return elem, nil
+}
+
+func (g *parserGen) tokenElem(name string) (varName, typ, tokType string) {
+	tokType = g.opts.tokenPrefix() + name
+	if name == "EOF" {
+		tokType = "runtime.EOF"
+	}
+	return safeIdent(camelCase(name) + "Tok"), tokenType, tokType
+}
+
+// *** Grammar text ***
+
+func nodeText(node ast.ParserNode) string {
+	switch n := node.(type) {
+	case *ast.ParserAlternatives:
+		return "(" + altsText(n.Rules) + ")"
+	case *ast.ParserZeroOrMore:
+		return nodeText(n.Node) + "*"
+	case *ast.ParserOneOrMore:
+		return nodeText(n.Node) + "+"
+	case *ast.ParserZeroOrOne:
+		return nodeText(n.Node) + "?"
+	case *ast.ParserAndPredicate:
+		return "&" + nodeText(n.Node)
+	case *ast.ParserNotPredicate:
+		return "!" + nodeText(n.Node)
+	case *ast.ParserLabel:
+		if n.List {
+			return n.Name + "+=" + nodeText(n.Node)
+		}
+		return n.Name + "=" + nodeText(n.Node)
+	case *ast.ParserRuleRef:
+		return n.Name
+	case *ast.ParserLexerRuleRef:
+		return n.Name
+	case *ast.ParserToken:
+		return n.Token.Data
+	case *subRuleRef:
+		return n.unit.text
+	default:
+		return fmt.Sprintf("%T", node)
+	}
+}
+
+func altsText(alts [][]ast.ParserNode) string {
+	texts := make([]string, 0, len(alts))
+	for _, alt := range alts {
+		nodes := make([]string, 0, len(alt))
+		for _, node := range alt {
+			nodes = append(nodes, nodeText(node))
+		}
+		texts = append(texts, strings.Join(nodes, " "))
+	}
+	return strings.Join(texts, " | ")
+}
+
+// *** Generation ***
+
+func (g *parserGen) generate() ([]byte, error) {
+	imports := append([]string{runtimeImport}, g.opts.Imports...)
+	if g.opts.TokenImport != "" {
+		imports = append(imports, g.opts.TokenImport)
+	}
+
+	w := new(writer)
+	w.Header(g.body.Parser, g.opts.Package, imports)
+	w.Blank()
+	g.parserType(w)
+
+	for _, u := range g.units {
+		if err := g.emitUnit(w, u); err != nil {
+			return nil, err
+		}
+	}
+	if g.hasVisitor() {
+		g.visitor(w)
+	}
+
+	unused := []string{}
+	for name := range g.blocks {
+		if !g.used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return nil, fmt.Errorf("code blocks do not match any rule: %s", strings.Join(unused, ", "))
+	}
+	return w.Format()
+}
+
+func (g *parserGen) parserType(w *writer) {
+	fields, methods := "", ""
+	if block := g.blocks["members"]; block != nil {
+		g.used["members"] = true
+		fields, methods = splitMembers(block.Code)
+	}
+
+	w.Line("// Parser is a packrat parser that memoizes the result of each rule")
+	w.Line("type Parser struct {")
+	w.Line("p *runtime.Parser")
+	w.Blank()
+	for _, u := range g.units {
+		if !u.unmemoized {
+			w.Line("%sMap map[int]runtime.Memo", u.ident)
+		}
+	}
+	if fields != "" {
+		w.Blank()
+		w.Line("%s", fields)
+	}
+	w.Line("}")
+	w.Blank()
+
+	name := g.opts.constructor("Parser")
+	w.Line("// %s creates a new parser that reads tokens from the given runtime parser", name)
+	w.Line("func %s(p *runtime.Parser) *Parser {", name)
+	w.Line("return &Parser{")
+	w.Line("p: p,")
+	for _, u := range g.units {
+		if !u.unmemoized {
+			w.Line("%sMap: make(map[int]runtime.Memo, 8),", u.ident)
+		}
+	}
+	w.Line("}")
+	w.Line("}")
+	w.Blank()
+
+	// The first parser rule is the start rule
+	start := g.rules[g.top.ParserRules[0].Name]
+	w.Line("// Parse parses the input starting from the \"%s\" parser rule. If", start.name)
+	w.Line("// parsing fails, the error describes the farthest point reached")
+	w.Line("func (p *Parser) Parse() (%s, error) {", start.typ)
+	w.Line("%s := p.%s()", start.varName(), start.exportedFunc())
+	w.Line("if %s == nil {", start.varName())
+	w.Line("return nil, p.p.Err()")
+	w.Line("}")
+	w.Line("return %s, nil", start.varName())
+	w.Line("}")
+
+	if g.opts.Lossless && g.hasVisitor() {
+		w.Blank()
+		w.Line("// ParseCST parses the input like Parse, but returns its concrete syntax tree.")
+		w.Line("// Trivia at the end of the input belongs to the EOF token, which is added to")
+		w.Line("// the tree when the start rule doesn't match EOF itself")
+		w.Line("func (p *Parser) ParseCST() (*runtime.CSTNode, error) {")
+		w.Line("%s, err := p.Parse()", start.varName())
+		w.Line("if err != nil {")
+		w.Line("return nil, err")
+		w.Line("}")
+		w.Line("cst := CST(%s)", start.varName())
+		w.Line("if tok := p.p.CurrToken(); tok.Type == runtime.EOF && len(tok.Trivia) > 0 {")
+		w.Line("cst.Children = append(cst.Children, &runtime.CSTNode{Token: tok})")
+		w.Line("}")
+		w.Line("return cst, nil")
+		w.Line("}")
+	}
+
+	if methods != "" {
+		w.Blank()
+		w.Line("%s", methods)
+	}
+}
+
+func (g *parserGen) emitUnit(w *writer, u *unit) error {
+	w.Blank()
+	if u.sub {
+		w.Line("// *** %s - %s ***", u.rule.name, u.text)
+	} else {
+		w.Line("// *** %s ***", u.name)
+	}
+	w.Blank()
+
+	if u.iface {
+		w.Line("// %s is the result of %s.", u.typ, u.what())
+		w.Line("// Each of its alternatives has a type implementing it")
+		w.Line("type %s interface {", u.typ)
+		w.Line("Node")
+		w.Line("%s()", u.marker())
+		w.Line("}")
+		w.Blank()
+	}
+	if u.structType() {
+		if u.exported {
+			w.Line("// %s is the result of %s", u.structName, u.what())
+		}
+		w.Line("type %s struct {", u.structName)
+		for _, field := range u.fields {
+			w.Line("%s %s", u.field(field.fieldName()), field.varType())
+		}
+		for _, list := range u.lists {
+			w.Line("%s %s", u.field(list.name), list.typ)
+		}
+		w.Line("}")
+		w.Blank()
+	}
+	if u.implements != nil {
+		w.Line("func (*%s) %s() {}", u.structName, u.implements.marker())
+		w.Blank()
+	}
+	if u.exported && u.structType() {
+		g.eachChild(w, u)
+		w.Blank()
+	}
+
+	w.Line("func (p *Parser) %s() %s {", u.memoFunc(), u.typ)
+	switch {
+	case u.leader:
+		w.Line("// Left recursive - grow the result from a failed seed until it stops getting longer")
+		w.Line("result := p.p.GrowSeed(p.%sMap, func() (interface{}, bool) {", u.ident)
+		w.Line("%s := p.%s()", u.varName(), u.parseFunc())
+		w.Line("return %s, %s != nil", u.varName(), u.varName())
+		w.Line("})")
+		w.Line("%s, _ := result.(%s)", u.varName(), u.typ)
+		w.Line("return %s", u.varName())
+	case u.unmemoized:
+		w.Line("// Part of a left recursive rule, so the result changes as the rule grows")
+		w.Line("return p.%s()", u.parseFunc())
+	default:
+		w.Line("pos := p.p.Pos()")
+		w.Line("if memo, ok := p.%sMap[pos]; ok {", u.ident)
+		w.Line("p.p.SetPos(memo.EndPos)")
+		w.Line("%s, _ := memo.Result.(%s)", u.varName(), u.typ)
+		w.Line("return %s", u.varName())
+		w.Line("}")
+		w.Line("%s := p.%s()", u.varName(), u.parseFunc())
+		w.Line("// Memoize what we did here in case this exact rule/position is needed again")
+		w.Line("p.%sMap[pos] = runtime.Memo{Result: %s, EndPos: p.p.Pos()}", u.ident, u.varName())
+		w.Line("return %s", u.varName())
+	}
+	w.Line("}")
+	w.Blank()
+
+	if u.leader && !u.sub {
+		w.Line("// %s parses the \"%s\" parser rule", u.exportedFunc(), u.name)
+		w.Line("func (p *Parser) %s() %s {", u.exportedFunc(), u.typ)
+		w.Line("return p.%s()", u.memoFunc())
+		w.Line("}")
+		w.Blank()
+	}
+
+	switch {
+	case u.sub:
+		w.Line("// %s parses a sub-rule of the \"%s\" parser rule",
+			u.parseFunc(), u.rule.name)
+	case u.leader:
+		w.Line("// %s parses one pass of the left recursive \"%s\" parser rule",
+			u.parseFunc(), u.name)
+	default:
+		w.Line("// %s parses the \"%s\" parser rule", u.parseFunc(), u.name)
+	}
+	w.Line("func (p *Parser) %s() %s {", u.parseFunc(), u.typ)
+	g.emitHooks(w, u)
+	var err error
+	if len(u.seqs) == 1 {
+		err = g.sequenceBody(w, u)
+	} else {
+		err = g.alternativesBody(w, u)
+	}
+	w.Line("}")
+	return err
+}
+
+// emitHooks emits the init, after and predicate blocks of a unit. As results
+// are memoized, they only run when a unit is actually parsed at a position,
+// not when its memoized result is reused
+func (g *parserGen) emitHooks(w *writer, u *unit) {
+	if code := g.hook(u, ast.InitBlock); code != "" {
+		w.Line("%s", code)
+		w.Blank()
+	}
+	if code := g.hook(u, ast.AfterBlock); code != "" {
+		w.Line("defer func() {")
+		w.Line("%s", code)
+		w.Line("}()")
+		w.Blank()
+	}
+	if code := g.hook(u, ast.PredicateBlock); code != "" {
+		w.Line("if !(%s) {", code)
+		w.Line("// Predicate failed")
+		w.Line("p.p.FailPredicate()")
+		w.Line("return nil")
+		w.Line("}")
+		w.Blank()
+	}
+}
+
+// code returns the action code for an alternative of a unit
+func (g *parserGen) code(u *unit, alt int) (string, error) {
+	seq := u.seqs[alt]
+	if callee := seq[0].callee; len(u.seqs) > 1 && callee != nil && callee.label != "" &&
+		g.blocks[callee.name] != nil {
+		// The sub-rule of a labeled alternative runs the code block itself
+		return "return " + seq[0].varName(), nil
+	}
+	if u.iface {
+		// The sub-rule of each alternative has a type implementing the interface
+		return "return " + seq[0].varName(), nil
+	}
+
+	name, block := g.altBlock(u, alt, ast.ActionBlock)
+	if block == nil {
+		name = u.name
+		block = g.blocks[name]
+	}
+	if block != nil {
+		g.used[name] = true
+		return block.Code, nil
+	}
+
+	if !u.structType() {
+		return "", fmt.Errorf("no code block for rule: %s", u.name)
+	}
+	fields := make([]string, 0, len(seq)+len(u.lists))
+	for _, elem := range seq {
+		if elem.pred != 0 {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s", u.field(elem.fieldName()), elem.varName()))
+	}
+	if len(u.seqs) == 1 {
+		for _, list := range u.lists {
+			fields = append(fields, fmt.Sprintf("%s: %s", u.field(list.name), list.name))
+		}
+	} else {
+		// Alternatives have a single element, which is always matched
+		for _, list := range seq[0].lists() {
+			fields = append(fields, fmt.Sprintf("%s: %s.%s", u.field(list.name), seq[0].varName(),
+				seq[0].callee.field(list.name)))
+		}
+	}
+	return fmt.Sprintf("return &%s{%s}", u.structName, strings.Join(fields, ", ")), nil
+}
+
+func (g *parserGen) alternativesBody(w *writer, u *unit) error {
+	for i, seq := range u.seqs {
+		code, err := g.code(u, i)
+		if err != nil {
+			return err
+		}
+
+		elem := seq[0]
+		w.Line("// ### %s ###", elem.text)
+		pred := g.altPred(u, i)
+		if pred != "" {
+			w.Line("if %s {", pred)
+		}
+		w.Line("if %s := %s; %s != nil {", elem.varName(), elem.matchExpr(), elem.varName())
+		w.Line("%s", code)
+		w.Line("}")
+		if pred != "" {
+			w.Line("} else {")
+			w.Line("// Predicate failed")
+			w.Line("p.p.FailPredicate()")
+			w.Line("}")
+		}
+		w.Blank()
+	}
+
+	w.Line("// No alternative matched")
+	w.Line("return nil")
+	return nil
+}
+
+// altPred returns the predicate of an alternative of a unit with more than one.
+// The sub-rule of a labeled alternative tests the predicate of the label itself
+func (g *parserGen) altPred(u *unit, alt int) string {
+	name, block := g.altBlock(u, alt, ast.PredicateBlock)
+	if callee := u.seqs[alt][0].callee; block != nil && callee != nil && callee.label != "" &&
+		name == blockName(callee.name, ast.PredicateBlock) {
+		return ""
+	}
+	if block == nil {
+		return ""
+	}
+	g.used[name] = true
+	return block.Code
+}
+
+func (g *parserGen) sequenceBody(w *writer, u *unit) error {
+	code, err := g.code(u, 0)
+	if err != nil {
+		return err
+	}
+	idents := codeIdents(code)
+
+	seq := u.seqs[0]
+	for _, elem := range seq {
+		if elem.canFail() {
+			w.Line("// Rule can fail - might need to rollback")
+			w.Line("oldPos := p.p.Pos()")
+			w.Blank()
+			break
+		}
+	}
+
+	for _, list := range u.lists {
+		w.Line("%s := %s{}", list.name, list.typ)
+	}
+	if len(u.lists) > 0 {
+		w.Blank()
+	}
+
+	for _, elem := range seq {
+		w.Line("// ### %s ###", elem.text)
+		g.emitElement(w, elem, idents)
+		g.emitLists(w, elem)
+		w.Blank()
+	}
+
+	w.Line("%s", code)
+	return nil
+}
+
+func (g *parserGen) emitElement(w *writer, elem *element, idents map[string]bool) {
+	if elem.pred != 0 {
+		predicate := "AndPredicate"
+		if elem.pred == '!' {
+			predicate = "NotPredicate"
+		}
+		w.Line("if !p.p.%s(func() bool { return %s != nil }) {", predicate, elem.matchExpr())
+		w.Line("// Predicate failed - rollback")
+		w.Line("p.p.SetPos(oldPos)")
+		w.Line("return nil")
+		w.Line("}")
+		return
+	}
+
+	name := elem.varName()
+
+	switch elem.suffix {
+	case 0:
+		if elem.callee != nil {
+			w.Line("%s := %s", name, elem.matchExpr())
+			w.Line("if %s == nil {", name)
+			w.Line("// Rule failed - rollback")
+			w.Line("p.p.SetPos(oldPos)")
+		} else {
+			w.Line("%s := p.p.MatchTokenOrRollback(%s, oldPos)", name, elem.tokType)
+			w.Line("if %s == nil {", name)
+		}
+		w.Line("return nil")
+		w.Line("}")
+	case '?':
+		if idents[name] || len(elem.lists()) > 0 {
+			w.Line("%s := %s", name, elem.matchExpr())
+		} else {
+			w.Line("%s", elem.matchExpr())
+		}
+	case '*', '+':
+		w.Line("%s := %s{}", name, elem.varType())
+		w.Line("for {")
+		// A match that consumes nothing would match again forever, so it is the
+		// last one kept
+		progress := elem.callee != nil && elem.callee.nullable
+		if progress {
+			w.Line("loopPos := p.p.Pos()")
+		}
+		w.Line("%s := %s", elem.itemName(), elem.matchExpr())
+		w.Line("if %s == nil {", elem.itemName())
+		w.Line("break")
+		w.Line("}")
+		w.Line("%s = append(%s, %s)", name, name, elem.itemName())
+		if progress {
+			w.Line("if p.p.Pos() == loopPos {")
+			w.Line("// No progress made - stop, otherwise this would never end")
+			w.Line("break")
+			w.Line("}")
+		}
+		w.Line("}")
+
+		if elem.suffix == '+' {
+			w.Line("if len(%s) == 0 {", name)
+			w.Line("// Failed - rollback")
+			w.Line("p.p.SetPos(oldPos)")
+			w.Line("return nil")
+			w.Line("}")
+		}
+	}
+}
+
+// emitLists adds a matched element to the list it is labeled with, or the
+// lists of a matched sub-rule to the same lists of this sequence
+func (g *parserGen) emitLists(w *writer, elem *element) {
+	lists := elem.lists()
+	if len(lists) == 0 {
+		return
+	}
+	name := elem.varName()
+
+	if elem.list {
+		switch elem.suffix {
+		case 0:
+			w.Line("%s = append(%s, %s)", elem.label, elem.label, name)
+		case '?':
+			w.Line("if %s != nil {", name)
+			w.Line("%s = append(%s, %s)", elem.label, elem.label, name)
+			w.Line("}")
+		case '*', '+':
+			w.Line("%s = append(%s, %s...)", elem.label, elem.label, name)
+		}
+		return
+	}
+
+	sub := name
+	switch elem.suffix {
+	case '?':
+		w.Line("if %s != nil {", name)
+	case '*', '+':
+		sub = elem.itemName()
+		w.Line("for _, %s := range %s {", sub, name)
+	}
+	for _, list := range lists {
+		w.Line("%s = append(%s, %s.%s...)", list.name, list.name, sub, elem.callee.field(list.name))
+	}
+	if elem.suffix != 0 {
+		w.Line("}")
+	}
+}
+
+// *** Visitor ***
+
+// eachChild emits the method of a generated struct type that hands its tokens
+// and nodes to a function. List labels only repeat other fields, so they are
+// left out
+func (g *parserGen) eachChild(w *writer, u *unit) {
+	children := []*element{}
+	for _, field := range u.fields {
+		if field.tokType != "" || (field.callee != nil && field.callee.exported) {
+			children = append(children, field)
+		}
+	}
+	if len(children) == 0 {
+		w.Line("func (n *%s) eachChild(func(child interface{})) {}", u.structName)
+		return
+	}
+
+	w.Line("func (n *%s) eachChild(fn func(child interface{})) {", u.structName)
+	for _, child := range children {
+		field := u.field(child.fieldName())
+		if child.many() {
+			w.Line("for _, child := range n.%s {", field)
+			w.Line("fn(child)")
+		} else {
+			w.Line("if n.%s != nil {", field)
+			w.Line("fn(n.%s)", field)
+		}
+		w.Line("}")
+	}
+	w.Line("}")
+}
+
+// hasVisitor returns true if the parser has generated result types, which get
+// a visitor
+func (g *parserGen) hasVisitor() bool {
+	return g.rules[g.top.ParserRules[0].Name].exported || len(g.visited()) > 0
+}
+
+// visited returns the units with generated types that have their own visitor
+// methods: rules and labeled alternatives. Other sub-rules are only part of
+// the rule they are in
+func (g *parserGen) visited() []*unit {
+	units := []*unit{}
+	for _, u := range g.units {
+		if u.exported && u.structType() && (!u.sub || u.label != "") {
+			units = append(units, u)
+		}
+	}
+	return units
+}
+
+// visitor emits the visitor and listener for the generated types
+func (g *parserGen) visitor(w *writer) {
+	units := g.visited()
+
+	w.Blank()
+	w.Line("// *** Visitor ***")
+	w.Blank()
+	w.Line("// Node is implemented by every generated type of the parse tree")
+	w.Line("type Node interface {")
+	w.Line("// eachChild calls fn with each token and node directly below the node in order")
+	w.Line("eachChild(fn func(child interface{}))")
+	w.Line("}")
+	w.Blank()
+
+	w.Line("// Visitor visits the nodes of a parse tree in depth first order. A visit method")
+	w.Line("// returns whether the nodes below the visited node are visited. Sub-rules without")
+	w.Line("// a label have no visit method, but the nodes below them are visited")
+	w.Line("type Visitor interface {")
+	for _, u := range units {
+		w.Line("Visit%s(n *%s) bool", u.structName, u.structName)
+	}
+	w.Line("VisitToken(tok *runtime.Token)")
+	w.Line("}")
+	w.Blank()
+	w.Line("// BaseVisitor visits every node without doing anything. Embed it in a visitor")
+	w.Line("// to only implement the visit methods needed")
+	w.Line("type BaseVisitor struct{}")
+	w.Blank()
+	for _, u := range units {
+		w.Line("func (BaseVisitor) Visit%s(*%s) bool { return true }", u.structName, u.structName)
+	}
+	w.Line("func (BaseVisitor) VisitToken(*runtime.Token) {}")
+	w.Blank()
+
+	w.Line("// Walk visits a node and the nodes and tokens below it with a visitor")
+	w.Line("func Walk(v Visitor, node Node) {")
+	w.Line("if !visitNode(v, node) {")
+	w.Line("return")
+	w.Line("}")
+	g.walkChildren(w, "v.VisitToken(child)", "Walk(v, child)")
+	w.Line("}")
+	w.Blank()
+	g.dispatch(w, "visitNode", "v Visitor", "bool", "return v.Visit%s(n)", "return true")
+
+	w.Blank()
+	w.Line("// Listener is notified as a walk enters and exits each node of a parse tree. As")
+	w.Line("// with visitors, sub-rules without a label have no methods of their own")
+	w.Line("type Listener interface {")
+	for _, u := range units {
+		w.Line("Enter%s(n *%s)", u.structName, u.structName)
+		w.Line("Exit%s(n *%s)", u.structName, u.structName)
+	}
+	w.Line("VisitToken(tok *runtime.Token)")
+	w.Line("}")
+	w.Blank()
+	w.Line("// BaseListener ignores everything. Embed it in a listener to only implement the")
+	w.Line("// methods needed")
+	w.Line("type BaseListener struct{}")
+	w.Blank()
+	for _, u := range units {
+		w.Line("func (BaseListener) Enter%s(*%s) {}", u.structName, u.structName)
+		w.Line("func (BaseListener) Exit%s(*%s)  {}", u.structName, u.structName)
+	}
+	w.Line("func (BaseListener) VisitToken(*runtime.Token) {}")
+	w.Blank()
+
+	w.Line("// WalkListener walks a node and the nodes and tokens below it, notifying a")
+	w.Line("// listener as it goes")
+	w.Line("func WalkListener(l Listener, node Node) {")
+	w.Line("enterNode(l, node)")
+	g.walkChildren(w, "l.VisitToken(child)", "WalkListener(l, child)")
+	w.Line("exitNode(l, node)")
+	w.Line("}")
+	w.Blank()
+	g.dispatch(w, "enterNode", "l Listener", "", "l.Enter%s(n)", "")
+	w.Blank()
+	g.dispatch(w, "exitNode", "l Listener", "", "l.Exit%s(n)", "")
+
+	if g.opts.Lossless {
+		g.cst(w)
+	}
+}
+
+// cst emits the function building a concrete syntax tree from the generated
+// types. Its nodes are named after the rules, alternatives and sub-rules they
+// were parsed by
+func (g *parserGen) cst(w *writer) {
+	w.Blank()
+	w.Line("// *** CST ***")
+	w.Blank()
+	w.Line("// CST builds the concrete syntax tree of a node. It has a node for the node")
+	w.Line("// itself and each node and token below it")
+	w.Line("func CST(node Node) *runtime.CSTNode {")
+	w.Line("cst := &runtime.CSTNode{Name: nodeName(node)}")
+	w.Line("node.eachChild(func(child interface{}) {")
+	w.Line("switch child := child.(type) {")
+	w.Line("case *runtime.Token:")
+	w.Line("cst.Children = append(cst.Children, &runtime.CSTNode{Token: child})")
+	w.Line("case Node:")
+	w.Line("cst.Children = append(cst.Children, CST(child))")
+	w.Line("}")
+	w.Line("})")
+	w.Line("return cst")
+	w.Line("}")
+	w.Blank()
+	w.Line("func nodeName(node Node) string {")
+	w.Line("switch node.(type) {")
+	for _, u := range g.units {
+		if u.exported && u.structType() {
+			w.Line("case *%s:", u.structName)
+			w.Line("return %s", strconv.Quote(u.name))
+		}
+	}
+	w.Line("}")
+	w.Line("return \"\"")
+	w.Line("}")
+}
+
+func (g *parserGen) walkChildren(w *writer, token, node string) {
+	w.Line("node.eachChild(func(child interface{}) {")
+	w.Line("switch child := child.(type) {")
+	w.Line("case *runtime.Token:")
+	w.Line("%s", token)
+	w.Line("case Node:")
+	w.Line("%s", node)
+	w.Line("}")
+	w.Line("})")
+}
+
+// dispatch emits a function calling the method for the type of a node. The
+// method call is formatted with the name of the type
+func (g *parserGen) dispatch(w *writer, name, param, result, call, fallback string) {
+	if result != "" {
+		result = " " + result
+	}
+	units := g.visited()
+	if len(units) == 0 && fallback == "" {
+		w.Line("func %s(%s, node Node) {}", name, param)
+		return
+	}
+
+	w.Line("func %s(%s, node Node)%s {", name, param, result)
+	if len(units) > 0 {
+		w.Line("switch n := node.(type) {")
+		for _, u := range units {
+			w.Line("case *%s:", u.structName)
+			w.Line(call, u.structName)
+		}
+		w.Line("}")
+	}
+	if fallback != "" {
+		w.Line("%s", fallback)
+	}
+	w.Line("}")
+}
+
+// splitMembers splits the code of a members block into the fields of the
+// parser struct and the declarations (typically methods) that follow it. A
+// declaration starts with 'func' at the start of a top level line and takes
+// any comment directly above it along
+func splitMembers(code string) (fields, decls string) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(code))
+
+	var s scanner.Scanner
+	s.Init(file, []byte(code), nil, scanner.ScanComments)
+	fieldParts, declParts := []string{}, []string{}
+	depth, start, line, inDecl := 0, 0, 0, false
+	// Offset of the comments directly above the current line, or -1
+	comments, commentLine := -1, 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		offset, tokLine := file.Offset(pos), file.Line(pos)
+		firstOnLine := tokLine != line
+		if tok == token.SEMICOLON && lit == "\n" {
+			// Inserted at the end of a line
+			continue
+		}
+		line = tokLine
+
+		switch tok {
+		case token.COMMENT:
+			if !firstOnLine {
+				continue
+			}
+			if comments < 0 || tokLine != commentLine+1 {
+				comments = offset
+			}
+			commentLine = tokLine + strings.Count(lit, "\n")
+			line = commentLine
+			continue
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth == 0 && inDecl {
+				declParts = append(declParts, strings.TrimSpace(code[start:offset+1]))
+				start, inDecl = offset+1, false
+			}
+		case token.FUNC:
+			if depth == 0 && !inDecl && firstOnLine {
+				if comments >= 0 && commentLine == tokLine-1 {
+					offset = comments
+				}
+				fieldParts = append(fieldParts, strings.TrimSpace(code[start:offset]))
+				start, inDecl = offset, true
+			}
+		}
+		comments = -1
+	}
+
+	rest := strings.TrimSpace(code[start:])
+	if inDecl {
+		declParts = append(declParts, rest)
+	} else {
+		fieldParts = append(fieldParts, rest)
+	}
+	return joinNonEmpty(fieldParts, "\n"), joinNonEmpty(declParts, "\n\n")
+}
+
+func joinNonEmpty(parts []string, sep string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+// codeIdents returns the set of identifiers used by a block of Go code
+func codeIdents(code string) map[string]bool {
+	idents := make(map[string]bool, 16)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(code))
+
+	var s scanner.Scanner
+	s.Init(file, []byte(code), nil, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.IDENT {
+			idents[lit] = true
+		}
+	}
+	return idents
+}
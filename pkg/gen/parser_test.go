@@ -0,0 +1,482 @@
+package gen_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	"github.com/nu11ptr/parsegen/pkg/gen"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/pgparser"
+	"github.com/nu11ptr/parsegen/pkg/pgtoken"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the generated packages")
+
+func parseGrammar(t *testing.T, grammar string) *ast.TopLevel {
+	lex := runtime.NewLexerFromString(grammar)
+	topLevel, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+	return topLevel
+}
+
+// loadGrammar parses a grammar file and resolves the grammars it imports from
+// the same directory
+func loadGrammar(t *testing.T, filename string) *ast.TopLevel {
+	parse := func(filename string) (*ast.TopLevel, error) {
+		lex, err := runtime.NewLexerFromFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		p := runtime.NewParser(token.New(lex))
+		p.SetFilename(filename)
+		return parser.New(p).Parse()
+	}
+
+	topLevel, err := parse(filename)
+	require.NoError(t, err)
+	err = topLevel.Resolve(func(name string) (*ast.TopLevel, error) {
+		return parse(filepath.Join(filepath.Dir(filename), name+".g4"))
+	})
+	require.NoError(t, err)
+	return topLevel
+}
+
+func parseBody(t *testing.T, filename string) *ast.Body {
+	lex, err := runtime.NewLexerFromFile(filename)
+	require.NoError(t, err)
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+	return body
+}
+
+// The parsers used to read grammars are themselves generated, so generating
+// them again must reproduce them exactly
+func TestGenerateParser(t *testing.T) {
+	tests := []struct {
+		name, grammar, body, output string
+		opts                        gen.Options
+	}{
+		{
+			name: "antlr", grammar: "../../grammars/antlr_parser.g4",
+			body: "../../grammars/antlr.pg", output: "../parser/parser.go",
+			opts: gen.Options{
+				Package:     "parser",
+				TokenImport: "github.com/nu11ptr/parsegen/pkg/token",
+				Imports:     []string{"github.com/nu11ptr/parsegen/pkg/ast"},
+			},
+		},
+		{
+			name: "pg", grammar: "../../grammars/pg_parser.g4",
+			body: "../../grammars/pg.pg", output: "../pgparser/parser.go",
+			opts: gen.Options{
+				Package:     "pgparser",
+				TokenImport: "github.com/nu11ptr/parsegen/pkg/pgtoken",
+				Imports:     []string{"github.com/nu11ptr/parsegen/pkg/ast"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := gen.GenerateParser(
+				loadGrammar(t, test.grammar), parseBody(t, test.body), &test.opts)
+			require.NoError(t, err)
+
+			if *update {
+				require.NoError(t, ioutil.WriteFile(test.output, code, 0644))
+			}
+			expected, err := ioutil.ReadFile(test.output)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(code))
+		})
+	}
+}
+
+// The example packages are generated by parsegen generate, so generating them
+// again must reproduce them exactly
+func TestGenerateExamples(t *testing.T) {
+	tests := []struct {
+		name, grammar, body string
+		opts                gen.Options
+	}{
+		{
+			name: "calc", grammar: "../../examples/calc/calc.g4", body: "../../examples/calc/calc.pg",
+			opts: gen.Options{Package: "calc", Imports: []string{"strconv"}, Combined: true},
+		},
+		{
+			// Without a .pg file, every rule gets a generated result type
+			name: "csv", grammar: "../../examples/csv/csv.g4",
+			opts: gen.Options{Package: "csv", Combined: true, Lossless: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			top := loadGrammar(t, test.grammar)
+			dir := filepath.Dir(test.grammar)
+			body := &ast.Body{Parser: filepath.Base(test.grammar), CodeBlocks: &ast.CodeBlocks{Language: "go"}}
+			if test.body != "" {
+				body = parseBody(t, test.body)
+			}
+
+			tokenizer, err := gen.GenerateTokenizer(top, &test.opts)
+			require.NoError(t, err)
+			parser, err := gen.GenerateParser(top, body, &test.opts)
+			require.NoError(t, err)
+
+			for name, code := range map[string][]byte{"tokenizer.go": tokenizer, "parser.go": parser} {
+				output := filepath.Join(dir, name)
+				if *update {
+					require.NoError(t, ioutil.WriteFile(output, code, 0644))
+				}
+				expected, err := ioutil.ReadFile(output)
+				require.NoError(t, err)
+				assert.Equal(t, string(expected), string(code))
+			}
+		})
+	}
+}
+
+// Labels name the variables of elements and the code blocks of alternatives
+func TestGenerateParserLabels(t *testing.T) {
+	top := parseGrammar(t, "call: name=NAME '(' (args+=arg (',' args+=arg)*)? ')';\n"+
+		"arg: v=NAME # Name | '[' items+=arg* ']' # List;")
+	lex := runtime.NewLexerFromString(`parser = 'x.g4' code('go') {
+		call -> []string {{ return append([]string{name.Data}, args...) }}
+		arg.Name -> *string {{ return &v.Data }}
+		arg.List {{ return items[0] }}
+	}`)
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	// Labeled elements are matched into variables of the same name
+	assert.Contains(t, src, "name := p.p.MatchTokenOrRollback(NAME, oldPos)")
+	assert.Contains(t, src, "if v := p.p.TryMatchToken(NAME); v != nil {")
+
+	// List labels are collected by the sequence and handed up by sub-rules
+	assert.Contains(t, src, "args := []*string{}")
+	assert.Contains(t, src, "args = append(args, arg)")
+	assert.Contains(t, src, "for _, callSub2 := range callSub2s {\n\t\targs = append(args, callSub2.args...)\n\t}")
+	assert.Contains(t, src, "if callSub1 != nil {\n\t\targs = append(args, callSub1.args...)\n\t}")
+	assert.Contains(t, src, "type callSub1 struct {\n\targ       *string\n\tcallSub2s []*callSub2\n\targs      []*string\n}")
+
+	// A labeled alternative gets a sub-rule named after it that runs its code
+	assert.Contains(t, src, "if argList := p.memoParseArgList(); argList != nil {\n\t\treturn argList\n\t}")
+	assert.Contains(t, src, "func (p *Parser) parseArgList() *string {")
+	assert.Contains(t, src, "items = append(items, args...)\n\n\t// ### ']' ###")
+}
+
+// Predicates test for a match without consuming any tokens
+func TestGenerateParserPredicates(t *testing.T) {
+	top := parseGrammar(t, "call: NAME &'(' args | !(NAME ':') args;\nargs: '(' ')';")
+	lex := runtime.NewLexerFromString(`parser = 'x.g4' code('go') {
+		call.alt1 -> *string {{ return &nameTok.Data }}
+		call.alt2 {{ return args }}
+		args -> *string {{ return &lparenTok.Data }}
+	}`)
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	assert.Contains(t, src, "// ### &'(' ###\n"+
+		"\tif !p.p.AndPredicate(func() bool { return p.p.TryMatchToken(LPAREN) != nil }) {\n"+
+		"\t\t// Predicate failed - rollback\n\t\tp.p.SetPos(oldPos)\n\t\treturn nil\n\t}")
+	assert.Contains(t, src, "if !p.p.NotPredicate(func() bool { return p.memoParseCallSub3() != nil }) {")
+
+	// Predicates have no result, so they aren't fields
+	assert.Contains(t, src, "type callSub1 struct {\n\tnameTok *runtime.Token\n\targs    *string\n}")
+	assert.Contains(t, src, "return &callSub1{nameTok: nameTok, args: args}")
+}
+
+// Hooks run on entry and exit, predicates gate rules and alternatives and
+// members are added to the parser
+func TestGenerateParserHooks(t *testing.T) {
+	top := parseGrammar(t, "stmt: NAME NAME # Decl | NAME # Ref | NUM;")
+	lex := runtime.NewLexerFromString(`parser = 'x.g4' code('go') {
+		members {{
+			depth int
+
+			// isType reports whether a name is a type
+			func (p *Parser) isType(name string) bool {
+				return name == "int"
+			}
+			types map[string]func() bool
+		}}
+		stmt.init {{ p.depth++ }}
+		stmt.after {{ p.depth-- }}
+		stmt.pred {{ p.depth < 10 }}
+		stmt.Decl.pred {{ p.isType(p.p.CurrToken().Data) }}
+		stmt.Ref.pred {{ p.depth > 1 }}
+		stmt.Decl -> *string {{ return &nameTok.Data }}
+		stmt.Ref {{ return &nameTok.Data }}
+		stmt.alt3 {{ return &numTok.Data }}
+	}`)
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	// Fields go in the parser, methods after it
+	assert.Contains(t, src, "\tstmtDeclMap map[int]runtime.Memo\n\n\tdepth int\n\ttypes map[string]func() bool\n}")
+	assert.Contains(t, src, "\n\n// isType reports whether a name is a type\nfunc (p *Parser) isType(")
+
+	assert.Contains(t, src, "func (p *Parser) ParseStmt() *string {\n\tp.depth++\n\n"+
+		"\tdefer func() {\n\t\tp.depth--\n\t}()\n\n"+
+		"\tif !(p.depth < 10) {\n\t\t// Predicate failed\n\t\tp.p.FailPredicate()\n\t\treturn nil\n\t}\n")
+
+	// The sub-rule of a labeled alternative tests its own predicate
+	assert.Contains(t, src, "func (p *Parser) parseStmtDecl() *string {\n"+
+		"\tif !(p.isType(p.p.CurrToken().Data)) {")
+	assert.Contains(t, src, "\tif p.depth > 1 {\n\t\tif nameTok := p.p.TryMatchToken(NAME); nameTok != nil {")
+	assert.Contains(t, src, "\t} else {\n\t\t// Predicate failed\n\t\tp.p.FailPredicate()\n\t}\n")
+}
+
+// Rules without action code blocks get generated result types
+func TestGenerateParserAutoTypes(t *testing.T) {
+	top := parseGrammar(t, "stmt: name=NAME '=' value=expr # Assign | 'print' args+=expr+ # Print;\n"+
+		"expr: NUM | '(' expr ')' | NAME?;")
+	lex := runtime.NewLexerFromString("parser = 'x.g4' code('go') { stmt.Assign.pred {{ true }} }")
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	// Labeled alternatives each get a type implementing the interface of the rule
+	assert.Contains(t, src, "type Stmt interface {\n\tNode\n\tisStmt()\n}")
+	assert.Contains(t, src, "func (p *Parser) Parse() (Stmt, error) {")
+	assert.Contains(t, src, "type StmtAssign struct {\n\tName      *runtime.Token\n"+
+		"\tEqualsTok *runtime.Token\n\tValue     *Expr\n}\n\nfunc (*StmtAssign) isStmt() {}")
+	assert.Contains(t, src, "if stmtAssign := p.memoParseStmtAssign(); stmtAssign != nil {\n\t\treturn stmtAssign\n\t}")
+	assert.Contains(t, src, "return &StmtAssign{Name: name, EqualsTok: equalsTok, Value: value}")
+	assert.Contains(t, src, "type StmtPrint struct {\n\tPrintTok *runtime.Token\n\tExprs    []*Expr\n\tArgs     []*Expr\n}")
+
+	// Everything else gets a struct, including sub-rules
+	assert.Contains(t, src, "type Expr struct {\n\tNumTok   *runtime.Token\n\tExprSub1 *ExprSub1\n\tExprSub2 *ExprSub2\n}")
+	assert.Contains(t, src, "// ExprSub1 is the result of a sub-rule of the \"expr\" parser rule\ntype ExprSub1 struct {")
+	assert.Contains(t, src, "return &Expr{ExprSub2: exprSub2}")
+}
+
+// Generated result types get a visitor and a listener
+func TestGenerateParserVisitor(t *testing.T) {
+	top := parseGrammar(t, "stmt: name=NAME '=' value=expr # Assign | 'print' args+=expr+ # Print;\n"+
+		"expr: NUM | '(' expr ')';")
+
+	code, err := gen.GenerateParser(top, &ast.Body{CodeBlocks: &ast.CodeBlocks{Language: "go"}}, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	// Lists only repeat other fields, so they aren't walked twice
+	assert.Contains(t, src, "func (n *StmtPrint) eachChild(fn func(child interface{})) {\n"+
+		"\tif n.PrintTok != nil {\n\t\tfn(n.PrintTok)\n\t}\n\tfor _, child := range n.Exprs {\n\t\tfn(child)\n\t}\n}")
+	assert.Contains(t, src, "type Visitor interface {\n\tVisitStmtAssign(n *StmtAssign) bool\n"+
+		"\tVisitStmtPrint(n *StmtPrint) bool\n\tVisitExpr(n *Expr) bool\n\tVisitToken(tok *runtime.Token)\n}")
+	assert.Contains(t, src, "func (BaseVisitor) VisitExpr(*Expr) bool             { return true }")
+	assert.Contains(t, src, "func Walk(v Visitor, node Node) {")
+	assert.Contains(t, src, "\tEnterExpr(n *Expr)\n\tExitExpr(n *Expr)\n\tVisitToken(tok *runtime.Token)\n}")
+	assert.Contains(t, src, "func WalkListener(l Listener, node Node) {")
+
+	// Unlabeled sub-rules are walked through without methods of their own
+	assert.Contains(t, src, "func (n *ExprSub1) eachChild(")
+	assert.NotContains(t, src, "VisitExprSub1")
+}
+
+// Lossless parsers build a concrete syntax tree from the generated types
+func TestGenerateParserLossless(t *testing.T) {
+	top := parseGrammar(t, "stmt: name=NAME '=' value=expr # Assign | 'print' expr # Print;\nexpr: NUM | '(' expr ')';")
+	body := &ast.Body{CodeBlocks: &ast.CodeBlocks{Language: "go"}}
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x", Lossless: true})
+	require.NoError(t, err)
+	src := string(code)
+
+	assert.Contains(t, src, "func (p *Parser) ParseCST() (*runtime.CSTNode, error) {\n\tstmt, err := p.Parse()\n")
+	assert.Contains(t, src, "func CST(node Node) *runtime.CSTNode {")
+	assert.Contains(t, src, "\tcase *StmtAssign:\n\t\treturn \"stmt.Assign\"\n")
+	assert.Contains(t, src, "\tcase *ExprSub1:\n\t\treturn \"expr.sub1\"\n")
+
+	// Without the option, there is no concrete syntax tree
+	code, err = gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(code), "CST")
+}
+
+// The exported parse function of a left recursive rule, which Parse also
+// uses, must grow the result, not just seed it
+func TestGenerateParserLeftRecursiveParse(t *testing.T) {
+	top := parseGrammar(t, "expr: expr '+' NUM | NUM;")
+	lex := runtime.NewLexerFromString(`parser = 'x.g4' code('go') {
+		expr.alt1 -> *string {{ return expr }}
+		expr.alt2 {{ return &numTok.Data }}
+	}`)
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+	assert.Contains(t, src, "func (p *Parser) Parse() (*string, error) {\n\texpr := p.ParseExpr()\n")
+	assert.Contains(t, src, "func (p *Parser) ParseExpr() *string {\n\treturn p.memoParseExpr()\n}")
+	assert.Contains(t, src, "expr := p.parseExpr()\n\t\treturn expr, expr != nil")
+}
+
+// Loops over something that can match nothing stop once it does
+func TestGenerateParserNullableLoop(t *testing.T) {
+	top := parseGrammar(t, "a: c* d+ EOF;\nc: X?;\nd: X;")
+	body := &ast.Body{CodeBlocks: &ast.CodeBlocks{Language: "go"}}
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	assert.Contains(t, src, "for {\n\t\tloopPos := p.p.Pos()\n\t\tc := p.memoParseC()\n")
+	assert.Contains(t, src, "cs = append(cs, c)\n\t\tif p.p.Pos() == loopPos {\n")
+	assert.Contains(t, src, "for {\n\t\td := p.memoParseD()\n")
+	assert.Equal(t, 1, strings.Count(src, "loopPos :="))
+}
+
+// Rules named like the variables of the generated code itself are renamed
+func TestGenerateParserReservedNames(t *testing.T) {
+	top := parseGrammar(t, "start: p pos po* EOF;\np: NAME;\npos: NAME;\npo: NUM;")
+	lex := runtime.NewLexerFromString(`parser = 'x.g4' code('go') {
+		start -> *string {{ return &p_.Data }}
+		p -> *runtime.Token {{ return nameTok }}
+		pos -> *runtime.Token {{ return nameTok }}
+		po -> *runtime.Token {{ return numTok }}
+	}`)
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+
+	code, err := gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src := string(code)
+
+	assert.Contains(t, src, "p_ := p.memoParseP()")
+	assert.Contains(t, src, "pos_ := p.memoParsePos()")
+	assert.Contains(t, src, "po2s := []*runtime.Token{}")
+	assert.Contains(t, src, "func (p *Parser) memoParsePos() *runtime.Token {\n\tpos := p.p.Pos()\n\tif memo, ok := p.posMap[pos]; ok {")
+
+	// Only variables are renamed, so fields and types keep their names
+	top = parseGrammar(t, "start: pos type (pos | type) EOF;\npos: NAME;\ntype: NAME;")
+	lex = runtime.NewLexerFromString("parser = 'x.g4' code('go') {}")
+	body, err = pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+	require.NoError(t, err)
+	code, err = gen.GenerateParser(top, body, &gen.Options{Package: "x"})
+	require.NoError(t, err)
+	src = string(code)
+
+	assert.Contains(t, src, "\tPos       *Pos\n\tType      *Type\n")
+	assert.Contains(t, src, "type_ := p.memoParseType()")
+	assert.Contains(t, src, "return &Start{Pos: pos_, Type: type_, ")
+	assert.Contains(t, src, "\ttypeMap      map[int]runtime.Memo\n")
+}
+
+func TestGenerateParserErrors(t *testing.T) {
+	tests := []struct {
+		name, grammar, code, err string
+	}{
+		{
+			name: "undefined rule", grammar: "a: b;",
+			code: "a -> *string {{ return nil }}", err: "a: undefined parser rule: b",
+		},
+		{
+			name: "no type", grammar: "a: B;",
+			code: "a {{ return nil }}", err: "no code block with a result type for rule: a",
+		},
+		{
+			name: "unknown block", grammar: "a: B;",
+			code: "a -> *string {{ return nil }} b {{ return nil }}",
+			err:  "code blocks do not match any rule: b",
+		},
+		{
+			name: "conflicting types", grammar: "a: B | C;",
+			code: "a.alt1 -> *string {{ return nil }} a.alt2 -> *int {{ return nil }}",
+			err:  "conflicting result types for a: *string and *int",
+		},
+		{
+			name: "non-nilable type", grammar: "a: B;",
+			code: "a -> string {{ return bTok.Data }}",
+			err:  "result type for a must be a pointer, slice, map, channel, function or interface, since failed parses return nil: string",
+		},
+		{
+			name: "non-nilable alternative type", grammar: "a: B | C;",
+			code: "a.alt1 -> [2]int {{ return nil }} a.alt2 {{ return nil }}",
+			err:  "result type for a must be a pointer, slice, map, channel, function or interface, since failed parses return nil: [2]int",
+		},
+		{
+			name: "reserved label", grammar: "a: B pos=C;",
+			code: "a -> *string {{ return nil }}",
+			err:  "1:6: error: a: label pos is a Go keyword or used by the generated code",
+		},
+		{
+			name: "keyword list label", grammar: "a: type+=B+;",
+			code: "a -> *string {{ return nil }}",
+			err:  "1:4: error: a: label type is a Go keyword or used by the generated code",
+		},
+		{
+			name: "duplicate label", grammar: "a: x=B x=C;",
+			code: "a -> *string {{ return nil }}", err: "a: duplicate label: x",
+		},
+		{
+			name: "numbered and labeled block", grammar: "a: B # One | C;",
+			code: "a.alt1 -> *string {{ return nil }} a.One {{ return nil }}",
+			err:  "code blocks a.alt1 and a.One are for the same alternative",
+		},
+		{
+			name: "typed predicate", grammar: "a: B;",
+			code: "a -> *string {{ return nil }} a.pred -> bool {{ true }}",
+			err:  "only action code blocks have a result type: a.pred",
+		},
+		{
+			name: "unknown hook", grammar: "a: B | C;",
+			code: "a -> *string {{ return nil }} a.alt1.init {{ }}",
+			err:  "code blocks do not match any rule: a.alt1.init",
+		},
+		{
+			name: "type conflict", grammar: "a: B;\nA: 'a';\nB: 'b';",
+			err: "generated type A for rule a conflicts with token A",
+		},
+		{
+			name: "sub-rule type conflict", grammar: "a: (B | C)+;\na_sub1: B;",
+			err: "generated type ASub1 for sub-rule a.sub1 conflicts with rule a_sub1",
+		},
+		{
+			name:    "no left recursion leader",
+			grammar: "a: b 'x' | c 'y';\nb: a 'z' | c 'w';\nc: a 'q' | b 'r';",
+			code:    "a -> *string {{ return nil }} b -> *string {{ return nil }} c -> *string {{ return nil }}",
+			err: "left recursion without a rule common to all cycles: " +
+				"a, a.sub1, a.sub2, b, b.sub1, b.sub2, c, c.sub1, c.sub2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := runtime.NewLexerFromString("parser = 'x.g4' code('go') {" + test.code + "}")
+			body, err := pgparser.New(runtime.NewParser(pgtoken.New(lex))).Parse()
+			require.NoError(t, err)
+
+			_, err = gen.GenerateParser(parseGrammar(t, test.grammar), body,
+				&gen.Options{Package: "x"})
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package interp parses input with the rules of a grammar directly, without
+// generating any code. Tokenizing and parsing follow the same rules as
+// generated tokenizers and parsers, so a grammar can be tried out before any
+// code is generated for it
+package interp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// defaultMode is the name of the mode lexer rules are in unless declared
+// otherwise
+const defaultMode = "DEFAULT_MODE"
+
+// Interpreter tokenizes and parses input with the lexer and parser rules of a
+// grammar. It holds no state of its own while parsing, so it can be used for
+// any number of parses
+type Interpreter struct {
+	top *ast.TopLevel
+
+	// Lexer rules that produce tokens in order of priority, including the
+	// implicit ones for literals used by parser rules without a lexer rule
+	tokens     []*lexToken
+	lexerRules map[string]*ast.LexerRule
+	// Token types by lexer rule name and by the quoted literal parser rules
+	// match them by
+	types    map[string]runtime.TokenType
+	literals map[string]runtime.TokenType
+	names    map[runtime.TokenType]string
+	keywords map[string]runtime.TokenType
+	modes    map[string]runtime.Mode
+	channels map[string]runtime.Channel
+
+	// matcher matches the lexer rules against the input of a tokenizer
+	matcher *ast.LexerMatcher
+
+	rules map[string]*rule
+}
+
+// New creates an interpreter for a grammar. Any imports of the grammar must
+// already be resolved. It returns an error if the grammar has problems that
+// keep it from being interpreted (the same ones that keep code from being
+// generated for it)
+func New(top *ast.TopLevel) (*Interpreter, error) {
+	i := &Interpreter{
+		top:        top,
+		lexerRules: make(map[string]*ast.LexerRule, len(top.LexerRules)),
+		types:      make(map[string]runtime.TokenType, len(top.LexerRules)),
+		literals:   make(map[string]runtime.TokenType, 16),
+		names:      make(map[runtime.TokenType]string, len(top.LexerRules)),
+		keywords:   make(map[string]runtime.TokenType, 16),
+		modes:      map[string]runtime.Mode{defaultMode: runtime.DefaultMode},
+		channels: map[string]runtime.Channel{
+			"DEFAULT_TOKEN_CHANNEL": runtime.DefaultChannel, "HIDDEN": runtime.HiddenChannel,
+		},
+		rules: make(map[string]*rule, len(top.ParserRules)),
+	}
+	i.matcher = ast.NewLexerMatcher(i.lexerRules)
+	if err := i.analyzeLexer(); err != nil {
+		return nil, err
+	}
+	if err := i.analyzeParser(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// TokenName returns the name of a token type used in syntax errors. Literal
+// tokens are named by their quoted literal
+func (i *Interpreter) TokenName(tt runtime.TokenType) string {
+	switch tt {
+	case runtime.ILLEGAL:
+		return "ILLEGAL"
+	case runtime.EOF:
+		return "EOF"
+	case runtime.BOM:
+		return "BOM"
+	}
+	return i.names[tt]
+}
+
+// RuleNames returns the names of the parser rules in grammar order. The first
+// one is the start rule
+func (i *Interpreter) RuleNames() []string {
+	names := make([]string, len(i.top.ParserRules))
+	for n, rule := range i.top.ParserRules {
+		names[n] = rule.Name
+	}
+	return names
+}
+
+// Parse parses the input of a lexer starting from the given parser rule, or
+// the first one if the name is empty. The parse tree has a node for each rule
+// matched, named after the rule (and the label of the alternative matched, ex:
+// "expr.Binary"), with the tokens it matched and the nodes of the rules it
+// called in input order. Like generated parsers, the rule doesn't have to
+// match all of the input. Skipped tokens are kept as trivia, so the tree
+// covers all of the input matched. Trivia at the end of the input belongs to
+// the EOF token, which is added to the tree when the rule doesn't match EOF
+// itself
+func (i *Interpreter) Parse(name string, lex *runtime.Lexer) (*runtime.CSTNode, error) {
+	return i.parse(name, lex, "")
+}
+
+// ParseString parses a string like Parse
+func (i *Interpreter) ParseString(name, input string) (*runtime.CSTNode, error) {
+	return i.parse(name, runtime.NewLexerFromString(input), "")
+}
+
+// ParseFile parses the contents of a file like Parse. Syntax errors are
+// reported against the file
+func (i *Interpreter) ParseFile(name, filename string) (*runtime.CSTNode, error) {
+	lex, err := runtime.NewLexerFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return i.parse(name, lex, filename)
+}
+
+func (i *Interpreter) parse(name string, lex *runtime.Lexer, filename string) (*runtime.CSTNode, error) {
+	if len(i.top.ParserRules) == 0 {
+		return nil, errors.New("grammar has no parser rules")
+	}
+	if name == "" {
+		name = i.top.ParserRules[0].Name
+	}
+	start, ok := i.rules[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined parser rule: %s", name)
+	}
+
+	p := &parser{
+		in: i, p: runtime.NewParser(i.NewTokenizer(lex)),
+		memos: make(map[*rule]map[int]runtime.Memo, len(i.rules)),
+	}
+	p.p.SetFilename(filename)
+	tree := p.call(start)
+	if tree == nil {
+		return nil, p.p.Err()
+	}
+	if tok := p.p.CurrToken(); tok.Type == runtime.EOF && len(tok.Trivia) > 0 {
+		tree.Children = append(tree.Children, &runtime.CSTNode{Token: tok})
+	}
+	return tree, nil
+}
+
+// ruleError prefixes an error with the name of the rule it was found in
+func ruleError(name string, err error) error {
+	return fmt.Errorf("%s: %w", name, err)
+}
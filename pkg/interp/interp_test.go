@@ -0,0 +1,173 @@
+package interp_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/parsegen/pkg/interp"
+	"github.com/nu11ptr/parsegen/pkg/parser"
+	"github.com/nu11ptr/parsegen/pkg/token"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInterpreter(t *testing.T, grammar string) *interp.Interpreter {
+	lex := runtime.NewLexerFromString(grammar)
+	topLevel, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+	i, err := interp.New(topLevel)
+	require.NoError(t, err)
+	return i
+}
+
+func newError(t *testing.T, grammar string) error {
+	lex := runtime.NewLexerFromString(grammar)
+	topLevel, err := parser.New(runtime.NewParser(token.New(lex))).Parse()
+	require.NoError(t, err)
+	_, err = interp.New(topLevel)
+	require.Error(t, err)
+	return err
+}
+
+const exprGrammar = `grammar Expr;
+
+stmts: stmt* EOF;
+stmt: 'print' expr ';' # Print
+    | IDENT '=' expr ';' # Assign
+    ;
+expr: expr ('*' | '/') expr # Mul
+    | expr ('+' | '-') expr # Add
+    | '(' expr ')' # Paren
+    | IDENT # Ident
+    | NUMBER # Number
+    ;
+
+PRINT: 'print';
+IDENT: [a-z]+;
+NUMBER: [0-9]+;
+COMMENT: '/*' .*? '*/' -> channel(HIDDEN);
+WS: [ \t\n]+ -> skip;
+`
+
+func TestParse(t *testing.T) {
+	i := newInterpreter(t, exprGrammar)
+	input := "x = 1 + 2 * y; /* done */\nprint (x - 3);\n"
+	tree, err := i.ParseString("", input)
+	require.NoError(t, err)
+	assert.Equal(t, input, tree.Text())
+
+	stmt := tree.Children[1]
+	assert.Equal(t, "stmt.Print", stmt.Name)
+	assert.Equal(t, "'print'", i.TokenName(stmt.Children[0].Token.Type))
+	assert.Equal(t, "/* done */", stmt.Children[0].Token.ChannelTrivia(runtime.HiddenChannel)[0].Data)
+
+	tree, err = i.ParseString("expr", "1 * 2 + y")
+	require.NoError(t, err)
+	assert.Equal(t, `expr.Mul:
+   └──expr.Number:
+      └──"1"
+   └──"*"
+   └──expr.Add:
+      └──expr.Number:
+         └──"2"
+      └──"+"
+      └──expr.Ident:
+         └──"y"
+`, tree.String())
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	i := newInterpreter(t, exprGrammar)
+	_, err := i.ParseString("stmt", "x = 1 +;")
+	require.Error(t, err)
+	assert.Equal(t, "1:8: expected '(', IDENT or NUMBER, found ';'", err.Error())
+
+	_, err = i.ParseString("missing", "x = 1;")
+	require.Error(t, err)
+	assert.Equal(t, "undefined parser rule: missing", err.Error())
+
+	// A rule that only fails on a not-predicate still reports why
+	i = newInterpreter(t, "grammar Not;\n\nstart: !ID ID EOF;\n\nID: [a-z]+;\n")
+	tree, err := i.ParseString("", "abc")
+	assert.Nil(t, tree)
+	require.Error(t, err)
+	assert.Equal(t, `1:1: not-predicate failed at ID "abc"`, err.Error())
+}
+
+func TestParseModes(t *testing.T) {
+	i := newInterpreter(t, `grammar Str;
+
+strs: str+;
+str: QUOTE (CHARS | ESC)* END;
+
+QUOTE: '"' -> pushMode(STRING);
+WS: ' '+ -> skip;
+
+mode STRING;
+CHARS: ~["\\]+;
+ESC: '\\' .;
+END: '"' -> popMode;
+`)
+	input := `"a\"b" ""`
+	tree, err := i.ParseString("", input)
+	require.NoError(t, err)
+	assert.Equal(t, input, tree.Text())
+	assert.Len(t, tree.Children, 2)
+	assert.Equal(t, []string{"\"", "a", "\\\"", "b", "\""}, tokenData(tree.Children[0]))
+}
+
+// Popping the mode with no mode to return to makes the token illegal
+func TestTokenizerModeUnderflow(t *testing.T) {
+	i := newInterpreter(t, "grammar Pop;\n\nstart: CLOSE* EOF;\n\n"+
+		"CLOSE: ')' -> channel(HIDDEN), popMode;\nEND: ';' -> skip, popMode;\n")
+	lex := runtime.NewLexerFromString(");")
+	tokenizer := i.NewTokenizer(lex)
+
+	var tok runtime.Token
+	for _, data := range []string{")", ";"} {
+		tokenizer.NextToken(&tok)
+		assert.Equal(t, runtime.ILLEGAL, tok.Type)
+		assert.Equal(t, runtime.DefaultChannel, tok.Channel)
+		assert.Equal(t, data, tok.Data)
+		require.NotNil(t, tok.Err)
+		assert.Equal(t, runtime.ModeStackUnderflow, tok.Err.Kind)
+	}
+	require.Len(t, lex.Errors(), 2)
+	assert.Equal(t, `1:2: mode stack underflow ";"`, lex.Errors()[1].Error())
+}
+
+// A non-greedy element in a fragment stops at what follows the fragment
+func TestTokenizerNonGreedyFragment(t *testing.T) {
+	i := newInterpreter(t, "grammar Tag;\n\nstart: TAG+ EOF;\n\n"+
+		"TAG: '<' BODY '>';\nWS: ' '+ -> skip;\nfragment BODY: .*?;\n")
+	tree, err := i.ParseString("", "<abc> <> <a b>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<abc>", "<>", "<a b>", ""}, tokenData(tree))
+}
+
+func tokenData(node *runtime.CSTNode) []string {
+	var data []string
+	for _, tok := range node.Tokens() {
+		data = append(data, tok.Data)
+	}
+	return data
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name, grammar, err string
+	}{
+		{"undefined rule", "grammar A;\na: b;\nB: 'b';\n", "a: undefined parser rule: b"},
+		{"undefined token", "grammar A;\na: B;\nC: 'c';\n", "a: undefined token: B"},
+		{"implicit token conflict", "grammar A;\na: 'end';\nEND: 'e' 'nd';\n",
+			"a: implicit token for literal 'end' conflicts with lexer rule: END"},
+		{"no leader", "grammar A;\na: b 'x' | c 'y' | 'z';\nb: c 'u' | a 'v';\nc: a 'w' | b 'q';\n",
+			"left recursion without a rule common to all cycles: a, b, c"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.err, newError(t, test.grammar).Error())
+		})
+	}
+}
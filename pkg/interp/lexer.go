@@ -0,0 +1,250 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// lexToken is a lexer rule that produces tokens (or is skipped)
+type lexToken struct {
+	rule    *ast.LexerRule
+	tt      runtime.TokenType
+	mode    runtime.Mode
+	literal string // Unquoted literal for literal only rules
+	keyword bool   // Literal matched by another rule - found via keyword map
+	hasKeys bool   // Rule matches at least one keyword
+}
+
+// *** Analysis ***
+
+func (i *Interpreter) analyzeLexer() error {
+	for n, mode := range i.top.Modes {
+		if _, ok := i.modes[mode]; ok {
+			return fmt.Errorf("duplicate mode: %s", mode)
+		}
+		i.modes[mode] = runtime.Mode(n + 1)
+	}
+	for _, rule := range i.top.LexerRules {
+		if _, ok := i.lexerRules[rule.Name]; ok {
+			return fmt.Errorf("duplicate lexer rule: %s", rule.Name)
+		}
+		i.lexerRules[rule.Name] = rule
+	}
+
+	for _, rule := range i.top.LexerRules {
+		if err := i.analyzeLexerRule(rule); err != nil {
+			return ruleError(rule.Name, err)
+		}
+		if rule.Fragment {
+			continue
+		}
+		tok, err := i.newToken(rule)
+		if err != nil {
+			return ruleError(rule.Name, err)
+		}
+		if lit, ok := rule.Literal(); ok {
+			tok.literal, _ = ast.Unquote(lit)
+			if _, ok := i.literals[lit]; !ok {
+				i.literals[lit] = tok.tt
+			}
+			i.names[tok.tt] = lit
+		}
+	}
+
+	if err := i.implicitTokens(); err != nil {
+		return err
+	}
+	if len(i.tokens) == 0 {
+		return errors.New("grammar has no lexer rules")
+	}
+	i.findKeywords()
+	return nil
+}
+
+// newToken adds the token of a rule
+func (i *Interpreter) newToken(rule *ast.LexerRule) (*lexToken, error) {
+	mode := defaultMode
+	if rule.Mode != "" {
+		mode = rule.Mode
+	}
+	m, ok := i.modes[mode]
+	if !ok {
+		return nil, fmt.Errorf("undefined mode: %s", mode)
+	}
+
+	tok := &lexToken{rule: rule, tt: runtime.EOF + 1 + runtime.TokenType(len(i.tokens)), mode: m}
+	i.tokens = append(i.tokens, tok)
+	i.types[rule.Name] = tok.tt
+	i.names[tok.tt] = rule.Name
+	return tok, nil
+}
+
+// analyzeLexerRule checks the nodes and actions of a rule, adding it to the
+// matcher
+func (i *Interpreter) analyzeLexerRule(rule *ast.LexerRule) error {
+	if err := i.matcher.AddRule(rule); err != nil {
+		return err
+	}
+
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case ast.PushModeAction:
+			if _, ok := i.modes[action.Mode]; !ok {
+				return fmt.Errorf("undefined mode: %s", action.Mode)
+			}
+		case ast.ChannelAction:
+			if rule.HasAction(ast.SkipAction) {
+				return errors.New("skipped and fragment rules can not have a channel")
+			}
+			if _, ok := i.channels[action.Channel]; !ok {
+				i.channels[action.Channel] = runtime.HiddenChannel + runtime.Channel(len(i.channels)-1)
+			}
+		}
+	}
+	return nil
+}
+
+// implicitTokens adds tokens for literals used by parser rules that aren't
+// matched by a lexer rule of their own. See ast.TopLevel.ImplicitTokens
+func (i *Interpreter) implicitTokens() error {
+	rules, err := i.top.ImplicitTokens()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := i.analyzeLexerRule(rule); err != nil {
+			return ruleError(rule.Name, err)
+		}
+		tok, err := i.newToken(rule)
+		if err != nil {
+			return ruleError(rule.Name, err)
+		}
+		lit, _ := rule.Literal()
+		tok.literal, _ = ast.Unquote(lit)
+		i.literals[lit] = tok.tt
+		i.names[tok.tt] = lit
+	}
+	return nil
+}
+
+// findKeywords finds the literal tokens looked up in the keyword map instead
+// of being matched. See ast.LexerMatcher.Keywords
+func (i *Interpreter) findKeywords() {
+	rules := make([]*ast.LexerRule, len(i.tokens))
+	for n, tok := range i.tokens {
+		rules[n] = tok.rule
+	}
+	keywords, hasKeys := i.matcher.Keywords(rules)
+	for _, tok := range i.tokens {
+		tok.keyword, tok.hasKeys = keywords[tok.rule], hasKeys[tok.rule]
+		if tok.keyword {
+			i.keywords[tok.literal] = tok.tt
+		}
+	}
+}
+
+// *** Tokenizer ***
+
+// Tokenizer splits its input into tokens by taking the longest match of any
+// lexer rule of the current mode at each position. Unlike generated
+// tokenizers, skipped tokens (and any byte order mark at the start of the
+// input) are always kept as trivia and every token has its text as data
+type Tokenizer struct {
+	in       *Interpreter
+	lex      *runtime.Lexer
+	tokens   [][]*lexToken
+	matchers [][]func() bool
+}
+
+// NewTokenizer creates a new tokenizer that reads characters from the given
+// lexer
+func (i *Interpreter) NewTokenizer(lex *runtime.Lexer) *Tokenizer {
+	lex.KeepBOM()
+	t := &Tokenizer{
+		in: i, lex: lex,
+		tokens: make([][]*lexToken, len(i.modes)), matchers: make([][]func() bool, len(i.modes)),
+	}
+	for _, tok := range i.tokens {
+		if tok.keyword {
+			continue
+		}
+		rule := tok.rule
+		t.tokens[tok.mode] = append(t.tokens[tok.mode], tok)
+		t.matchers[tok.mode] = append(t.matchers[tok.mode], func() bool {
+			return i.matcher.Match(lex, rule)
+		})
+	}
+	return t
+}
+
+// TokenName returns the name of a token type for use in syntax errors
+func (t *Tokenizer) TokenName(tt runtime.TokenType) string {
+	return t.in.TokenName(tt)
+}
+
+// NextToken matches the next token in the input, keeping any skipped as trivia
+func (t *Tokenizer) NextToken(tok *runtime.Token) {
+	lex := t.lex
+	for {
+		mode := lex.CurrentMode()
+		if int(mode) >= len(t.tokens) {
+			mode = runtime.DefaultMode
+		}
+
+		match := lex.LongestMatch(t.matchers[mode])
+		if match < 0 {
+			if lex.CurrChar() == runtime.EOFChar {
+				lex.BuildToken(runtime.EOF, tok)
+			} else {
+				lex.BuildIllegalToken(tok)
+			}
+			return
+		}
+
+		lt := t.tokens[mode][match]
+		if lt.rule.HasAction(ast.SkipAction) {
+			if !t.modeActions(lt) {
+				// No mode to return to, so the token is not valid here
+				lex.BuildToken(runtime.ILLEGAL, tok)
+				lex.MakeIllegal(tok, runtime.ModeStackUnderflow)
+				return
+			}
+			lex.BuildTrivia(lt.tt)
+			continue
+		}
+
+		lex.BuildTokenData(lt.tt, tok)
+		if tt, ok := t.in.keywords[tok.Data]; ok && lt.hasKeys {
+			tok.Type = tt
+		}
+		for _, action := range lt.rule.Actions {
+			if action.Type == ast.ChannelAction {
+				tok.Channel = t.in.channels[action.Channel]
+			}
+		}
+		if !t.modeActions(lt) {
+			// No mode to return to, so the token is not valid here
+			lex.MakeIllegal(tok, runtime.ModeStackUnderflow)
+		}
+		return
+	}
+}
+
+// modeActions makes the mode changes of a token. It returns false if a mode
+// couldn't be popped
+func (t *Tokenizer) modeActions(tok *lexToken) bool {
+	for _, action := range tok.rule.Actions {
+		switch action.Type {
+		case ast.PushModeAction:
+			t.lex.PushMode(t.in.modes[action.Mode])
+		case ast.PopModeAction:
+			if t.lex.PopMode() != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+package interp
+
+import (
+	"fmt"
+
+	"github.com/nu11ptr/parsegen/pkg/ast"
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
+
+// rule is a parser rule along with how it is memoized
+type rule struct {
+	*ast.ParserRule
+
+	// Set for left recursive rules. See analyzeLeftRecursion
+	leader     bool
+	unmemoized bool
+}
+
+// name returns the name of the parse tree node for an alternative of the rule
+func (r *rule) name(alt int) string {
+	if labels := r.Rules.Labels; alt < len(labels) && labels[alt] != nil {
+		return r.Name + "." + labels[alt].Name
+	}
+	return r.Name
+}
+
+// *** Analysis ***
+
+func (i *Interpreter) analyzeParser() error {
+	for _, pr := range i.top.ParserRules {
+		if _, ok := i.rules[pr.Name]; ok {
+			return fmt.Errorf("duplicate parser rule: %s", pr.Name)
+		}
+		i.rules[pr.Name] = &rule{ParserRule: pr}
+	}
+
+	for _, pr := range i.top.ParserRules {
+		var err error
+		ast.WalkParserNode(pr.Rules, func(node ast.ParserNode) bool {
+			switch n := node.(type) {
+			case *ast.ParserRuleRef:
+				if _, ok := i.rules[n.Name]; !ok {
+					err = fmt.Errorf("undefined parser rule: %s", n.Name)
+				}
+			case *ast.ParserLexerRuleRef:
+				if _, ok := i.types[n.Name]; !ok && n.Name != "EOF" {
+					err = fmt.Errorf("undefined token: %s", n.Name)
+				}
+			}
+			return err == nil
+		})
+		if err != nil {
+			return ruleError(pr.Name, err)
+		}
+	}
+	return i.analyzeLeftRecursion()
+}
+
+// analyzeLeftRecursion finds the leader of each left recursive group of rules.
+// See ast.LeftRecursion
+func (i *Interpreter) analyzeLeftRecursion() error {
+	graph := &ruleGraph{in: i, rules: make([]*rule, len(i.top.ParserRules))}
+	graph.index = make(map[string]int, len(graph.rules))
+	for n, pr := range i.top.ParserRules {
+		graph.rules[n], graph.index[pr.Name] = i.rules[pr.Name], n
+	}
+
+	groups, err := ast.LeftRecursion(graph)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		for _, n := range group.Members {
+			graph.rules[n].unmemoized = n != group.Leader
+		}
+		graph.rules[group.Leader].leader = true
+	}
+	return nil
+}
+
+// ruleGraph is the call graph of the parser rules of a grammar
+type ruleGraph struct {
+	in    *Interpreter
+	rules []*rule
+	index map[string]int
+}
+
+func (g *ruleGraph) Len() int {
+	return len(g.rules)
+}
+
+func (g *ruleGraph) Name(fn int) string {
+	return g.rules[fn].Name
+}
+
+func (g *ruleGraph) Nullable(fn int, nullable []bool) bool {
+	return g.isNullable(g.rules[fn].Rules, nullable)
+}
+
+// Predicates can fail, but never match any tokens, so what follows them is
+// still matched at the same position
+func (g *ruleGraph) isNullable(node ast.ParserNode, nullable []bool) bool {
+	switch n := node.(type) {
+	case *ast.ParserAlternatives:
+		for _, alt := range n.Rules {
+			if g.nullableSeq(alt, nullable) {
+				return true
+			}
+		}
+		return false
+	case *ast.ParserZeroOrMore, *ast.ParserZeroOrOne, *ast.ParserAndPredicate, *ast.ParserNotPredicate:
+		return true
+	case *ast.ParserOneOrMore:
+		return g.isNullable(n.Node, nullable)
+	case *ast.ParserLabel:
+		return g.isNullable(n.Node, nullable)
+	case *ast.ParserRuleRef:
+		return nullable[g.index[n.Name]]
+	default:
+		return false
+	}
+}
+
+func (g *ruleGraph) nullableSeq(seq []ast.ParserNode, nullable []bool) bool {
+	for _, node := range seq {
+		if !g.isNullable(node, nullable) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *ruleGraph) LeftCalls(fn int, nullable []bool) []int {
+	return g.leftCallees(g.rules[fn].Rules, nullable, []int{})
+}
+
+// leftCallees adds the rules a node can call before matching any tokens
+func (g *ruleGraph) leftCallees(node ast.ParserNode, nullable []bool, callees []int) []int {
+	switch n := node.(type) {
+	case *ast.ParserAlternatives:
+		for _, alt := range n.Rules {
+			for _, node := range alt {
+				callees = g.leftCallees(node, nullable, callees)
+				if !g.isNullable(node, nullable) {
+					break
+				}
+			}
+		}
+	case *ast.ParserZeroOrMore:
+		callees = g.leftCallees(n.Node, nullable, callees)
+	case *ast.ParserOneOrMore:
+		callees = g.leftCallees(n.Node, nullable, callees)
+	case *ast.ParserZeroOrOne:
+		callees = g.leftCallees(n.Node, nullable, callees)
+	case *ast.ParserLabel:
+		callees = g.leftCallees(n.Node, nullable, callees)
+	case *ast.ParserAndPredicate:
+		callees = g.leftCallees(n.Node, nullable, callees)
+	case *ast.ParserNotPredicate:
+		callees = g.leftCallees(n.Node, nullable, callees)
+	case *ast.ParserRuleRef:
+		callees = append(callees, g.index[n.Name])
+	}
+	return callees
+}
+
+// Preferred treats all rules alike, so the first suitable rule leads
+func (g *ruleGraph) Preferred(fn int) bool {
+	return true
+}
+
+// *** Parsing ***
+
+// parser holds the state of a single parse
+type parser struct {
+	in    *Interpreter
+	p     *runtime.Parser
+	memos map[*rule]map[int]runtime.Memo
+}
+
+// call parses a rule at the current position, memoizing the result
+func (p *parser) call(r *rule) *runtime.CSTNode {
+	memos, ok := p.memos[r]
+	if !ok {
+		memos = make(map[int]runtime.Memo, 8)
+		p.memos[r] = memos
+	}
+
+	switch {
+	case r.leader:
+		result := p.p.GrowSeed(memos, func() (interface{}, bool) {
+			node := p.rule(r)
+			return node, node != nil
+		})
+		node, _ := result.(*runtime.CSTNode)
+		return node
+	case r.unmemoized:
+		return p.rule(r)
+	}
+
+	pos := p.p.Pos()
+	if memo, ok := memos[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		node, _ := memo.Result.(*runtime.CSTNode)
+		return node
+	}
+	node := p.rule(r)
+	// Memoize what we did here in case this exact rule/position is needed again
+	memos[pos] = runtime.Memo{Result: node, EndPos: p.p.Pos()}
+	return node
+}
+
+// rule parses the first alternative of a rule that matches
+func (p *parser) rule(r *rule) *runtime.CSTNode {
+	for alt, seq := range r.Rules.Rules {
+		children := []*runtime.CSTNode{}
+		if p.seq(seq, &children) {
+			return &runtime.CSTNode{Name: r.name(alt), Children: children}
+		}
+	}
+	return nil
+}
+
+// seq matches a sequence, adding what it matched to children. Nothing is
+// matched or added when it fails
+func (p *parser) seq(seq []ast.ParserNode, children *[]*runtime.CSTNode) bool {
+	pos, count := p.p.Pos(), len(*children)
+	for _, node := range seq {
+		if !p.node(node, children) {
+			// Failed - rollback
+			p.p.SetPos(pos)
+			*children = (*children)[:count]
+			return false
+		}
+	}
+	return true
+}
+
+// node matches a node, adding what it matched to children. Nothing is matched
+// or added when it fails
+func (p *parser) node(node ast.ParserNode, children *[]*runtime.CSTNode) bool {
+	switch n := node.(type) {
+	case *ast.ParserAlternatives:
+		for _, alt := range n.Rules {
+			if p.seq(alt, children) {
+				return true
+			}
+		}
+		return false
+	case *ast.ParserZeroOrOne:
+		p.node(n.Node, children)
+		return true
+	case *ast.ParserZeroOrMore:
+		p.loop(n.Node, children)
+		return true
+	case *ast.ParserOneOrMore:
+		if !p.node(n.Node, children) {
+			return false
+		}
+		p.loop(n.Node, children)
+		return true
+	case *ast.ParserLabel:
+		return p.node(n.Node, children)
+	case *ast.ParserAndPredicate:
+		return p.p.AndPredicate(func() bool {
+			return p.node(n.Node, &[]*runtime.CSTNode{})
+		})
+	case *ast.ParserNotPredicate:
+		return p.p.NotPredicate(func() bool {
+			return p.node(n.Node, &[]*runtime.CSTNode{})
+		})
+	case *ast.ParserRuleRef:
+		child := p.call(p.in.rules[n.Name])
+		if child == nil {
+			return false
+		}
+		*children = append(*children, child)
+		return true
+	case *ast.ParserLexerRuleRef:
+		tt := runtime.EOF
+		if n.Name != "EOF" {
+			tt = p.in.types[n.Name]
+		}
+		return p.token(tt, children)
+	case *ast.ParserToken:
+		return p.token(p.in.literals[n.Token.Data], children)
+	default:
+		return false
+	}
+}
+
+// loop matches a node as many times as possible. As in generated parsers, a
+// match that consumes nothing is kept, but is the last one, as it would match
+// again forever
+func (p *parser) loop(node ast.ParserNode, children *[]*runtime.CSTNode) {
+	for {
+		start := p.p.Pos()
+		if !p.node(node, children) || p.p.Pos() == start {
+			return
+		}
+	}
+}
+
+func (p *parser) token(tt runtime.TokenType, children *[]*runtime.CSTNode) bool {
+	tok := p.p.TryMatchToken(tt)
+	if tok == nil {
+		return false
+	}
+	*children = append(*children, &runtime.CSTNode{Token: tok})
+	return true
+}
@@ -1,3 +1,5 @@
+// Code generated by parsegen from antlr_parser.g4. DO NOT EDIT.
+
 package parser
 
 import (
@@ -6,46 +8,129 @@ import (
 	runtime "github.com/nu11ptr/parsegen/runtime/go"
 )
 
-// *** Below will be automatically generated
-
+// Parser is a packrat parser that memoizes the result of each rule
 type Parser struct {
 	p *runtime.Parser
 
-	topLevelMap     map[int]*ast.TopLevel
-	parseRuleMap    map[int]*ast.ParserRule
-	ruleBodyMap     map[int]*ast.ParserAlternatives
-	ruleBodySub1Map map[int]*ruleBodySub1
-	ruleSectMap     map[int]ast.ParserNode
-	rulePartMap     map[int]ast.ParserNode
-	rulePartSub1Map map[int]*rulePartSub1
-	suffixMap       map[int]*runtime.Token
+	topLevelMap          map[int]runtime.Memo
+	topLevelSub1Map      map[int]runtime.Memo
+	topLevelSub2Map      map[int]runtime.Memo
+	grammarDeclMap       map[int]runtime.Memo
+	grammarTypeMap       map[int]runtime.Memo
+	optionsSpecMap       map[int]runtime.Memo
+	optionMap            map[int]runtime.Memo
+	optionValueMap       map[int]runtime.Memo
+	optionValueSub1Map   map[int]runtime.Memo
+	optionValueSub2Map   map[int]runtime.Memo
+	importsMap           map[int]runtime.Memo
+	importsSub1Map       map[int]runtime.Memo
+	identMap             map[int]runtime.Memo
+	parseRuleMap         map[int]runtime.Memo
+	ruleBodyMap          map[int]runtime.Memo
+	ruleBodySub1Map      map[int]runtime.Memo
+	altLabelMap          map[int]runtime.Memo
+	ruleSectMap          map[int]runtime.Memo
+	ruleSectPredicateMap map[int]runtime.Memo
+	ruleSectElementMap   map[int]runtime.Memo
+	predicateMap         map[int]runtime.Memo
+	elementLabelMap      map[int]runtime.Memo
+	elementLabelSub1Map  map[int]runtime.Memo
+	rulePartMap          map[int]runtime.Memo
+	rulePartSub1Map      map[int]runtime.Memo
+	suffixMap            map[int]runtime.Memo
+	lexRuleMap           map[int]runtime.Memo
+	lexRuleSub1Map       map[int]runtime.Memo
+	lexModeMap           map[int]runtime.Memo
+	lexActionsMap        map[int]runtime.Memo
+	lexActionsSub1Map    map[int]runtime.Memo
+	lexActionMap         map[int]runtime.Memo
+	lexActionSub1Map     map[int]runtime.Memo
+	lexActionSub2Map     map[int]runtime.Memo
+	lexRuleBodyMap       map[int]runtime.Memo
+	lexRuleBodySub1Map   map[int]runtime.Memo
+	lexRuleSectMap       map[int]runtime.Memo
+	lexRuleSectSub1Map   map[int]runtime.Memo
+	lexRulePartMap       map[int]runtime.Memo
+	lexRulePartSub1Map   map[int]runtime.Memo
+	charSetMap           map[int]runtime.Memo
+	charSetSub1Map       map[int]runtime.Memo
+	charLitMap           map[int]runtime.Memo
+	charRangeMap         map[int]runtime.Memo
 }
 
+// New creates a new parser that reads tokens from the given runtime parser
 func New(p *runtime.Parser) *Parser {
 	return &Parser{
-		p:               p,
-		topLevelMap:     make(map[int]*ast.TopLevel, 8),
-		parseRuleMap:    make(map[int]*ast.ParserRule, 8),
-		ruleBodyMap:     make(map[int]*ast.ParserAlternatives, 8),
-		ruleBodySub1Map: make(map[int]*ruleBodySub1, 8),
-		ruleSectMap:     make(map[int]ast.ParserNode, 8),
-		rulePartMap:     make(map[int]ast.ParserNode, 8),
-		rulePartSub1Map: make(map[int]*rulePartSub1, 8),
-		suffixMap:       make(map[int]*runtime.Token, 8),
+		p:                    p,
+		topLevelMap:          make(map[int]runtime.Memo, 8),
+		topLevelSub1Map:      make(map[int]runtime.Memo, 8),
+		topLevelSub2Map:      make(map[int]runtime.Memo, 8),
+		grammarDeclMap:       make(map[int]runtime.Memo, 8),
+		grammarTypeMap:       make(map[int]runtime.Memo, 8),
+		optionsSpecMap:       make(map[int]runtime.Memo, 8),
+		optionMap:            make(map[int]runtime.Memo, 8),
+		optionValueMap:       make(map[int]runtime.Memo, 8),
+		optionValueSub1Map:   make(map[int]runtime.Memo, 8),
+		optionValueSub2Map:   make(map[int]runtime.Memo, 8),
+		importsMap:           make(map[int]runtime.Memo, 8),
+		importsSub1Map:       make(map[int]runtime.Memo, 8),
+		identMap:             make(map[int]runtime.Memo, 8),
+		parseRuleMap:         make(map[int]runtime.Memo, 8),
+		ruleBodyMap:          make(map[int]runtime.Memo, 8),
+		ruleBodySub1Map:      make(map[int]runtime.Memo, 8),
+		altLabelMap:          make(map[int]runtime.Memo, 8),
+		ruleSectMap:          make(map[int]runtime.Memo, 8),
+		ruleSectPredicateMap: make(map[int]runtime.Memo, 8),
+		ruleSectElementMap:   make(map[int]runtime.Memo, 8),
+		predicateMap:         make(map[int]runtime.Memo, 8),
+		elementLabelMap:      make(map[int]runtime.Memo, 8),
+		elementLabelSub1Map:  make(map[int]runtime.Memo, 8),
+		rulePartMap:          make(map[int]runtime.Memo, 8),
+		rulePartSub1Map:      make(map[int]runtime.Memo, 8),
+		suffixMap:            make(map[int]runtime.Memo, 8),
+		lexRuleMap:           make(map[int]runtime.Memo, 8),
+		lexRuleSub1Map:       make(map[int]runtime.Memo, 8),
+		lexModeMap:           make(map[int]runtime.Memo, 8),
+		lexActionsMap:        make(map[int]runtime.Memo, 8),
+		lexActionsSub1Map:    make(map[int]runtime.Memo, 8),
+		lexActionMap:         make(map[int]runtime.Memo, 8),
+		lexActionSub1Map:     make(map[int]runtime.Memo, 8),
+		lexActionSub2Map:     make(map[int]runtime.Memo, 8),
+		lexRuleBodyMap:       make(map[int]runtime.Memo, 8),
+		lexRuleBodySub1Map:   make(map[int]runtime.Memo, 8),
+		lexRuleSectMap:       make(map[int]runtime.Memo, 8),
+		lexRuleSectSub1Map:   make(map[int]runtime.Memo, 8),
+		lexRulePartMap:       make(map[int]runtime.Memo, 8),
+		lexRulePartSub1Map:   make(map[int]runtime.Memo, 8),
+		charSetMap:           make(map[int]runtime.Memo, 8),
+		charSetSub1Map:       make(map[int]runtime.Memo, 8),
+		charLitMap:           make(map[int]runtime.Memo, 8),
+		charRangeMap:         make(map[int]runtime.Memo, 8),
+	}
+}
+
+// Parse parses the input starting from the "top_level" parser rule. If
+// parsing fails, the error describes the farthest point reached
+func (p *Parser) Parse() (*ast.TopLevel, error) {
+	topLevel := p.ParseTopLevel()
+	if topLevel == nil {
+		return nil, p.p.Err()
 	}
+	return topLevel, nil
 }
 
 // *** top_level ***
 
 func (p *Parser) memoParseTopLevel() *ast.TopLevel {
 	pos := p.p.Pos()
-	topLevel, ok := p.topLevelMap[pos]
-	if ok {
+	if memo, ok := p.topLevelMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		topLevel, _ := memo.Result.(*ast.TopLevel)
 		return topLevel
 	}
-	topLevel = p.ParseTopLevel()
+	topLevel := p.ParseTopLevel()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.topLevelMap[pos] = topLevel
+	p.topLevelMap[pos] = runtime.Memo{Result: topLevel, EndPos: p.p.Pos()}
 	return topLevel
 }
 
@@ -54,14 +139,27 @@ func (p *Parser) ParseTopLevel() *ast.TopLevel {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### parse_rule* ###
-	parseRules := []*ast.ParserRule{}
+	// ### grammar_decl? ###
+	grammarDecl := p.memoParseGrammarDecl()
+
+	// ### (options_spec | imports)* ###
+	topLevelSub1s := []*topLevelSub1{}
+	for {
+		topLevelSub1 := p.memoParseTopLevelSub1()
+		if topLevelSub1 == nil {
+			break
+		}
+		topLevelSub1s = append(topLevelSub1s, topLevelSub1)
+	}
+
+	// ### (parse_rule | lex_rule | lex_mode)* ###
+	topLevelSub2s := []*topLevelSub2{}
 	for {
-		parseRule := p.memoParseParseRule()
-		if parseRule == nil {
+		topLevelSub2 := p.memoParseTopLevelSub2()
+		if topLevelSub2 == nil {
 			break
 		}
-		parseRules = append(parseRules, parseRule)
+		topLevelSub2s = append(topLevelSub2s, topLevelSub2)
 	}
 
 	// ### EOF ###
@@ -70,43 +168,147 @@ func (p *Parser) ParseTopLevel() *ast.TopLevel {
 		return nil
 	}
 
-	return ast.NewTopLevel(parseRules)
+	parseRules := []*ast.ParserRule{}
+	lexRules := []*ast.LexerRule{}
+	modes := []string{}
+	for _, rule := range topLevelSub2s {
+		switch {
+		case rule.parseRule != nil:
+			parseRules = append(parseRules, rule.parseRule)
+		case rule.lexRule != nil:
+			// Lexer rules belong to the most recently declared mode
+			if len(modes) > 0 {
+				rule.lexRule.Mode = modes[len(modes)-1]
+			}
+			lexRules = append(lexRules, rule.lexRule)
+		default:
+			modes = append(modes, *rule.lexMode)
+		}
+	}
+	topLevel := ast.NewTopLevel(parseRules, lexRules)
+	topLevel.Header = grammarDecl
+	for _, prequel := range topLevelSub1s {
+		if prequel.optionsSpec != nil {
+			topLevel.Options = append(topLevel.Options, prequel.optionsSpec...)
+		} else {
+			topLevel.Imports = append(topLevel.Imports, prequel.imports...)
+		}
+	}
+	topLevel.Modes = modes
+	topLevel.Span = ast.FileSpan(p.p.Filename(), eofTok)
+	return topLevel
 }
 
-// *** parse_rule ***
+// *** top_level - options_spec | imports ***
 
-func (p *Parser) memoParseParseRule() *ast.ParserRule {
+type topLevelSub1 struct {
+	optionsSpec []*ast.Option
+	imports     []*ast.Import
+}
+
+func (p *Parser) memoParseTopLevelSub1() *topLevelSub1 {
 	pos := p.p.Pos()
-	parseRule, ok := p.parseRuleMap[pos]
-	if ok {
-		return parseRule
+	if memo, ok := p.topLevelSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		topLevelSub1, _ := memo.Result.(*topLevelSub1)
+		return topLevelSub1
 	}
-	parseRule = p.ParseParseRule()
+	topLevelSub1 := p.parseTopLevelSub1()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.parseRuleMap[pos] = parseRule
-	return parseRule
+	p.topLevelSub1Map[pos] = runtime.Memo{Result: topLevelSub1, EndPos: p.p.Pos()}
+	return topLevelSub1
 }
 
-// ParseParseRule parses the "parse_rule" parser rule
-func (p *Parser) ParseParseRule() *ast.ParserRule {
+// parseTopLevelSub1 parses a sub-rule of the "top_level" parser rule
+func (p *Parser) parseTopLevelSub1() *topLevelSub1 {
+	// ### options_spec ###
+	if optionsSpec := p.memoParseOptionsSpec(); optionsSpec != nil {
+		return &topLevelSub1{optionsSpec: optionsSpec}
+	}
+
+	// ### imports ###
+	if imports := p.memoParseImports(); imports != nil {
+		return &topLevelSub1{imports: imports}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** top_level - parse_rule | lex_rule | lex_mode ***
+
+type topLevelSub2 struct {
+	parseRule *ast.ParserRule
+	lexRule   *ast.LexerRule
+	lexMode   *string
+}
+
+func (p *Parser) memoParseTopLevelSub2() *topLevelSub2 {
+	pos := p.p.Pos()
+	if memo, ok := p.topLevelSub2Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		topLevelSub2, _ := memo.Result.(*topLevelSub2)
+		return topLevelSub2
+	}
+	topLevelSub2 := p.parseTopLevelSub2()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.topLevelSub2Map[pos] = runtime.Memo{Result: topLevelSub2, EndPos: p.p.Pos()}
+	return topLevelSub2
+}
+
+// parseTopLevelSub2 parses a sub-rule of the "top_level" parser rule
+func (p *Parser) parseTopLevelSub2() *topLevelSub2 {
+	// ### parse_rule ###
+	if parseRule := p.memoParseParseRule(); parseRule != nil {
+		return &topLevelSub2{parseRule: parseRule}
+	}
+
+	// ### lex_rule ###
+	if lexRule := p.memoParseLexRule(); lexRule != nil {
+		return &topLevelSub2{lexRule: lexRule}
+	}
+
+	// ### lex_mode ###
+	if lexMode := p.memoParseLexMode(); lexMode != nil {
+		return &topLevelSub2{lexMode: lexMode}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** grammar_decl ***
+
+func (p *Parser) memoParseGrammarDecl() *ast.GrammarHeader {
+	pos := p.p.Pos()
+	if memo, ok := p.grammarDeclMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		grammarDecl, _ := memo.Result.(*ast.GrammarHeader)
+		return grammarDecl
+	}
+	grammarDecl := p.ParseGrammarDecl()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.grammarDeclMap[pos] = runtime.Memo{Result: grammarDecl, EndPos: p.p.Pos()}
+	return grammarDecl
+}
+
+// ParseGrammarDecl parses the "grammar_decl" parser rule
+func (p *Parser) ParseGrammarDecl() *ast.GrammarHeader {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### RULE_NAME ###
-	ruleNameTok := p.p.MatchTokenOrRollback(token.RULE_NAME, oldPos)
-	if ruleNameTok == nil {
-		return nil
-	}
+	// ### grammar_type? ###
+	grammarType := p.memoParseGrammarType()
 
-	// ### ':' ###
-	colonTok := p.p.MatchTokenOrRollback(token.COLON, oldPos)
-	if colonTok == nil {
+	// ### 'grammar' ###
+	grammarTok := p.p.MatchTokenOrRollback(token.GRAMMAR, oldPos)
+	if grammarTok == nil {
 		return nil
 	}
 
-	// ### rule_body ###
-	ruleBody := p.memoParseRuleBody()
-	if ruleBody == nil {
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
 		// Rule failed - rollback
 		p.p.SetPos(oldPos)
 		return nil
@@ -118,266 +320,1800 @@ func (p *Parser) ParseParseRule() *ast.ParserRule {
 		return nil
 	}
 
-	return &ast.ParserRule{ruleNameTok.Data, ruleBody}
+	first := grammarTok
+	if grammarType != nil {
+		first = grammarType
+	}
+	return ast.NewGrammarHeader(ast.TokenSpan(p.p.Filename(), first, semiTok), grammarType, ident)
 }
 
-// *** rule_body ***
+// *** grammar_type ***
 
-func (p *Parser) memoParseRuleBody() *ast.ParserAlternatives {
+func (p *Parser) memoParseGrammarType() *runtime.Token {
 	pos := p.p.Pos()
-	ruleBody, ok := p.ruleBodyMap[pos]
-	if ok {
-		return ruleBody
+	if memo, ok := p.grammarTypeMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		grammarType, _ := memo.Result.(*runtime.Token)
+		return grammarType
 	}
-	ruleBody = p.ParseRuleBody()
+	grammarType := p.ParseGrammarType()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.ruleBodyMap[pos] = ruleBody
-	return ruleBody
+	p.grammarTypeMap[pos] = runtime.Memo{Result: grammarType, EndPos: p.p.Pos()}
+	return grammarType
 }
 
-// ParseRuleBody parses the "rule_body" parser rule
-func (p *Parser) ParseRuleBody() *ast.ParserAlternatives {
+// ParseGrammarType parses the "grammar_type" parser rule
+func (p *Parser) ParseGrammarType() *runtime.Token {
+	// ### 'parser' ###
+	if parserTok := p.p.TryMatchToken(token.PARSER); parserTok != nil {
+		return parserTok
+	}
+
+	// ### 'lexer' ###
+	if lexerTok := p.p.TryMatchToken(token.LEXER); lexerTok != nil {
+		return lexerTok
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** options_spec ***
+
+func (p *Parser) memoParseOptionsSpec() []*ast.Option {
+	pos := p.p.Pos()
+	if memo, ok := p.optionsSpecMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		optionsSpec, _ := memo.Result.([]*ast.Option)
+		return optionsSpec
+	}
+	optionsSpec := p.ParseOptionsSpec()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.optionsSpecMap[pos] = runtime.Memo{Result: optionsSpec, EndPos: p.p.Pos()}
+	return optionsSpec
+}
+
+// ParseOptionsSpec parses the "options_spec" parser rule
+func (p *Parser) ParseOptionsSpec() []*ast.Option {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### rule_sect+ ###
-	ruleSects := []ast.ParserNode{}
-	matched := false
+	// ### 'options' ###
+	optionsTok := p.p.MatchTokenOrRollback(token.OPTIONS, oldPos)
+	if optionsTok == nil {
+		return nil
+	}
+
+	// ### '{' ###
+	lbraceTok := p.p.MatchTokenOrRollback(token.LBRACE, oldPos)
+	if lbraceTok == nil {
+		return nil
+	}
+
+	// ### option* ###
+	options := []*ast.Option{}
 	for {
-		ruleSect := p.memoParseRuleSect()
-		if ruleSect == nil {
+		option := p.memoParseOption()
+		if option == nil {
 			break
 		}
-		matched = true
-		ruleSects = append(ruleSects, ruleSect)
+		options = append(options, option)
 	}
-	if !matched {
-		// Failed - rollback
+
+	// ### '}' ###
+	rbraceTok := p.p.MatchTokenOrRollback(token.RBRACE, oldPos)
+	if rbraceTok == nil {
+		return nil
+	}
+
+	return options
+}
+
+// *** option ***
+
+func (p *Parser) memoParseOption() *ast.Option {
+	pos := p.p.Pos()
+	if memo, ok := p.optionMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		option, _ := memo.Result.(*ast.Option)
+		return option
+	}
+	option := p.ParseOption()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.optionMap[pos] = runtime.Memo{Result: option, EndPos: p.p.Pos()}
+	return option
+}
+
+// ParseOption parses the "option" parser rule
+func (p *Parser) ParseOption() *ast.Option {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
+		// Rule failed - rollback
 		p.p.SetPos(oldPos)
 		return nil
 	}
 
-	// ### ('|' rule_sect+)* ###
-	ruleBodySub1s := []*ruleBodySub1{}
-	for {
-		ruleBodySub1 := p.memoParseRuleBodySub1()
-		if ruleBodySub1 == nil {
-			break
+	// ### '=' ###
+	equalsTok := p.p.MatchTokenOrRollback(token.EQUALS, oldPos)
+	if equalsTok == nil {
+		return nil
+	}
+
+	// ### option_value ###
+	optionValue := p.memoParseOptionValue()
+	if optionValue == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ';' ###
+	semiTok := p.p.MatchTokenOrRollback(token.SEMI, oldPos)
+	if semiTok == nil {
+		return nil
+	}
+
+	return &ast.Option{
+		Span: ast.TokenSpan(p.p.Filename(), ident, semiTok), Name: ident.Data, Value: *optionValue,
+	}
+}
+
+// *** option_value ***
+
+func (p *Parser) memoParseOptionValue() *string {
+	pos := p.p.Pos()
+	if memo, ok := p.optionValueMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		optionValue, _ := memo.Result.(*string)
+		return optionValue
+	}
+	optionValue := p.ParseOptionValue()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.optionValueMap[pos] = runtime.Memo{Result: optionValue, EndPos: p.p.Pos()}
+	return optionValue
+}
+
+// ParseOptionValue parses the "option_value" parser rule
+func (p *Parser) ParseOptionValue() *string {
+	// ### ident ('.' ident)* ###
+	if optionValueSub1 := p.memoParseOptionValueSub1(); optionValueSub1 != nil {
+		value := optionValueSub1.ident.Data
+		for _, node := range optionValueSub1.optionValueSub2s {
+			value += "." + node.ident.Data
 		}
-		ruleBodySub1s = append(ruleBodySub1s, ruleBodySub1)
+		return &value
 	}
 
-	parserNodes := [][]ast.ParserNode{ruleSects}
-	for _, node := range ruleBodySub1s {
-		parserNodes = append(parserNodes, node.ruleSects)
+	// ### TOKEN_LIT ###
+	if tokenLitTok := p.p.TryMatchToken(token.TOKEN_LIT); tokenLitTok != nil {
+		return &tokenLitTok.Data
 	}
-	return &ast.ParserAlternatives{Rules: parserNodes}
+
+	// No alternative matched
+	return nil
 }
 
-// *** rule_body - '|' rule_sect+ ***
+// *** option_value - ident ('.' ident)* ***
 
-type ruleBodySub1 struct {
-	pipeTok   *runtime.Token
-	ruleSects []ast.ParserNode
+type optionValueSub1 struct {
+	ident            *runtime.Token
+	optionValueSub2s []*optionValueSub2
 }
 
-func (p *Parser) memoParseRuleBodySub1() *ruleBodySub1 {
+func (p *Parser) memoParseOptionValueSub1() *optionValueSub1 {
 	pos := p.p.Pos()
-	ruleBodySub1, ok := p.ruleBodySub1Map[pos]
-	if ok {
-		return ruleBodySub1
+	if memo, ok := p.optionValueSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		optionValueSub1, _ := memo.Result.(*optionValueSub1)
+		return optionValueSub1
 	}
-	ruleBodySub1 = p.ParseRuleBodySub1()
+	optionValueSub1 := p.parseOptionValueSub1()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.ruleBodySub1Map[pos] = ruleBodySub1
-	return ruleBodySub1
+	p.optionValueSub1Map[pos] = runtime.Memo{Result: optionValueSub1, EndPos: p.p.Pos()}
+	return optionValueSub1
 }
 
-func (p *Parser) ParseRuleBodySub1() *ruleBodySub1 {
+// parseOptionValueSub1 parses a sub-rule of the "option_value" parser rule
+func (p *Parser) parseOptionValueSub1() *optionValueSub1 {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### '|' ###
-	pipeTok := p.p.MatchTokenOrRollback(token.PIPE, oldPos)
-	if pipeTok == nil {
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
 		return nil
 	}
 
-	// ### rule_sect+ ###
-	ruleSects := []ast.ParserNode{}
-	matched := false
+	// ### ('.' ident)* ###
+	optionValueSub2s := []*optionValueSub2{}
 	for {
-		ruleSect := p.memoParseRuleSect()
-		if ruleSect == nil {
+		optionValueSub2 := p.memoParseOptionValueSub2()
+		if optionValueSub2 == nil {
 			break
 		}
-		matched = true
-		ruleSects = append(ruleSects, ruleSect)
-	}
-	if !matched {
-		// Failed - rollback
-		p.p.SetPos(oldPos)
-		return nil
+		optionValueSub2s = append(optionValueSub2s, optionValueSub2)
 	}
 
-	return &ruleBodySub1{pipeTok: pipeTok, ruleSects: ruleSects}
+	return &optionValueSub1{ident: ident, optionValueSub2s: optionValueSub2s}
 }
 
-// *** rule_sect ***
+// *** option_value - '.' ident ***
 
-func (p *Parser) memoParseRuleSect() ast.ParserNode {
+type optionValueSub2 struct {
+	dotTok *runtime.Token
+	ident  *runtime.Token
+}
+
+func (p *Parser) memoParseOptionValueSub2() *optionValueSub2 {
 	pos := p.p.Pos()
-	ruleSect, ok := p.ruleSectMap[pos]
-	if ok {
-		return ruleSect
+	if memo, ok := p.optionValueSub2Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		optionValueSub2, _ := memo.Result.(*optionValueSub2)
+		return optionValueSub2
 	}
-	ruleSect = p.ParseRuleSect()
+	optionValueSub2 := p.parseOptionValueSub2()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.ruleSectMap[pos] = ruleSect
-	return ruleSect
+	p.optionValueSub2Map[pos] = runtime.Memo{Result: optionValueSub2, EndPos: p.p.Pos()}
+	return optionValueSub2
 }
 
-func (p *Parser) ParseRuleSect() ast.ParserNode {
+// parseOptionValueSub2 parses a sub-rule of the "option_value" parser rule
+func (p *Parser) parseOptionValueSub2() *optionValueSub2 {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### rule_part ###
-	rulePart := p.memoParseRulePart()
-	if rulePart == nil {
+	// ### '.' ###
+	dotTok := p.p.MatchTokenOrRollback(token.DOT, oldPos)
+	if dotTok == nil {
+		return nil
+	}
+
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
 		// Rule failed - rollback
 		p.p.SetPos(oldPos)
 		return nil
 	}
 
-	// ### suffix? ###
-	suffix := p.memoParseSuffix()
-
-	return ast.NewNestedNode(rulePart, suffix)
+	return &optionValueSub2{dotTok: dotTok, ident: ident}
 }
 
-// *** rule_part ***
+// *** imports ***
 
-func (p *Parser) memoParseRulePart() ast.ParserNode {
+func (p *Parser) memoParseImports() []*ast.Import {
 	pos := p.p.Pos()
-	rulePart, ok := p.rulePartMap[pos]
-	if ok {
-		return rulePart
+	if memo, ok := p.importsMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		imports, _ := memo.Result.([]*ast.Import)
+		return imports
 	}
-	rulePart = p.ParseRulePart()
+	imports := p.ParseImports()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.rulePartMap[pos] = rulePart
-	return rulePart
+	p.importsMap[pos] = runtime.Memo{Result: imports, EndPos: p.p.Pos()}
+	return imports
 }
 
-func (p *Parser) ParseRulePart() ast.ParserNode {
+// ParseImports parses the "imports" parser rule
+func (p *Parser) ParseImports() []*ast.Import {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### '(' rule_body ')' ###
-	rulePartSub1 := p.memoParseRulePartSub1()
-	if rulePartSub1 != nil {
-		return rulePartSub1.ruleBody
+	// ### 'import' ###
+	importTok := p.p.MatchTokenOrRollback(token.IMPORT, oldPos)
+	if importTok == nil {
+		return nil
 	}
 
-	// ### RULE_NAME ###
-	if ruleNameTok := p.p.TryMatchToken(token.RULE_NAME); ruleNameTok != nil {
-		return &ast.ParserRuleRef{Name: ruleNameTok.Data}
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
 	}
 
-	// ### TOKEN_NAME ###
-	if tokenNameTok := p.p.TryMatchToken(token.TOKEN_NAME); tokenNameTok != nil {
-		return &ast.ParserLexerRuleRef{Name: tokenNameTok.Data}
+	// ### (',' ident)* ###
+	importsSub1s := []*importsSub1{}
+	for {
+		importsSub1 := p.memoParseImportsSub1()
+		if importsSub1 == nil {
+			break
+		}
+		importsSub1s = append(importsSub1s, importsSub1)
 	}
 
-	// ### TOKEN_LIT ###
-	tokenLitTok := p.p.MatchTokenOrRollback(token.TOKEN_LIT, oldPos)
-	if tokenLitTok == nil {
+	// ### ';' ###
+	semiTok := p.p.MatchTokenOrRollback(token.SEMI, oldPos)
+	if semiTok == nil {
 		return nil
 	}
 
-	return &ast.ParserToken{Token: tokenLitTok}
+	idents := []*runtime.Token{ident}
+	for _, node := range importsSub1s {
+		idents = append(idents, node.ident)
+	}
+	imports := make([]*ast.Import, 0, len(idents))
+	for _, ident := range idents {
+		imports = append(imports, &ast.Import{
+			Span: ast.TokenSpan(p.p.Filename(), ident, ident), Name: ident.Data,
+		})
+	}
+	return imports
 }
 
-// *** rule_part - '(' rule_body ')' ***
+// *** imports - ',' ident ***
 
-type rulePartSub1 struct {
-	lparenTok *runtime.Token
-	ruleBody  *ast.ParserAlternatives
-	rparenTok *runtime.Token
+type importsSub1 struct {
+	commaTok *runtime.Token
+	ident    *runtime.Token
 }
 
-func (p *Parser) memoParseRulePartSub1() *rulePartSub1 {
+func (p *Parser) memoParseImportsSub1() *importsSub1 {
 	pos := p.p.Pos()
-	rulePartSub1, ok := p.rulePartSub1Map[pos]
-	if ok {
-		return rulePartSub1
+	if memo, ok := p.importsSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		importsSub1, _ := memo.Result.(*importsSub1)
+		return importsSub1
 	}
-	rulePartSub1 = p.ParseRulePartSub1()
+	importsSub1 := p.parseImportsSub1()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.rulePartSub1Map[pos] = rulePartSub1
-	return rulePartSub1
+	p.importsSub1Map[pos] = runtime.Memo{Result: importsSub1, EndPos: p.p.Pos()}
+	return importsSub1
 }
 
-func (p *Parser) ParseRulePartSub1() *rulePartSub1 {
+// parseImportsSub1 parses a sub-rule of the "imports" parser rule
+func (p *Parser) parseImportsSub1() *importsSub1 {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### '(' ###
-	lparenTok := p.p.MatchTokenOrRollback(token.LPAREN, oldPos)
-	if lparenTok == nil {
+	// ### ',' ###
+	commaTok := p.p.MatchTokenOrRollback(token.COMMA, oldPos)
+	if commaTok == nil {
 		return nil
 	}
 
-	// ### rule_body ###
-	ruleBody := p.memoParseRuleBody()
-	if ruleBody == nil {
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
 		// Rule failed - rollback
 		p.p.SetPos(oldPos)
 		return nil
 	}
 
-	// ### ')' ###
-	rparenTok := p.p.MatchTokenOrRollback(token.RPAREN, oldPos)
-	if rparenTok == nil {
-		return nil
+	return &importsSub1{commaTok: commaTok, ident: ident}
+}
+
+// *** ident ***
+
+func (p *Parser) memoParseIdent() *runtime.Token {
+	pos := p.p.Pos()
+	if memo, ok := p.identMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		ident, _ := memo.Result.(*runtime.Token)
+		return ident
+	}
+	ident := p.ParseIdent()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.identMap[pos] = runtime.Memo{Result: ident, EndPos: p.p.Pos()}
+	return ident
+}
+
+// ParseIdent parses the "ident" parser rule
+func (p *Parser) ParseIdent() *runtime.Token {
+	// ### RULE_NAME ###
+	if ruleNameTok := p.p.TryMatchToken(token.RULE_NAME); ruleNameTok != nil {
+		return ruleNameTok
+	}
+
+	// ### TOKEN_NAME ###
+	if tokenNameTok := p.p.TryMatchToken(token.TOKEN_NAME); tokenNameTok != nil {
+		return tokenNameTok
 	}
 
-	return &rulePartSub1{lparenTok: lparenTok, ruleBody: ruleBody, rparenTok: rparenTok}
+	// No alternative matched
+	return nil
 }
 
-// *** suffix ***
+// *** parse_rule ***
 
-func (p *Parser) memoParseSuffix() *runtime.Token {
+func (p *Parser) memoParseParseRule() *ast.ParserRule {
 	pos := p.p.Pos()
-	suffix, ok := p.suffixMap[pos]
-	if ok {
-		return suffix
+	if memo, ok := p.parseRuleMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		parseRule, _ := memo.Result.(*ast.ParserRule)
+		return parseRule
 	}
-	suffix = p.ParseSuffix()
+	parseRule := p.ParseParseRule()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.suffixMap[pos] = suffix
-	return suffix
+	p.parseRuleMap[pos] = runtime.Memo{Result: parseRule, EndPos: p.p.Pos()}
+	return parseRule
 }
 
-func (p *Parser) ParseSuffix() *runtime.Token {
+// ParseParseRule parses the "parse_rule" parser rule
+func (p *Parser) ParseParseRule() *ast.ParserRule {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
-	// ### '+' ###
-	if plusTok := p.p.TryMatchToken(token.PLUS); plusTok != nil {
-		return plusTok
+	// ### RULE_NAME ###
+	ruleNameTok := p.p.MatchTokenOrRollback(token.RULE_NAME, oldPos)
+	if ruleNameTok == nil {
+		return nil
 	}
 
-	// ### '*' ###
+	// ### ':' ###
+	colonTok := p.p.MatchTokenOrRollback(token.COLON, oldPos)
+	if colonTok == nil {
+		return nil
+	}
+
+	// ### rule_body ###
+	ruleBody := p.memoParseRuleBody()
+	if ruleBody == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ';' ###
+	semiTok := p.p.MatchTokenOrRollback(token.SEMI, oldPos)
+	if semiTok == nil {
+		return nil
+	}
+
+	return &ast.ParserRule{
+		Span: ast.TokenSpan(p.p.Filename(), ruleNameTok, semiTok),
+		Name: ruleNameTok.Data, Rules: ruleBody,
+	}
+}
+
+// *** rule_body ***
+
+func (p *Parser) memoParseRuleBody() *ast.ParserAlternatives {
+	pos := p.p.Pos()
+	if memo, ok := p.ruleBodyMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		ruleBody, _ := memo.Result.(*ast.ParserAlternatives)
+		return ruleBody
+	}
+	ruleBody := p.ParseRuleBody()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.ruleBodyMap[pos] = runtime.Memo{Result: ruleBody, EndPos: p.p.Pos()}
+	return ruleBody
+}
+
+// ParseRuleBody parses the "rule_body" parser rule
+func (p *Parser) ParseRuleBody() *ast.ParserAlternatives {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### rule_sect+ ###
+	ruleSects := []ast.ParserNode{}
+	for {
+		ruleSect := p.memoParseRuleSect()
+		if ruleSect == nil {
+			break
+		}
+		ruleSects = append(ruleSects, ruleSect)
+	}
+	if len(ruleSects) == 0 {
+		// Failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### alt_label? ###
+	altLabel := p.memoParseAltLabel()
+
+	// ### ('|' rule_sect+ alt_label?)* ###
+	ruleBodySub1s := []*ruleBodySub1{}
+	for {
+		ruleBodySub1 := p.memoParseRuleBodySub1()
+		if ruleBodySub1 == nil {
+			break
+		}
+		ruleBodySub1s = append(ruleBodySub1s, ruleBodySub1)
+	}
+
+	parserNodes := [][]ast.ParserNode{ruleSects}
+	labels := []*ast.AltLabel{altLabel}
+	for _, node := range ruleBodySub1s {
+		parserNodes = append(parserNodes, node.ruleSects)
+		labels = append(labels, node.altLabel)
+	}
+	return ast.NewParserAlternatives(parserNodes, labels)
+}
+
+// *** rule_body - '|' rule_sect+ alt_label? ***
+
+type ruleBodySub1 struct {
+	pipeTok   *runtime.Token
+	ruleSects []ast.ParserNode
+	altLabel  *ast.AltLabel
+}
+
+func (p *Parser) memoParseRuleBodySub1() *ruleBodySub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.ruleBodySub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		ruleBodySub1, _ := memo.Result.(*ruleBodySub1)
+		return ruleBodySub1
+	}
+	ruleBodySub1 := p.parseRuleBodySub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.ruleBodySub1Map[pos] = runtime.Memo{Result: ruleBodySub1, EndPos: p.p.Pos()}
+	return ruleBodySub1
+}
+
+// parseRuleBodySub1 parses a sub-rule of the "rule_body" parser rule
+func (p *Parser) parseRuleBodySub1() *ruleBodySub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '|' ###
+	pipeTok := p.p.MatchTokenOrRollback(token.PIPE, oldPos)
+	if pipeTok == nil {
+		return nil
+	}
+
+	// ### rule_sect+ ###
+	ruleSects := []ast.ParserNode{}
+	for {
+		ruleSect := p.memoParseRuleSect()
+		if ruleSect == nil {
+			break
+		}
+		ruleSects = append(ruleSects, ruleSect)
+	}
+	if len(ruleSects) == 0 {
+		// Failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### alt_label? ###
+	altLabel := p.memoParseAltLabel()
+
+	return &ruleBodySub1{pipeTok: pipeTok, ruleSects: ruleSects, altLabel: altLabel}
+}
+
+// *** alt_label ***
+
+func (p *Parser) memoParseAltLabel() *ast.AltLabel {
+	pos := p.p.Pos()
+	if memo, ok := p.altLabelMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		altLabel, _ := memo.Result.(*ast.AltLabel)
+		return altLabel
+	}
+	altLabel := p.ParseAltLabel()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.altLabelMap[pos] = runtime.Memo{Result: altLabel, EndPos: p.p.Pos()}
+	return altLabel
+}
+
+// ParseAltLabel parses the "alt_label" parser rule
+func (p *Parser) ParseAltLabel() *ast.AltLabel {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '#' ###
+	poundTok := p.p.MatchTokenOrRollback(token.POUND, oldPos)
+	if poundTok == nil {
+		return nil
+	}
+
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	return &ast.AltLabel{Span: ast.TokenSpan(p.p.Filename(), poundTok, ident), Name: ident.Data}
+}
+
+// *** rule_sect ***
+
+func (p *Parser) memoParseRuleSect() ast.ParserNode {
+	pos := p.p.Pos()
+	if memo, ok := p.ruleSectMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		ruleSect, _ := memo.Result.(ast.ParserNode)
+		return ruleSect
+	}
+	ruleSect := p.ParseRuleSect()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.ruleSectMap[pos] = runtime.Memo{Result: ruleSect, EndPos: p.p.Pos()}
+	return ruleSect
+}
+
+// ParseRuleSect parses the "rule_sect" parser rule
+func (p *Parser) ParseRuleSect() ast.ParserNode {
+	// ### predicate rule_part suffix? ###
+	if ruleSectPredicate := p.memoParseRuleSectPredicate(); ruleSectPredicate != nil {
+		return ruleSectPredicate
+	}
+
+	// ### element_label? rule_part suffix? ###
+	if ruleSectElement := p.memoParseRuleSectElement(); ruleSectElement != nil {
+		return ruleSectElement
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** rule_sect - predicate rule_part suffix? ***
+
+func (p *Parser) memoParseRuleSectPredicate() ast.ParserNode {
+	pos := p.p.Pos()
+	if memo, ok := p.ruleSectPredicateMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		ruleSectPredicate, _ := memo.Result.(ast.ParserNode)
+		return ruleSectPredicate
+	}
+	ruleSectPredicate := p.parseRuleSectPredicate()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.ruleSectPredicateMap[pos] = runtime.Memo{Result: ruleSectPredicate, EndPos: p.p.Pos()}
+	return ruleSectPredicate
+}
+
+// parseRuleSectPredicate parses a sub-rule of the "rule_sect" parser rule
+func (p *Parser) parseRuleSectPredicate() ast.ParserNode {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### predicate ###
+	predicate := p.memoParsePredicate()
+	if predicate == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### rule_part ###
+	rulePart := p.memoParseRulePart()
+	if rulePart == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### suffix? ###
+	suffix := p.memoParseSuffix()
+
+	return ast.NewPredicateNode(predicate, ast.NewNestedNode(rulePart, suffix))
+}
+
+// *** rule_sect - element_label? rule_part suffix? ***
+
+func (p *Parser) memoParseRuleSectElement() ast.ParserNode {
+	pos := p.p.Pos()
+	if memo, ok := p.ruleSectElementMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		ruleSectElement, _ := memo.Result.(ast.ParserNode)
+		return ruleSectElement
+	}
+	ruleSectElement := p.parseRuleSectElement()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.ruleSectElementMap[pos] = runtime.Memo{Result: ruleSectElement, EndPos: p.p.Pos()}
+	return ruleSectElement
+}
+
+// parseRuleSectElement parses a sub-rule of the "rule_sect" parser rule
+func (p *Parser) parseRuleSectElement() ast.ParserNode {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### element_label? ###
+	elementLabel := p.memoParseElementLabel()
+
+	// ### rule_part ###
+	rulePart := p.memoParseRulePart()
+	if rulePart == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### suffix? ###
+	suffix := p.memoParseSuffix()
+
+	return ast.NewLabeledNode(elementLabel, ast.NewNestedNode(rulePart, suffix))
+}
+
+// *** predicate ***
+
+func (p *Parser) memoParsePredicate() *runtime.Token {
+	pos := p.p.Pos()
+	if memo, ok := p.predicateMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		predicate, _ := memo.Result.(*runtime.Token)
+		return predicate
+	}
+	predicate := p.ParsePredicate()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.predicateMap[pos] = runtime.Memo{Result: predicate, EndPos: p.p.Pos()}
+	return predicate
+}
+
+// ParsePredicate parses the "predicate" parser rule
+func (p *Parser) ParsePredicate() *runtime.Token {
+	// ### '&' ###
+	if ampTok := p.p.TryMatchToken(token.AMP); ampTok != nil {
+		return ampTok
+	}
+
+	// ### '!' ###
+	if bangTok := p.p.TryMatchToken(token.BANG); bangTok != nil {
+		return bangTok
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** element_label ***
+
+func (p *Parser) memoParseElementLabel() *ast.ParserLabel {
+	pos := p.p.Pos()
+	if memo, ok := p.elementLabelMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		elementLabel, _ := memo.Result.(*ast.ParserLabel)
+		return elementLabel
+	}
+	elementLabel := p.ParseElementLabel()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.elementLabelMap[pos] = runtime.Memo{Result: elementLabel, EndPos: p.p.Pos()}
+	return elementLabel
+}
+
+// ParseElementLabel parses the "element_label" parser rule
+func (p *Parser) ParseElementLabel() *ast.ParserLabel {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### ident ###
+	ident := p.memoParseIdent()
+	if ident == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ('=' | '+=') ###
+	elementLabelSub1 := p.memoParseElementLabelSub1()
+	if elementLabelSub1 == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	return &ast.ParserLabel{
+		Span: ast.TokenSpan(p.p.Filename(), ident, ident), Name: ident.Data,
+		List: elementLabelSub1.plusAssignTok != nil,
+	}
+}
+
+// *** element_label - '=' | '+=' ***
+
+type elementLabelSub1 struct {
+	equalsTok     *runtime.Token
+	plusAssignTok *runtime.Token
+}
+
+func (p *Parser) memoParseElementLabelSub1() *elementLabelSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.elementLabelSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		elementLabelSub1, _ := memo.Result.(*elementLabelSub1)
+		return elementLabelSub1
+	}
+	elementLabelSub1 := p.parseElementLabelSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.elementLabelSub1Map[pos] = runtime.Memo{Result: elementLabelSub1, EndPos: p.p.Pos()}
+	return elementLabelSub1
+}
+
+// parseElementLabelSub1 parses a sub-rule of the "element_label" parser rule
+func (p *Parser) parseElementLabelSub1() *elementLabelSub1 {
+	// ### '=' ###
+	if equalsTok := p.p.TryMatchToken(token.EQUALS); equalsTok != nil {
+		return &elementLabelSub1{equalsTok: equalsTok}
+	}
+
+	// ### '+=' ###
+	if plusAssignTok := p.p.TryMatchToken(token.PLUS_ASSIGN); plusAssignTok != nil {
+		return &elementLabelSub1{plusAssignTok: plusAssignTok}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** rule_part ***
+
+func (p *Parser) memoParseRulePart() ast.ParserNode {
+	pos := p.p.Pos()
+	if memo, ok := p.rulePartMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		rulePart, _ := memo.Result.(ast.ParserNode)
+		return rulePart
+	}
+	rulePart := p.ParseRulePart()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.rulePartMap[pos] = runtime.Memo{Result: rulePart, EndPos: p.p.Pos()}
+	return rulePart
+}
+
+// ParseRulePart parses the "rule_part" parser rule
+func (p *Parser) ParseRulePart() ast.ParserNode {
+	// ### '(' rule_body ')' ###
+	if rulePartSub1 := p.memoParseRulePartSub1(); rulePartSub1 != nil {
+		// Copy so the memoized rule body keeps its own span
+		body := *rulePartSub1.ruleBody
+		body.Span = ast.TokenSpan(p.p.Filename(), rulePartSub1.lparenTok, rulePartSub1.rparenTok)
+		return &body
+	}
+
+	// ### RULE_NAME ###
+	if ruleNameTok := p.p.TryMatchToken(token.RULE_NAME); ruleNameTok != nil {
+		return &ast.ParserRuleRef{
+			Span: ast.TokenSpan(p.p.Filename(), ruleNameTok, ruleNameTok), Name: ruleNameTok.Data,
+		}
+	}
+
+	// ### TOKEN_NAME ###
+	if tokenNameTok := p.p.TryMatchToken(token.TOKEN_NAME); tokenNameTok != nil {
+		return &ast.ParserLexerRuleRef{
+			Span: ast.TokenSpan(p.p.Filename(), tokenNameTok, tokenNameTok), Name: tokenNameTok.Data,
+		}
+	}
+
+	// ### TOKEN_LIT ###
+	if tokenLitTok := p.p.TryMatchToken(token.TOKEN_LIT); tokenLitTok != nil {
+		return &ast.ParserToken{
+			Span: ast.TokenSpan(p.p.Filename(), tokenLitTok, tokenLitTok), Token: tokenLitTok,
+		}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** rule_part - '(' rule_body ')' ***
+
+type rulePartSub1 struct {
+	lparenTok *runtime.Token
+	ruleBody  *ast.ParserAlternatives
+	rparenTok *runtime.Token
+}
+
+func (p *Parser) memoParseRulePartSub1() *rulePartSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.rulePartSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		rulePartSub1, _ := memo.Result.(*rulePartSub1)
+		return rulePartSub1
+	}
+	rulePartSub1 := p.parseRulePartSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.rulePartSub1Map[pos] = runtime.Memo{Result: rulePartSub1, EndPos: p.p.Pos()}
+	return rulePartSub1
+}
+
+// parseRulePartSub1 parses a sub-rule of the "rule_part" parser rule
+func (p *Parser) parseRulePartSub1() *rulePartSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '(' ###
+	lparenTok := p.p.MatchTokenOrRollback(token.LPAREN, oldPos)
+	if lparenTok == nil {
+		return nil
+	}
+
+	// ### rule_body ###
+	ruleBody := p.memoParseRuleBody()
+	if ruleBody == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ')' ###
+	rparenTok := p.p.MatchTokenOrRollback(token.RPAREN, oldPos)
+	if rparenTok == nil {
+		return nil
+	}
+
+	return &rulePartSub1{
+		lparenTok: lparenTok, ruleBody: ruleBody, rparenTok: rparenTok,
+	}
+}
+
+// *** suffix ***
+
+func (p *Parser) memoParseSuffix() *runtime.Token {
+	pos := p.p.Pos()
+	if memo, ok := p.suffixMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		suffix, _ := memo.Result.(*runtime.Token)
+		return suffix
+	}
+	suffix := p.ParseSuffix()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.suffixMap[pos] = runtime.Memo{Result: suffix, EndPos: p.p.Pos()}
+	return suffix
+}
+
+// ParseSuffix parses the "suffix" parser rule
+func (p *Parser) ParseSuffix() *runtime.Token {
+	// ### '+' ###
+	if plusTok := p.p.TryMatchToken(token.PLUS); plusTok != nil {
+		return plusTok
+	}
+
+	// ### '*' ###
 	if starTok := p.p.TryMatchToken(token.STAR); starTok != nil {
 		return starTok
 	}
 
-	// ### '?' ###
-	questMarkTok := p.p.MatchTokenOrRollback(token.QUEST_MARK, oldPos)
-	if questMarkTok == nil {
+	// ### '?' ###
+	if questMarkTok := p.p.TryMatchToken(token.QUEST_MARK); questMarkTok != nil {
+		return questMarkTok
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** lex_rule ***
+
+func (p *Parser) memoParseLexRule() *ast.LexerRule {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRuleMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRule, _ := memo.Result.(*ast.LexerRule)
+		return lexRule
+	}
+	lexRule := p.ParseLexRule()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRuleMap[pos] = runtime.Memo{Result: lexRule, EndPos: p.p.Pos()}
+	return lexRule
+}
+
+// ParseLexRule parses the "lex_rule" parser rule
+func (p *Parser) ParseLexRule() *ast.LexerRule {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### 'fragment'? ###
+	fragmentTok := p.p.TryMatchToken(token.FRAGMENT)
+
+	// ### TOKEN_NAME ###
+	tokenNameTok := p.p.MatchTokenOrRollback(token.TOKEN_NAME, oldPos)
+	if tokenNameTok == nil {
+		return nil
+	}
+
+	// ### ':' ###
+	colonTok := p.p.MatchTokenOrRollback(token.COLON, oldPos)
+	if colonTok == nil {
+		return nil
+	}
+
+	// ### lex_rule_body ###
+	lexRuleBody := p.memoParseLexRuleBody()
+	if lexRuleBody == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ('->' lex_actions)? ###
+	lexRuleSub1 := p.memoParseLexRuleSub1()
+
+	// ### ';' ###
+	semiTok := p.p.MatchTokenOrRollback(token.SEMI, oldPos)
+	if semiTok == nil {
+		return nil
+	}
+
+	first := tokenNameTok
+	if fragmentTok != nil {
+		first = fragmentTok
+	}
+	rule := &ast.LexerRule{
+		Span: ast.TokenSpan(p.p.Filename(), first, semiTok), Fragment: fragmentTok != nil,
+		Name: tokenNameTok.Data, Rules: lexRuleBody,
+	}
+	if lexRuleSub1 != nil {
+		rule.Actions = lexRuleSub1.lexActions
+	}
+	return rule
+}
+
+// *** lex_rule - '->' lex_actions ***
+
+type lexRuleSub1 struct {
+	rarrowTok  *runtime.Token
+	lexActions []*ast.LexerAction
+}
+
+func (p *Parser) memoParseLexRuleSub1() *lexRuleSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRuleSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRuleSub1, _ := memo.Result.(*lexRuleSub1)
+		return lexRuleSub1
+	}
+	lexRuleSub1 := p.parseLexRuleSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRuleSub1Map[pos] = runtime.Memo{Result: lexRuleSub1, EndPos: p.p.Pos()}
+	return lexRuleSub1
+}
+
+// parseLexRuleSub1 parses a sub-rule of the "lex_rule" parser rule
+func (p *Parser) parseLexRuleSub1() *lexRuleSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '->' ###
+	rarrowTok := p.p.MatchTokenOrRollback(token.RARROW, oldPos)
+	if rarrowTok == nil {
+		return nil
+	}
+
+	// ### lex_actions ###
+	lexActions := p.memoParseLexActions()
+	if lexActions == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	return &lexRuleSub1{rarrowTok: rarrowTok, lexActions: lexActions}
+}
+
+// *** lex_mode ***
+
+func (p *Parser) memoParseLexMode() *string {
+	pos := p.p.Pos()
+	if memo, ok := p.lexModeMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexMode, _ := memo.Result.(*string)
+		return lexMode
+	}
+	lexMode := p.ParseLexMode()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexModeMap[pos] = runtime.Memo{Result: lexMode, EndPos: p.p.Pos()}
+	return lexMode
+}
+
+// ParseLexMode parses the "lex_mode" parser rule
+func (p *Parser) ParseLexMode() *string {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### 'mode' ###
+	modeTok := p.p.MatchTokenOrRollback(token.MODE, oldPos)
+	if modeTok == nil {
+		return nil
+	}
+
+	// ### TOKEN_NAME ###
+	tokenNameTok := p.p.MatchTokenOrRollback(token.TOKEN_NAME, oldPos)
+	if tokenNameTok == nil {
+		return nil
+	}
+
+	// ### ';' ###
+	semiTok := p.p.MatchTokenOrRollback(token.SEMI, oldPos)
+	if semiTok == nil {
+		return nil
+	}
+
+	return &tokenNameTok.Data
+}
+
+// *** lex_actions ***
+
+func (p *Parser) memoParseLexActions() []*ast.LexerAction {
+	pos := p.p.Pos()
+	if memo, ok := p.lexActionsMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexActions, _ := memo.Result.([]*ast.LexerAction)
+		return lexActions
+	}
+	lexActions := p.ParseLexActions()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexActionsMap[pos] = runtime.Memo{Result: lexActions, EndPos: p.p.Pos()}
+	return lexActions
+}
+
+// ParseLexActions parses the "lex_actions" parser rule
+func (p *Parser) ParseLexActions() []*ast.LexerAction {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### lex_action ###
+	lexAction := p.memoParseLexAction()
+	if lexAction == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### (',' lex_action)* ###
+	lexActionsSub1s := []*lexActionsSub1{}
+	for {
+		lexActionsSub1 := p.memoParseLexActionsSub1()
+		if lexActionsSub1 == nil {
+			break
+		}
+		lexActionsSub1s = append(lexActionsSub1s, lexActionsSub1)
+	}
+
+	actions := []*ast.LexerAction{lexAction}
+	for _, node := range lexActionsSub1s {
+		actions = append(actions, node.lexAction)
+	}
+	return actions
+}
+
+// *** lex_actions - ',' lex_action ***
+
+type lexActionsSub1 struct {
+	commaTok  *runtime.Token
+	lexAction *ast.LexerAction
+}
+
+func (p *Parser) memoParseLexActionsSub1() *lexActionsSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexActionsSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexActionsSub1, _ := memo.Result.(*lexActionsSub1)
+		return lexActionsSub1
+	}
+	lexActionsSub1 := p.parseLexActionsSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexActionsSub1Map[pos] = runtime.Memo{Result: lexActionsSub1, EndPos: p.p.Pos()}
+	return lexActionsSub1
+}
+
+// parseLexActionsSub1 parses a sub-rule of the "lex_actions" parser rule
+func (p *Parser) parseLexActionsSub1() *lexActionsSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### ',' ###
+	commaTok := p.p.MatchTokenOrRollback(token.COMMA, oldPos)
+	if commaTok == nil {
+		return nil
+	}
+
+	// ### lex_action ###
+	lexAction := p.memoParseLexAction()
+	if lexAction == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	return &lexActionsSub1{commaTok: commaTok, lexAction: lexAction}
+}
+
+// *** lex_action ***
+
+func (p *Parser) memoParseLexAction() *ast.LexerAction {
+	pos := p.p.Pos()
+	if memo, ok := p.lexActionMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexAction, _ := memo.Result.(*ast.LexerAction)
+		return lexAction
+	}
+	lexAction := p.ParseLexAction()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexActionMap[pos] = runtime.Memo{Result: lexAction, EndPos: p.p.Pos()}
+	return lexAction
+}
+
+// ParseLexAction parses the "lex_action" parser rule
+func (p *Parser) ParseLexAction() *ast.LexerAction {
+	// ### 'skip' ###
+	if skipActionTok := p.p.TryMatchToken(token.SKIP_ACTION); skipActionTok != nil {
+		return &ast.LexerAction{
+			Span: ast.TokenSpan(p.p.Filename(), skipActionTok, skipActionTok), Type: ast.SkipAction,
+		}
+	}
+
+	// ### 'pushMode' '(' TOKEN_NAME ')' ###
+	if lexActionSub1 := p.memoParseLexActionSub1(); lexActionSub1 != nil {
+		return &ast.LexerAction{
+			Span: ast.TokenSpan(p.p.Filename(), lexActionSub1.pushActionTok, lexActionSub1.rparenTok),
+			Type: ast.PushModeAction, Mode: lexActionSub1.tokenNameTok.Data,
+		}
+	}
+
+	// ### 'popMode' ###
+	if popActionTok := p.p.TryMatchToken(token.POP_ACTION); popActionTok != nil {
+		return &ast.LexerAction{
+			Span: ast.TokenSpan(p.p.Filename(), popActionTok, popActionTok), Type: ast.PopModeAction,
+		}
+	}
+
+	// ### 'channel' '(' TOKEN_NAME ')' ###
+	if lexActionSub2 := p.memoParseLexActionSub2(); lexActionSub2 != nil {
+		return &ast.LexerAction{
+			Span: ast.TokenSpan(p.p.Filename(), lexActionSub2.channelActionTok, lexActionSub2.rparenTok),
+			Type: ast.ChannelAction, Channel: lexActionSub2.tokenNameTok.Data,
+		}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** lex_action - 'pushMode' '(' TOKEN_NAME ')' ***
+
+type lexActionSub1 struct {
+	pushActionTok *runtime.Token
+	lparenTok     *runtime.Token
+	tokenNameTok  *runtime.Token
+	rparenTok     *runtime.Token
+}
+
+func (p *Parser) memoParseLexActionSub1() *lexActionSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexActionSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexActionSub1, _ := memo.Result.(*lexActionSub1)
+		return lexActionSub1
+	}
+	lexActionSub1 := p.parseLexActionSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexActionSub1Map[pos] = runtime.Memo{Result: lexActionSub1, EndPos: p.p.Pos()}
+	return lexActionSub1
+}
+
+// parseLexActionSub1 parses a sub-rule of the "lex_action" parser rule
+func (p *Parser) parseLexActionSub1() *lexActionSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### 'pushMode' ###
+	pushActionTok := p.p.MatchTokenOrRollback(token.PUSH_ACTION, oldPos)
+	if pushActionTok == nil {
+		return nil
+	}
+
+	// ### '(' ###
+	lparenTok := p.p.MatchTokenOrRollback(token.LPAREN, oldPos)
+	if lparenTok == nil {
+		return nil
+	}
+
+	// ### TOKEN_NAME ###
+	tokenNameTok := p.p.MatchTokenOrRollback(token.TOKEN_NAME, oldPos)
+	if tokenNameTok == nil {
+		return nil
+	}
+
+	// ### ')' ###
+	rparenTok := p.p.MatchTokenOrRollback(token.RPAREN, oldPos)
+	if rparenTok == nil {
+		return nil
+	}
+
+	return &lexActionSub1{pushActionTok: pushActionTok, lparenTok: lparenTok, tokenNameTok: tokenNameTok, rparenTok: rparenTok}
+}
+
+// *** lex_action - 'channel' '(' TOKEN_NAME ')' ***
+
+type lexActionSub2 struct {
+	channelActionTok *runtime.Token
+	lparenTok        *runtime.Token
+	tokenNameTok     *runtime.Token
+	rparenTok        *runtime.Token
+}
+
+func (p *Parser) memoParseLexActionSub2() *lexActionSub2 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexActionSub2Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexActionSub2, _ := memo.Result.(*lexActionSub2)
+		return lexActionSub2
+	}
+	lexActionSub2 := p.parseLexActionSub2()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexActionSub2Map[pos] = runtime.Memo{Result: lexActionSub2, EndPos: p.p.Pos()}
+	return lexActionSub2
+}
+
+// parseLexActionSub2 parses a sub-rule of the "lex_action" parser rule
+func (p *Parser) parseLexActionSub2() *lexActionSub2 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### 'channel' ###
+	channelActionTok := p.p.MatchTokenOrRollback(token.CHANNEL_ACTION, oldPos)
+	if channelActionTok == nil {
+		return nil
+	}
+
+	// ### '(' ###
+	lparenTok := p.p.MatchTokenOrRollback(token.LPAREN, oldPos)
+	if lparenTok == nil {
+		return nil
+	}
+
+	// ### TOKEN_NAME ###
+	tokenNameTok := p.p.MatchTokenOrRollback(token.TOKEN_NAME, oldPos)
+	if tokenNameTok == nil {
+		return nil
+	}
+
+	// ### ')' ###
+	rparenTok := p.p.MatchTokenOrRollback(token.RPAREN, oldPos)
+	if rparenTok == nil {
+		return nil
+	}
+
+	return &lexActionSub2{channelActionTok: channelActionTok, lparenTok: lparenTok, tokenNameTok: tokenNameTok, rparenTok: rparenTok}
+}
+
+// *** lex_rule_body ***
+
+func (p *Parser) memoParseLexRuleBody() *ast.LexerAlternatives {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRuleBodyMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRuleBody, _ := memo.Result.(*ast.LexerAlternatives)
+		return lexRuleBody
+	}
+	lexRuleBody := p.ParseLexRuleBody()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRuleBodyMap[pos] = runtime.Memo{Result: lexRuleBody, EndPos: p.p.Pos()}
+	return lexRuleBody
+}
+
+// ParseLexRuleBody parses the "lex_rule_body" parser rule
+func (p *Parser) ParseLexRuleBody() *ast.LexerAlternatives {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### lex_rule_sect+ ###
+	lexRuleSects := []ast.LexerNode{}
+	for {
+		lexRuleSect := p.memoParseLexRuleSect()
+		if lexRuleSect == nil {
+			break
+		}
+		lexRuleSects = append(lexRuleSects, lexRuleSect)
+	}
+	if len(lexRuleSects) == 0 {
+		// Failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ('|' lex_rule_sect+)* ###
+	lexRuleBodySub1s := []*lexRuleBodySub1{}
+	for {
+		lexRuleBodySub1 := p.memoParseLexRuleBodySub1()
+		if lexRuleBodySub1 == nil {
+			break
+		}
+		lexRuleBodySub1s = append(lexRuleBodySub1s, lexRuleBodySub1)
+	}
+
+	lexerNodes := [][]ast.LexerNode{lexRuleSects}
+	for _, node := range lexRuleBodySub1s {
+		lexerNodes = append(lexerNodes, node.lexRuleSects)
+	}
+	return ast.NewLexerAlternatives(lexerNodes)
+}
+
+// *** lex_rule_body - '|' lex_rule_sect+ ***
+
+type lexRuleBodySub1 struct {
+	pipeTok      *runtime.Token
+	lexRuleSects []ast.LexerNode
+}
+
+func (p *Parser) memoParseLexRuleBodySub1() *lexRuleBodySub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRuleBodySub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRuleBodySub1, _ := memo.Result.(*lexRuleBodySub1)
+		return lexRuleBodySub1
+	}
+	lexRuleBodySub1 := p.parseLexRuleBodySub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRuleBodySub1Map[pos] = runtime.Memo{Result: lexRuleBodySub1, EndPos: p.p.Pos()}
+	return lexRuleBodySub1
+}
+
+// parseLexRuleBodySub1 parses a sub-rule of the "lex_rule_body" parser rule
+func (p *Parser) parseLexRuleBodySub1() *lexRuleBodySub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '|' ###
+	pipeTok := p.p.MatchTokenOrRollback(token.PIPE, oldPos)
+	if pipeTok == nil {
+		return nil
+	}
+
+	// ### lex_rule_sect+ ###
+	lexRuleSects := []ast.LexerNode{}
+	for {
+		lexRuleSect := p.memoParseLexRuleSect()
+		if lexRuleSect == nil {
+			break
+		}
+		lexRuleSects = append(lexRuleSects, lexRuleSect)
+	}
+	if len(lexRuleSects) == 0 {
+		// Failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	return &lexRuleBodySub1{pipeTok: pipeTok, lexRuleSects: lexRuleSects}
+}
+
+// *** lex_rule_sect ***
+
+func (p *Parser) memoParseLexRuleSect() ast.LexerNode {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRuleSectMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRuleSect, _ := memo.Result.(ast.LexerNode)
+		return lexRuleSect
+	}
+	lexRuleSect := p.ParseLexRuleSect()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRuleSectMap[pos] = runtime.Memo{Result: lexRuleSect, EndPos: p.p.Pos()}
+	return lexRuleSect
+}
+
+// ParseLexRuleSect parses the "lex_rule_sect" parser rule
+func (p *Parser) ParseLexRuleSect() ast.LexerNode {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '~'? ###
+	tildeTok := p.p.TryMatchToken(token.TILDE)
+
+	// ### lex_rule_part ###
+	lexRulePart := p.memoParseLexRulePart()
+	if lexRulePart == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### (suffix '?'?)? ###
+	lexRuleSectSub1 := p.memoParseLexRuleSectSub1()
+
+	if lexRuleSectSub1 == nil {
+		return ast.NewLexerNestedNode(lexRulePart, tildeTok, nil, nil)
+	}
+	return ast.NewLexerNestedNode(lexRulePart, tildeTok, lexRuleSectSub1.suffix,
+		lexRuleSectSub1.questMarkTok)
+}
+
+// *** lex_rule_sect - suffix '?'? ***
+
+type lexRuleSectSub1 struct {
+	suffix       *runtime.Token
+	questMarkTok *runtime.Token
+}
+
+func (p *Parser) memoParseLexRuleSectSub1() *lexRuleSectSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRuleSectSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRuleSectSub1, _ := memo.Result.(*lexRuleSectSub1)
+		return lexRuleSectSub1
+	}
+	lexRuleSectSub1 := p.parseLexRuleSectSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRuleSectSub1Map[pos] = runtime.Memo{Result: lexRuleSectSub1, EndPos: p.p.Pos()}
+	return lexRuleSectSub1
+}
+
+// parseLexRuleSectSub1 parses a sub-rule of the "lex_rule_sect" parser rule
+func (p *Parser) parseLexRuleSectSub1() *lexRuleSectSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### suffix ###
+	suffix := p.memoParseSuffix()
+	if suffix == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### '?'? ###
+	questMarkTok := p.p.TryMatchToken(token.QUEST_MARK)
+
+	return &lexRuleSectSub1{suffix: suffix, questMarkTok: questMarkTok}
+}
+
+// *** lex_rule_part ***
+
+func (p *Parser) memoParseLexRulePart() ast.LexerNode {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRulePartMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRulePart, _ := memo.Result.(ast.LexerNode)
+		return lexRulePart
+	}
+	lexRulePart := p.ParseLexRulePart()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRulePartMap[pos] = runtime.Memo{Result: lexRulePart, EndPos: p.p.Pos()}
+	return lexRulePart
+}
+
+// ParseLexRulePart parses the "lex_rule_part" parser rule
+func (p *Parser) ParseLexRulePart() ast.LexerNode {
+	// ### '(' lex_rule_body ')' ###
+	if lexRulePartSub1 := p.memoParseLexRulePartSub1(); lexRulePartSub1 != nil {
+		// Copy so the memoized rule body keeps its own span
+		body := *lexRulePartSub1.lexRuleBody
+		body.Span = ast.TokenSpan(p.p.Filename(), lexRulePartSub1.lparenTok, lexRulePartSub1.rparenTok)
+		return &body
+	}
+
+	// ### TOKEN_NAME ###
+	if tokenNameTok := p.p.TryMatchToken(token.TOKEN_NAME); tokenNameTok != nil {
+		return &ast.LexerRuleRef{
+			Span: ast.TokenSpan(p.p.Filename(), tokenNameTok, tokenNameTok), Name: tokenNameTok.Data,
+		}
+	}
+
+	// ### TOKEN_LIT ###
+	if tokenLitTok := p.p.TryMatchToken(token.TOKEN_LIT); tokenLitTok != nil {
+		return &ast.LexerToken{
+			Span: ast.TokenSpan(p.p.Filename(), tokenLitTok, tokenLitTok), Token: tokenLitTok,
+		}
+	}
+
+	// ### '.' ###
+	if dotTok := p.p.TryMatchToken(token.DOT); dotTok != nil {
+		return &ast.LexerAnyChar{Span: ast.TokenSpan(p.p.Filename(), dotTok, dotTok)}
+	}
+
+	// ### char_set ###
+	if charSet := p.memoParseCharSet(); charSet != nil {
+		return charSet
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** lex_rule_part - '(' lex_rule_body ')' ***
+
+type lexRulePartSub1 struct {
+	lparenTok   *runtime.Token
+	lexRuleBody *ast.LexerAlternatives
+	rparenTok   *runtime.Token
+}
+
+func (p *Parser) memoParseLexRulePartSub1() *lexRulePartSub1 {
+	pos := p.p.Pos()
+	if memo, ok := p.lexRulePartSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		lexRulePartSub1, _ := memo.Result.(*lexRulePartSub1)
+		return lexRulePartSub1
+	}
+	lexRulePartSub1 := p.parseLexRulePartSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.lexRulePartSub1Map[pos] = runtime.Memo{Result: lexRulePartSub1, EndPos: p.p.Pos()}
+	return lexRulePartSub1
+}
+
+// parseLexRulePartSub1 parses a sub-rule of the "lex_rule_part" parser rule
+func (p *Parser) parseLexRulePartSub1() *lexRulePartSub1 {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '(' ###
+	lparenTok := p.p.MatchTokenOrRollback(token.LPAREN, oldPos)
+	if lparenTok == nil {
+		return nil
+	}
+
+	// ### lex_rule_body ###
+	lexRuleBody := p.memoParseLexRuleBody()
+	if lexRuleBody == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ')' ###
+	rparenTok := p.p.MatchTokenOrRollback(token.RPAREN, oldPos)
+	if rparenTok == nil {
+		return nil
+	}
+
+	return &lexRulePartSub1{lparenTok: lparenTok, lexRuleBody: lexRuleBody, rparenTok: rparenTok}
+}
+
+// *** char_set ***
+
+func (p *Parser) memoParseCharSet() *ast.LexerCharClass {
+	pos := p.p.Pos()
+	if memo, ok := p.charSetMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		charSet, _ := memo.Result.(*ast.LexerCharClass)
+		return charSet
+	}
+	charSet := p.ParseCharSet()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.charSetMap[pos] = runtime.Memo{Result: charSet, EndPos: p.p.Pos()}
+	return charSet
+}
+
+// ParseCharSet parses the "char_set" parser rule
+func (p *Parser) ParseCharSet() *ast.LexerCharClass {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### '[' ###
+	lbrackTok := p.p.MatchTokenOrRollback(token.LBRACK, oldPos)
+	if lbrackTok == nil {
+		return nil
+	}
+
+	// ### (char_range | char_lit)+ ###
+	charSetSub1s := [][]*runtime.Token{}
+	for {
+		charSetSub1 := p.memoParseCharSetSub1()
+		if charSetSub1 == nil {
+			break
+		}
+		charSetSub1s = append(charSetSub1s, charSetSub1)
+	}
+	if len(charSetSub1s) == 0 {
+		// Failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### ']' ###
+	rbrackTok := p.p.MatchTokenOrRollback(token.RBRACK, oldPos)
+	if rbrackTok == nil {
+		return nil
+	}
+
+	class := ast.NewLexerCharClass(charSetSub1s)
+	class.Span = ast.TokenSpan(p.p.Filename(), lbrackTok, rbrackTok)
+	return class
+}
+
+// *** char_set - char_range | char_lit ***
+
+func (p *Parser) memoParseCharSetSub1() []*runtime.Token {
+	pos := p.p.Pos()
+	if memo, ok := p.charSetSub1Map[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		charSetSub1, _ := memo.Result.([]*runtime.Token)
+		return charSetSub1
+	}
+	charSetSub1 := p.parseCharSetSub1()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.charSetSub1Map[pos] = runtime.Memo{Result: charSetSub1, EndPos: p.p.Pos()}
+	return charSetSub1
+}
+
+// parseCharSetSub1 parses a sub-rule of the "char_set" parser rule
+func (p *Parser) parseCharSetSub1() []*runtime.Token {
+	// ### char_range ###
+	if charRange := p.memoParseCharRange(); charRange != nil {
+		return charRange
+	}
+
+	// ### char_lit ###
+	if charLit := p.memoParseCharLit(); charLit != nil {
+		return []*runtime.Token{charLit}
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** char_lit ***
+
+func (p *Parser) memoParseCharLit() *runtime.Token {
+	pos := p.p.Pos()
+	if memo, ok := p.charLitMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		charLit, _ := memo.Result.(*runtime.Token)
+		return charLit
+	}
+	charLit := p.ParseCharLit()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.charLitMap[pos] = runtime.Memo{Result: charLit, EndPos: p.p.Pos()}
+	return charLit
+}
+
+// ParseCharLit parses the "char_lit" parser rule
+func (p *Parser) ParseCharLit() *runtime.Token {
+	// ### UNICODE_ESCAPE_CHAR ###
+	if unicodeEscapeCharTok := p.p.TryMatchToken(token.UNICODE_ESCAPE_CHAR); unicodeEscapeCharTok != nil {
+		return unicodeEscapeCharTok
+	}
+
+	// ### ESCAPE_CHAR ###
+	if escapeCharTok := p.p.TryMatchToken(token.ESCAPE_CHAR); escapeCharTok != nil {
+		return escapeCharTok
+	}
+
+	// ### BASIC_CHAR ###
+	if basicCharTok := p.p.TryMatchToken(token.BASIC_CHAR); basicCharTok != nil {
+		return basicCharTok
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** char_range ***
+
+func (p *Parser) memoParseCharRange() []*runtime.Token {
+	pos := p.p.Pos()
+	if memo, ok := p.charRangeMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		charRange, _ := memo.Result.([]*runtime.Token)
+		return charRange
+	}
+	charRange := p.ParseCharRange()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.charRangeMap[pos] = runtime.Memo{Result: charRange, EndPos: p.p.Pos()}
+	return charRange
+}
+
+// ParseCharRange parses the "char_range" parser rule
+func (p *Parser) ParseCharRange() []*runtime.Token {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### char_lit ###
+	charLit := p.memoParseCharLit()
+	if charLit == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
+		return nil
+	}
+
+	// ### '-' ###
+	dashTok := p.p.MatchTokenOrRollback(token.DASH, oldPos)
+	if dashTok == nil {
+		return nil
+	}
+
+	// ### char_lit ###
+	charLit2 := p.memoParseCharLit()
+	if charLit2 == nil {
+		// Rule failed - rollback
+		p.p.SetPos(oldPos)
 		return nil
 	}
 
-	return questMarkTok
+	return []*runtime.Token{charLit, charLit2}
 }
@@ -3,6 +3,7 @@ package parser_test
 import (
 	"testing"
 
+	"github.com/nu11ptr/parsegen/pkg/ast"
 	"github.com/nu11ptr/parsegen/pkg/parser"
 	"github.com/nu11ptr/parsegen/pkg/token"
 	runtime "github.com/nu11ptr/parsegen/runtime/go"
@@ -107,3 +108,289 @@ func TestParser(t *testing.T) {
 	require.NotNil(t, ast)
 	assert.Equal(t, expected, ast.String())
 }
+
+const (
+	lexerGrammar = `fragment HEX_DIGIT: [A-Fa-f0-9];
+
+UNICODE_ESCAPE_CHAR: '\\u' (HEX_DIGIT+ | '{' HEX_DIGIT+ '}');
+
+ESCAPE_CHAR: '\\' .;
+
+BASIC_CHAR: ~[\]\\\-];
+
+COMMENT: '//' ~[\r\n]* -> skip;
+
+LBRACK: '[' -> pushMode(CHAR_CLASS), popMode, channel(HIDDEN);
+
+mode CHAR_CLASS;
+
+DASH: '-';
+`
+
+	lexerExpected = `TopLevel:
+   └──Fragment LexerRule: HEX_DIGIT
+      └──Alternatives:
+         └──Alternative 0:
+            └──CharClass: [0-9A-Fa-f]
+   └──LexerRule: UNICODE_ESCAPE_CHAR
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '\\u'
+            └──Alternatives:
+               └──Alternative 0:
+                  └──OneOrMore:
+                     └──LexerRuleRef: HEX_DIGIT
+               └──Alternative 1:
+                  └──Token Literal:
+                     └──Data: '{'
+                  └──OneOrMore:
+                     └──LexerRuleRef: HEX_DIGIT
+                  └──Token Literal:
+                     └──Data: '}'
+   └──LexerRule: ESCAPE_CHAR
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '\\'
+            └──AnyChar
+   └──LexerRule: BASIC_CHAR
+      └──Alternatives:
+         └──Alternative 0:
+            └──CharClass: ~[\-\\\]]
+   └──LexerRule: COMMENT
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '//'
+            └──ZeroOrMore:
+               └──CharClass: ~[\n\r]
+      └──Actions:
+         └──Skip
+   └──LexerRule: LBRACK
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '['
+      └──Actions:
+         └──PushMode: CHAR_CLASS
+         └──PopMode
+         └──Channel: HIDDEN
+   └──LexerRule: DASH
+      └──Mode: CHAR_CLASS
+      └──Alternatives:
+         └──Alternative 0:
+            └──Token Literal:
+               └──Data: '-'
+`
+)
+
+func TestParserLexerRules(t *testing.T) {
+	lex := runtime.NewLexerFromString(lexerGrammar)
+	parsegen := parser.New(runtime.NewParser(token.New(lex)))
+
+	ast := parsegen.ParseTopLevel()
+	require.NotNil(t, ast)
+	assert.Equal(t, lexerExpected, ast.String())
+	assert.Len(t, ast.LexerRulesMap, 7)
+	assert.Empty(t, ast.ParserRules)
+	assert.Equal(t, []string{"CHAR_CLASS"}, ast.Modes)
+}
+
+const (
+	headerGrammar = `parser grammar expr;
+
+options {
+	tokenVocab = expr_lexer;
+	superClass = base.Parser;
+}
+
+options { language = 'Go'; }
+
+import common, Literals;
+
+expr: NUM;
+`
+
+	headerExpected = `TopLevel:
+   └──parser grammar: expr
+   └──Option: tokenVocab = expr_lexer
+   └──Option: superClass = base.Parser
+   └──Option: language = 'Go'
+   └──Import: common
+   └──Import: Literals
+   └──ParserRule: expr
+      └──Alternatives:
+         └──Alternative 0:
+            └──LexerRuleRef: NUM
+`
+)
+
+func TestParserHeader(t *testing.T) {
+	top, err := parser.New(runtime.NewParser(token.New(runtime.NewLexerFromString(headerGrammar)))).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, headerExpected, top.String())
+	assert.Equal(t, ast.ParserGrammar, top.Type())
+	assert.Equal(t, "expr", top.Header.Name)
+
+	vocab, ok := top.Option("tokenVocab")
+	assert.True(t, ok)
+	assert.Equal(t, "expr_lexer", vocab)
+	_, ok = top.Option("tokenvocab")
+	assert.False(t, ok)
+
+	for grammar, type_ := range map[string]ast.GrammarType{
+		"grammar calc;":     ast.CombinedGrammar,
+		"lexer grammar L;":  ast.LexerGrammar,
+		"a: B;":             ast.CombinedGrammar,
+		"options {} a: B;":  ast.CombinedGrammar,
+		"parser grammar P;": ast.ParserGrammar,
+	} {
+		top, err := parser.New(runtime.NewParser(token.New(runtime.NewLexerFromString(grammar)))).Parse()
+		require.NoError(t, err, grammar)
+		assert.Equal(t, type_, top.Type(), grammar)
+	}
+}
+
+func TestParserSyntaxError(t *testing.T) {
+	tests := []struct {
+		name, grammar, err string
+	}{
+		{
+			name:    "missing semi",
+			grammar: "a: b\n\nc: FOO;",
+			err:     `test.g4:3:2: expected '=', '+=', '+', '*', '?', '&', '!', RULE_NAME, TOKEN_NAME, '(', TOKEN_LIT, '#', '|' or ';', found ':'`,
+		},
+		{
+			name:    "bad rule name",
+			grammar: "a: b;\nFOO BAR;",
+			err:     `test.g4:2:5: expected ':', found TOKEN_NAME "BAR"`,
+		},
+		{
+			name:    "illegal char",
+			grammar: "a: $;",
+			err:     `test.g4:1:4: expected '&', '!', RULE_NAME, TOKEN_NAME, '(' or TOKEN_LIT, found ILLEGAL "$" (unexpected char)`,
+		},
+		{
+			name:    "unterminated literal",
+			grammar: "a: 'b;\n",
+			err:     `test.g4:1:4: expected '&', '!', RULE_NAME, TOKEN_NAME, '(' or TOKEN_LIT, found ILLEGAL "'b;\n" (unterminated token)`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := runtime.NewParser(token.New(runtime.NewLexerFromString(test.grammar)))
+			p.SetFilename("test.g4")
+			top, err := parser.New(p).Parse()
+			assert.Nil(t, top)
+			require.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
+
+const (
+	labelGrammar = `expr
+	: lhs=expr op=('+' | '-') rhs=term # Binary
+	| args+=term (',' args+=term)*     # List
+	;
+`
+
+	labelExpected = `TopLevel:
+   └──ParserRule: expr
+      └──Alternatives:
+         └──Alternative 0: # Binary
+            └──Label: lhs=
+               └──ParserRuleRef: expr
+            └──Label: op=
+               └──Alternatives:
+                  └──Alternative 0:
+                     └──Token Literal:
+                        └──Data: '+'
+                  └──Alternative 1:
+                     └──Token Literal:
+                        └──Data: '-'
+            └──Label: rhs=
+               └──ParserRuleRef: term
+         └──Alternative 1: # List
+            └──Label: args+=
+               └──ParserRuleRef: term
+            └──ZeroOrMore:
+               └──Alternatives:
+                  └──Alternative 0:
+                     └──Token Literal:
+                        └──Data: ','
+                     └──Label: args+=
+                        └──ParserRuleRef: term
+`
+)
+
+func TestParserLabels(t *testing.T) {
+	p := runtime.NewParser(token.New(runtime.NewLexerFromString(labelGrammar)))
+	p.SetFilename("test.g4")
+	top, err := parser.New(p).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, labelExpected, top.String())
+
+	alts := top.ParserRulesMap["expr"].Rules
+	assert.Equal(t, "Binary", alts.Label(0))
+	assert.Equal(t, "test.g4:2:37-2:44", alts.Labels[0].String())
+	label := alts.Rules[0][0].(*ast.ParserLabel)
+	assert.Equal(t, "test.g4:2:4-2:11", label.Span.String())
+	assert.False(t, label.List)
+	assert.True(t, alts.Rules[1][0].(*ast.ParserLabel).List)
+
+	// Nested alternatives without labels have no label slice
+	nested := alts.Rules[1][1].(*ast.ParserZeroOrMore).Node.(*ast.ParserAlternatives)
+	assert.Nil(t, nested.Labels)
+	assert.Equal(t, "", nested.Label(0))
+}
+
+func TestParserPredicates(t *testing.T) {
+	p := runtime.NewParser(token.New(runtime.NewLexerFromString("ident: !KEYWORD NAME &'(';")))
+	p.SetFilename("test.g4")
+	top, err := parser.New(p).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, `TopLevel:
+   └──ParserRule: ident
+      └──Alternatives:
+         └──Alternative 0:
+            └──NotPredicate:
+               └──LexerRuleRef: KEYWORD
+            └──LexerRuleRef: NAME
+            └──AndPredicate:
+               └──Token Literal:
+                  └──Data: '('
+`, top.String())
+
+	alt := top.ParserRulesMap["ident"].Rules.Rules[0]
+	assert.Equal(t, "test.g4:1:8-1:15", alt[0].NodeSpan().String())
+	assert.Equal(t, "test.g4:1:22-1:25", alt[2].NodeSpan().String())
+}
+
+func TestParserNonGreedy(t *testing.T) {
+	p := runtime.NewParser(token.New(runtime.NewLexerFromString("A: .*? [a-z]+? ~'x'?? '?';")))
+	p.SetFilename("test.g4")
+	top, err := parser.New(p).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, `TopLevel:
+   └──LexerRule: A
+      └──Alternatives:
+         └──Alternative 0:
+            └──NonGreedyZeroOrMore:
+               └──AnyChar
+            └──NonGreedyOneOrMore:
+               └──CharClass: [a-z]
+            └──NonGreedyZeroOrOne:
+               └──Not:
+                  └──Token Literal:
+                     └──Data: 'x'
+            └──Token Literal:
+               └──Data: '?'
+`, top.String())
+
+	alt := top.LexerRulesMap["A"].Rules.Rules[0]
+	assert.Equal(t, "test.g4:1:4-1:6", alt[0].NodeSpan().String())
+	assert.Equal(t, "test.g4:1:16-1:21", alt[2].NodeSpan().String())
+}
@@ -1,3 +1,5 @@
+// Code generated by parsegen from pg_parser.g4. DO NOT EDIT.
+
 package pgparser
 
 import (
@@ -6,38 +8,53 @@ import (
 	runtime "github.com/nu11ptr/parsegen/runtime/go"
 )
 
-// *** Below will be automatically generated
-
+// Parser is a packrat parser that memoizes the result of each rule
 type Parser struct {
 	p *runtime.Parser
 
-	bodyMap       map[int]*ast.Body
-	parserDecl    map[int]*string
-	codeBlocksMap map[int]*ast.CodeBlocks
-	codeBlockMap  map[int]*ast.CodeBlock
+	bodyMap             map[int]runtime.Memo
+	parserDeclMap       map[int]runtime.Memo
+	codeBlocksMap       map[int]runtime.Memo
+	codeBlockMap        map[int]runtime.Memo
+	codeBlockRuleMap    map[int]runtime.Memo
+	codeBlockMembersMap map[int]runtime.Memo
 }
 
+// New creates a new parser that reads tokens from the given runtime parser
 func New(p *runtime.Parser) *Parser {
 	return &Parser{
-		p:             p,
-		bodyMap:       make(map[int]*ast.Body, 8),
-		parserDecl:    make(map[int]*string, 8),
-		codeBlocksMap: make(map[int]*ast.CodeBlocks, 8),
-		codeBlockMap:  make(map[int]*ast.CodeBlock, 8),
+		p:                   p,
+		bodyMap:             make(map[int]runtime.Memo, 8),
+		parserDeclMap:       make(map[int]runtime.Memo, 8),
+		codeBlocksMap:       make(map[int]runtime.Memo, 8),
+		codeBlockMap:        make(map[int]runtime.Memo, 8),
+		codeBlockRuleMap:    make(map[int]runtime.Memo, 8),
+		codeBlockMembersMap: make(map[int]runtime.Memo, 8),
+	}
+}
+
+// Parse parses the input starting from the "body" parser rule. If
+// parsing fails, the error describes the farthest point reached
+func (p *Parser) Parse() (*ast.Body, error) {
+	body := p.ParseBody()
+	if body == nil {
+		return nil, p.p.Err()
 	}
+	return body, nil
 }
 
 // *** body ***
 
 func (p *Parser) memoParseBody() *ast.Body {
 	pos := p.p.Pos()
-	body, ok := p.bodyMap[pos]
-	if ok {
+	if memo, ok := p.bodyMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		body, _ := memo.Result.(*ast.Body)
 		return body
 	}
-	body = p.ParseBody()
+	body := p.ParseBody()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.bodyMap[pos] = body
+	p.bodyMap[pos] = runtime.Memo{Result: body, EndPos: p.p.Pos()}
 	return body
 }
 
@@ -68,20 +85,23 @@ func (p *Parser) ParseBody() *ast.Body {
 		return nil
 	}
 
-	return ast.NewBody(*parserDecl, codeBlocks)
+	body := ast.NewBody(*parserDecl, codeBlocks)
+	body.Span = ast.FileSpan(p.p.Filename(), eofTok)
+	return body
 }
 
 // *** parser_decl ***
 
 func (p *Parser) memoParseParserDecl() *string {
 	pos := p.p.Pos()
-	parserDecl, ok := p.parserDecl[pos]
-	if ok {
+	if memo, ok := p.parserDeclMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		parserDecl, _ := memo.Result.(*string)
 		return parserDecl
 	}
-	parserDecl = p.ParseParserDecl()
+	parserDecl := p.ParseParserDecl()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.parserDecl[pos] = parserDecl
+	p.parserDeclMap[pos] = runtime.Memo{Result: parserDecl, EndPos: p.p.Pos()}
 	return parserDecl
 }
 
@@ -115,13 +135,14 @@ func (p *Parser) ParseParserDecl() *string {
 
 func (p *Parser) memoParseCodeBlocks() *ast.CodeBlocks {
 	pos := p.p.Pos()
-	codeBlocks, ok := p.codeBlocksMap[pos]
-	if ok {
+	if memo, ok := p.codeBlocksMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		codeBlocks, _ := memo.Result.(*ast.CodeBlocks)
 		return codeBlocks
 	}
-	codeBlocks = p.ParseCodeBlocks()
+	codeBlocks := p.ParseCodeBlocks()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.codeBlocksMap[pos] = codeBlocks
+	p.codeBlocksMap[pos] = runtime.Memo{Result: codeBlocks, EndPos: p.p.Pos()}
 	return codeBlocks
 }
 
@@ -176,25 +197,59 @@ func (p *Parser) ParseCodeBlocks() *ast.CodeBlocks {
 		return nil
 	}
 
-	return ast.NewCodeBlocks(stringTok.Data, codeBlocks)
+	blocks := ast.NewCodeBlocks(stringTok.Data, codeBlocks)
+	blocks.Span = ast.TokenSpan(p.p.Filename(), codeTok, rbraceTok)
+	return blocks
 }
 
 // *** code_block ***
 
 func (p *Parser) memoParseCodeBlock() *ast.CodeBlock {
 	pos := p.p.Pos()
-	codeBlock, ok := p.codeBlockMap[pos]
-	if ok {
+	if memo, ok := p.codeBlockMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		codeBlock, _ := memo.Result.(*ast.CodeBlock)
 		return codeBlock
 	}
-	codeBlock = p.ParseCodeBlock()
+	codeBlock := p.ParseCodeBlock()
 	// Memoize what we did here in case this exact rule/position is needed again
-	p.codeBlockMap[pos] = codeBlock
+	p.codeBlockMap[pos] = runtime.Memo{Result: codeBlock, EndPos: p.p.Pos()}
 	return codeBlock
 }
 
 // ParseCodeBlock parses the "code_block" parser rule
 func (p *Parser) ParseCodeBlock() *ast.CodeBlock {
+	// ### RULE_NAME TYPE? CODE_BLOCK ###
+	if codeBlockRule := p.memoParseCodeBlockRule(); codeBlockRule != nil {
+		return codeBlockRule
+	}
+
+	// ### 'members' CODE_BLOCK ###
+	if codeBlockMembers := p.memoParseCodeBlockMembers(); codeBlockMembers != nil {
+		return codeBlockMembers
+	}
+
+	// No alternative matched
+	return nil
+}
+
+// *** code_block - RULE_NAME TYPE? CODE_BLOCK ***
+
+func (p *Parser) memoParseCodeBlockRule() *ast.CodeBlock {
+	pos := p.p.Pos()
+	if memo, ok := p.codeBlockRuleMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		codeBlockRule, _ := memo.Result.(*ast.CodeBlock)
+		return codeBlockRule
+	}
+	codeBlockRule := p.parseCodeBlockRule()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.codeBlockRuleMap[pos] = runtime.Memo{Result: codeBlockRule, EndPos: p.p.Pos()}
+	return codeBlockRule
+}
+
+// parseCodeBlockRule parses a sub-rule of the "code_block" parser rule
+func (p *Parser) parseCodeBlockRule() *ast.CodeBlock {
 	// Rule can fail - might need to rollback
 	oldPos := p.p.Pos()
 
@@ -213,5 +268,44 @@ func (p *Parser) ParseCodeBlock() *ast.CodeBlock {
 		return nil
 	}
 
-	return ast.NewCodeBlock(ruleNameTok.Data, typeTok, codeBlockTok.Data)
+	block := ast.NewCodeBlock(ruleNameTok.Data, typeTok, codeBlockTok.Data)
+	block.Span = ast.TokenSpan(p.p.Filename(), ruleNameTok, codeBlockTok)
+	return block
+}
+
+// *** code_block - 'members' CODE_BLOCK ***
+
+func (p *Parser) memoParseCodeBlockMembers() *ast.CodeBlock {
+	pos := p.p.Pos()
+	if memo, ok := p.codeBlockMembersMap[pos]; ok {
+		p.p.SetPos(memo.EndPos)
+		codeBlockMembers, _ := memo.Result.(*ast.CodeBlock)
+		return codeBlockMembers
+	}
+	codeBlockMembers := p.parseCodeBlockMembers()
+	// Memoize what we did here in case this exact rule/position is needed again
+	p.codeBlockMembersMap[pos] = runtime.Memo{Result: codeBlockMembers, EndPos: p.p.Pos()}
+	return codeBlockMembers
+}
+
+// parseCodeBlockMembers parses a sub-rule of the "code_block" parser rule
+func (p *Parser) parseCodeBlockMembers() *ast.CodeBlock {
+	// Rule can fail - might need to rollback
+	oldPos := p.p.Pos()
+
+	// ### 'members' ###
+	membersTok := p.p.MatchTokenOrRollback(pgtoken.MEMBERS, oldPos)
+	if membersTok == nil {
+		return nil
+	}
+
+	// ### CODE_BLOCK ###
+	codeBlockTok := p.p.MatchTokenOrRollback(pgtoken.CODE_BLOCK, oldPos)
+	if codeBlockTok == nil {
+		return nil
+	}
+
+	block := ast.NewMembersBlock(codeBlockTok.Data)
+	block.Span = ast.TokenSpan(p.p.Filename(), membersTok, codeBlockTok)
+	return block
 }
@@ -72,3 +72,51 @@ func TestParser(t *testing.T) {
 	require.NotNil(t, ast)
 	assert.Equal(t, expected, ast.String())
 }
+
+const (
+	hooksGrammar = `parser = 'expr.g4'
+
+code('go') {
+    members {{ depth int }}
+
+    expr.init {{ p.depth++ }}
+
+    expr.after {{ p.depth-- }}
+
+    expr.Call.pred {{ p.depth < 10 }}
+}
+`
+
+	hooksExpected = `Body:
+   └──Parser: expr.g4
+   └──Code Blocks:
+      └──Language: go
+      └──Code Block:
+         └──Kind: members
+         └──Code: {{ depth int }}
+      └──Code Block:
+         └──Kind: init
+         └──Rule: expr
+         └──Code: {{ p.depth++ }}
+      └──Code Block:
+         └──Kind: after
+         └──Rule: expr
+         └──Code: {{ p.depth-- }}
+      └──Code Block:
+         └──Kind: pred
+         └──Rule: expr.Call
+         └──Code: {{ p.depth < 10 }}
+`
+)
+
+func TestParserHooks(t *testing.T) {
+	body, err := pgparser.New(runtime.NewParser(pgtoken.New(runtime.NewLexerFromString(hooksGrammar)))).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, hooksExpected, body.String())
+
+	names := []string{}
+	for _, block := range body.CodeBlocks.Blocks {
+		names = append(names, block.Name())
+	}
+	assert.Equal(t, []string{"members", "expr.init", "expr.after", "expr.Call.pred"}, names)
+}
@@ -1,23 +1,19 @@
-package pgtoken
+// Code generated by parsegen. DO NOT EDIT.
 
-import runtime "github.com/nu11ptr/parsegen/runtime/go"
+package pgtoken
 
-// *** Potentially Generated ***
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+)
 
 const (
-	// Char set
 	RULE_NAME runtime.TokenType = iota + runtime.EOF + 1
-
-	// Sequences
 	STRING
 	TYPE
 	CODE_BLOCK
-
-	// Keywords
 	PARSER
 	CODE
-
-	// Basic Sequences
+	MEMBERS
 	EQUALS
 	LBRACE
 	RBRACE
@@ -25,149 +21,288 @@ const (
 	RPAREN
 )
 
-var (
-	keywords = map[string]runtime.TokenType{
-		"parser": PARSER,
-		"code":   CODE,
-	}
-)
+var tokenNames = map[runtime.TokenType]string{
+	RULE_NAME:  "RULE_NAME",
+	STRING:     "STRING",
+	TYPE:       "TYPE",
+	CODE_BLOCK: "CODE_BLOCK",
+	PARSER:     "'parser'",
+	CODE:       "'code'",
+	MEMBERS:    "'members'",
+	EQUALS:     "'='",
+	LBRACE:     "'{'",
+	RBRACE:     "'}'",
+	LPAREN:     "'('",
+	RPAREN:     "')'",
+}
+
+var keywords = map[string]runtime.TokenType{
+	"parser":  PARSER,
+	"code":    CODE,
+	"members": MEMBERS,
+}
 
+// Tokenizer splits its input into tokens by taking the longest match of
+// any lexer rule at each position
 type Tokenizer struct {
-	lex *runtime.Lexer
+	lex      *runtime.Lexer
+	matchers []func() bool
 }
 
+// New creates a new tokenizer that reads characters from the given lexer
 func New(lex *runtime.Lexer) *Tokenizer {
-	return &Tokenizer{lex: lex}
+	t := &Tokenizer{lex: lex}
+	t.matchers = []func() bool{
+		t.matchRuleName,
+		t.matchString,
+		t.matchType,
+		t.matchCodeBlock,
+		t.matchComment,
+		t.matchMlComment,
+		t.matchWs,
+		t.matchEquals,
+		t.matchLbrace,
+		t.matchRbrace,
+		t.matchLparen,
+		t.matchRparen,
+	}
+	return t
+}
+
+// TokenName returns the name of a token type for use in syntax errors
+func (t *Tokenizer) TokenName(tt runtime.TokenType) string {
+	return tokenNames[tt]
 }
 
-func (t *Tokenizer) processRuleName(tok *runtime.Token) bool {
+// NextToken matches the next token in the input, discarding any skipped
+func (t *Tokenizer) NextToken(tok *runtime.Token) {
+	for {
+		switch t.lex.LongestMatch(t.matchers) {
+		case 0: // RULE_NAME
+			t.lex.BuildTokenData(RULE_NAME, tok)
+			if tt, ok := keywords[tok.Data]; ok {
+				tok.Type, tok.Data = tt, ""
+			}
+		case 1: // STRING
+			t.lex.BuildTokenData(STRING, tok)
+		case 2: // TYPE
+			t.lex.BuildTokenData(TYPE, tok)
+		case 3: // CODE_BLOCK
+			t.lex.BuildTokenData(CODE_BLOCK, tok)
+		case 7: // EQUALS
+			t.lex.BuildToken(EQUALS, tok)
+		case 8: // LBRACE
+			t.lex.BuildToken(LBRACE, tok)
+		case 9: // RBRACE
+			t.lex.BuildToken(RBRACE, tok)
+		case 10: // LPAREN
+			t.lex.BuildToken(LPAREN, tok)
+		case 11: // RPAREN
+			t.lex.BuildToken(RPAREN, tok)
+		case 4, 5, 6: // COMMENT, ML_COMMENT, WS
+			t.lex.DiscardTokenData()
+			continue
+		default:
+			if t.lex.CurrChar() == runtime.EOFChar {
+				t.lex.BuildToken(runtime.EOF, tok)
+			} else {
+				t.lex.BuildIllegalToken(tok)
+			}
+		}
+		return
+	}
+}
+
+// *** RULE_NAME ***
+
+// matchRuleName matches the RULE_NAME lexer rule
+func (t *Tokenizer) matchRuleName() bool {
 	// [a-z]
 	if !t.lex.MatchCharInRange('a', 'z') {
 		return false
 	}
 
-	// [A-Za-z0-9_]*
-	for t.lex.MatchCharInRange('A', 'Z') || t.lex.MatchCharInRange('a', 'z') ||
-		t.lex.MatchCharInRange('0', '9') || t.lex.MatchChar('_') || t.lex.MatchChar('.') {
+	// [.0-9A-Z_a-z]*
+	for t.lex.MatchCharInRange('0', '9') ||
+		t.lex.MatchCharInRange('A', 'Z') ||
+		t.lex.MatchCharInRange('a', 'z') ||
+		t.lex.MatchCharInSeq("._") {
 	}
 
-	t.lex.BuildTokenData(RULE_NAME, tok)
+	return true
+}
+
+// *** STRING ***
 
-	// Possible conflicting keyword
-	tt, ok := keywords[tok.Data]
-	if ok {
-		tok.Type = tt
-		tok.Data = ""
+// matchString matches the STRING lexer rule
+func (t *Tokenizer) matchString() bool {
+	pos := t.lex.Pos()
+
+	// '\''
+	if !t.lex.MatchChar('\'') {
+		return false
+	}
+
+	// ('\\\'' | ~'\'')+
+	if !t.matchStringSub1() {
+		t.lex.SetPos(pos)
+		return false
+	}
+	for t.matchStringSub1() {
+	}
+
+	// '\''
+	if !t.lex.MatchChar('\'') {
+		t.lex.SetPos(pos)
+		return false
 	}
 
 	return true
 }
 
-func (t *Tokenizer) NextToken(tok *runtime.Token) {
-	// Skip
-	skipping := true
-	for skipping {
-		switch t.lex.CurrChar() {
-		case '/':
-			switch t.lex.NextChar() {
-			// '//'
-			case '/':
-				t.lex.NextChar()
-
-				// ~[\r\n]*
-				for t.lex.MatchCharExceptInSeq("\r\n") {
-				}
-				t.lex.DiscardTokenData()
-			// '/*'
-			case '*':
-				t.lex.NextChar()
-				t.lex.MatchUntilSeq("*/")
-				t.lex.MatchSeq("*/")
-				t.lex.DiscardTokenData()
-			default:
-				t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-				return
-			}
-		// [ \t\r\n\f]+
-		case ' ', '\t', '\r', '\n', '\f':
-			t.lex.NextChar()
+// matchStringSub1 matches part of the STRING lexer rule
+func (t *Tokenizer) matchStringSub1() bool {
+	// '\\\'' | ~'\''
+	return t.lex.MatchSeq("\\'") || t.lex.MatchCharExcept('\'')
+}
 
-			for t.lex.MatchCharInSeq(" \t\r\n\f") {
-			}
-			t.lex.DiscardTokenData()
-		default:
-			skipping = false
-		}
+// *** TYPE ***
+
+// matchType matches the TYPE lexer rule
+func (t *Tokenizer) matchType() bool {
+	pos := t.lex.Pos()
+
+	// '->'
+	if !t.lex.MatchSeq("->") {
+		return false
 	}
 
-	if t.processRuleName(tok) {
-		return
+	// ~'{{'+
+	if !t.lex.MatchCharUnlessSeq("{{") {
+		t.lex.SetPos(pos)
+		return false
+	}
+	for t.lex.MatchCharUnlessSeq("{{") {
 	}
 
-	switch t.lex.CurrChar() {
-	case '\'':
-		t.lex.NextChar()
+	return true
+}
 
-		// ('\\\'' | ~'\'')+
-		matched := false
-		for t.lex.MatchSeq("\\'") || t.lex.MatchCharExcept('\'') {
-			matched = true
-		}
-		if !matched {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
-		}
+// *** CODE_BLOCK ***
 
-		// '\''
-		if !t.lex.MatchChar('\'') {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
-		}
+// matchCodeBlock matches the CODE_BLOCK lexer rule
+func (t *Tokenizer) matchCodeBlock() bool {
+	pos := t.lex.Pos()
 
-		t.lex.BuildTokenData(STRING, tok)
-	case '-':
-		t.lex.NextChar()
+	// '{{'
+	if !t.lex.MatchSeq("{{") {
+		return false
+	}
 
-		// '->'
-		if !t.lex.MatchChar('>') {
-			t.lex.BuildTokenData(runtime.ILLEGAL, tok)
-			return
-		}
+	// ~'}}'+
+	if !t.lex.MatchCharUnlessSeq("}}") {
+		t.lex.SetPos(pos)
+		return false
+	}
+	for t.lex.MatchCharUnlessSeq("}}") {
+	}
 
-		// ~'{{'+
-		if !t.lex.MatchUntilSeq("{{") {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
-		}
+	// '}}'
+	if !t.lex.MatchSeq("}}") {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
 
-		t.lex.BuildTokenData(TYPE, tok)
-	case '=':
-		t.lex.BuildTokenNext(EQUALS, tok)
-	case '{':
-		t.lex.NextChar()
+// *** COMMENT ***
 
-		if !t.lex.MatchChar('{') {
-			t.lex.BuildToken(LBRACE, tok)
-			return
-		}
+// matchComment matches the COMMENT lexer rule
+func (t *Tokenizer) matchComment() bool {
+	// '//'
+	if !t.lex.MatchSeq("//") {
+		return false
+	}
+
+	// ~[\n\r]*
+	for t.lex.MatchCharExceptInSeq("\n\r") {
+	}
+
+	return true
+}
+
+// *** ML_COMMENT ***
+
+// matchMlComment matches the ML_COMMENT lexer rule
+func (t *Tokenizer) matchMlComment() bool {
+	pos := t.lex.Pos()
 
-		// ~'}}'*
-		if !t.lex.MatchUntilSeq("}}") {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
+	// '/*'
+	if !t.lex.MatchSeq("/*") {
+		return false
+	}
+
+	// .*? '*/'
+	for !t.lex.MatchSeq("*/") {
+		if !t.lex.MatchAnyChar() {
+			t.lex.SetPos(pos)
+			return false
 		}
+	}
+	return true
+}
+
+// *** WS ***
 
-		t.lex.MatchSeq("}}")
-		t.lex.BuildTokenData(CODE_BLOCK, tok)
-	case '}':
-		t.lex.BuildTokenNext(RBRACE, tok)
-	case '(':
-		t.lex.BuildTokenNext(LPAREN, tok)
-	case ')':
-		t.lex.BuildTokenNext(RPAREN, tok)
-	case runtime.EOFChar:
-		t.lex.BuildToken(runtime.EOF, tok)
-	default:
-		t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
+// matchWs matches the WS lexer rule
+func (t *Tokenizer) matchWs() bool {
+	// [\t\n\f\r ]+
+	if !t.lex.MatchCharInSeq("\t\n\f\r ") {
+		return false
+	}
+	for t.lex.MatchCharInSeq("\t\n\f\r ") {
 	}
+
+	return true
+}
+
+// *** EQUALS ***
+
+// matchEquals matches the EQUALS lexer rule
+func (t *Tokenizer) matchEquals() bool {
+	// '='
+	return t.lex.MatchChar('=')
+}
+
+// *** LBRACE ***
+
+// matchLbrace matches the LBRACE lexer rule
+func (t *Tokenizer) matchLbrace() bool {
+	// '{'
+	return t.lex.MatchChar('{')
+}
+
+// *** RBRACE ***
+
+// matchRbrace matches the RBRACE lexer rule
+func (t *Tokenizer) matchRbrace() bool {
+	// '}'
+	return t.lex.MatchChar('}')
+}
+
+// *** LPAREN ***
+
+// matchLparen matches the LPAREN lexer rule
+func (t *Tokenizer) matchLparen() bool {
+	// '('
+	return t.lex.MatchChar('(')
+}
+
+// *** RPAREN ***
+
+// matchRparen matches the RPAREN lexer rule
+func (t *Tokenizer) matchRparen() bool {
+	// ')'
+	return t.lex.MatchChar(')')
 }
@@ -1,33 +1,26 @@
-package token
-
-import runtime "github.com/nu11ptr/parsegen/runtime/go"
+// Code generated by parsegen. DO NOT EDIT.
 
-// *** Potentially Generated ***
-
-type Mode int
+package token
 
-const (
-	REGULAR Mode = iota
-	CHAR_CLASS
+import (
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
 )
 
 const (
-	// Mode: Regular
-
-	// Char set
 	RULE_NAME runtime.TokenType = iota + runtime.EOF + 1
 	TOKEN_NAME
-
-	// Sequences
 	TOKEN_LIT
-
-	// Keywords
 	FRAGMENT
 	SKIP_ACTION
 	PUSH_ACTION
 	POP_ACTION
-
-	// Basic Sequences
+	CHANNEL_ACTION
+	MODE
+	GRAMMAR
+	PARSER
+	LEXER
+	OPTIONS
+	IMPORT
 	RARROW
 	DOT
 	COLON
@@ -40,242 +33,679 @@ const (
 	QUEST_MARK
 	TILDE
 	COMMA
+	EQUALS
+	PLUS_ASSIGN
+	POUND
+	AMP
+	BANG
+	LBRACE
+	RBRACE
 	LBRACK
-
-	// Mode: CHAR_CLASS
-
-	// Char set
-	BASIC_CHAR
-
-	// Basic Sequences
 	UNICODE_ESCAPE_CHAR
 	ESCAPE_CHAR
+	BASIC_CHAR
 	DASH
 	RBRACK
 )
 
-var (
-	keywords = map[string]runtime.TokenType{
-		"fragment": FRAGMENT,
-		"skip":     SKIP_ACTION,
-		"pushMode": PUSH_ACTION,
-		"popMode":  POP_ACTION,
-	}
+const (
+	DEFAULT_MODE runtime.Mode = iota
+	CHAR_CLASS
 )
 
-type Tokenizer struct {
-	lex *runtime.Lexer
+var tokenNames = map[runtime.TokenType]string{
+	RULE_NAME:           "RULE_NAME",
+	TOKEN_NAME:          "TOKEN_NAME",
+	TOKEN_LIT:           "TOKEN_LIT",
+	FRAGMENT:            "'fragment'",
+	SKIP_ACTION:         "'skip'",
+	PUSH_ACTION:         "'pushMode'",
+	POP_ACTION:          "'popMode'",
+	CHANNEL_ACTION:      "'channel'",
+	MODE:                "'mode'",
+	GRAMMAR:             "'grammar'",
+	PARSER:              "'parser'",
+	LEXER:               "'lexer'",
+	OPTIONS:             "'options'",
+	IMPORT:              "'import'",
+	RARROW:              "'->'",
+	DOT:                 "'.'",
+	COLON:               "':'",
+	SEMI:                "';'",
+	PIPE:                "'|'",
+	LPAREN:              "'('",
+	RPAREN:              "')'",
+	PLUS:                "'+'",
+	STAR:                "'*'",
+	QUEST_MARK:          "'?'",
+	TILDE:               "'~'",
+	COMMA:               "','",
+	EQUALS:              "'='",
+	PLUS_ASSIGN:         "'+='",
+	POUND:               "'#'",
+	AMP:                 "'&'",
+	BANG:                "'!'",
+	LBRACE:              "'{'",
+	RBRACE:              "'}'",
+	LBRACK:              "'['",
+	UNICODE_ESCAPE_CHAR: "UNICODE_ESCAPE_CHAR",
+	ESCAPE_CHAR:         "ESCAPE_CHAR",
+	BASIC_CHAR:          "BASIC_CHAR",
+	DASH:                "'-'",
+	RBRACK:              "']'",
+}
+
+var keywords = map[string]runtime.TokenType{
+	"fragment": FRAGMENT,
+	"skip":     SKIP_ACTION,
+	"pushMode": PUSH_ACTION,
+	"popMode":  POP_ACTION,
+	"channel":  CHANNEL_ACTION,
+	"mode":     MODE,
+	"grammar":  GRAMMAR,
+	"parser":   PARSER,
+	"lexer":    LEXER,
+	"options":  OPTIONS,
+	"import":   IMPORT,
+}
 
-	mode Mode
+// Tokenizer splits its input into tokens by taking the longest match of
+// any lexer rule of the current mode at each position
+type Tokenizer struct {
+	lex      *runtime.Lexer
+	matchers [][]func() bool
 }
 
+// New creates a new tokenizer that reads characters from the given lexer
 func New(lex *runtime.Lexer) *Tokenizer {
-	return &Tokenizer{lex: lex, mode: REGULAR}
+	t := &Tokenizer{lex: lex}
+	t.matchers = [][]func() bool{
+		DEFAULT_MODE: {
+			t.matchRuleName,
+			t.matchTokenName,
+			t.matchTokenLit,
+			t.matchComment,
+			t.matchMlComment,
+			t.matchWs,
+			t.matchRarrow,
+			t.matchDot,
+			t.matchColon,
+			t.matchSemi,
+			t.matchPipe,
+			t.matchLparen,
+			t.matchRparen,
+			t.matchPlus,
+			t.matchStar,
+			t.matchQuestMark,
+			t.matchTilde,
+			t.matchComma,
+			t.matchEquals,
+			t.matchPlusAssign,
+			t.matchPound,
+			t.matchAmp,
+			t.matchBang,
+			t.matchLbrace,
+			t.matchRbrace,
+			t.matchLbrack,
+		},
+		CHAR_CLASS: {
+			t.matchUnicodeEscapeChar,
+			t.matchEscapeChar,
+			t.matchBasicChar,
+			t.matchDash,
+			t.matchRbrack,
+		},
+	}
+	return t
+}
+
+// TokenName returns the name of a token type for use in syntax errors
+func (t *Tokenizer) TokenName(tt runtime.TokenType) string {
+	return tokenNames[tt]
+}
+
+// NextToken matches the next token in the input, discarding any skipped
+func (t *Tokenizer) NextToken(tok *runtime.Token) {
+	for {
+		var skipped bool
+		switch t.lex.CurrentMode() {
+		case CHAR_CLASS:
+			skipped = t.nextCharClassToken(tok)
+		default:
+			skipped = t.nextDefaultModeToken(tok)
+		}
+		if !skipped {
+			return
+		}
+	}
+}
+
+// nextDefaultModeToken matches the next token in the DEFAULT_MODE mode and
+// returns true if it was skipped
+func (t *Tokenizer) nextDefaultModeToken(tok *runtime.Token) bool {
+	switch t.lex.LongestMatch(t.matchers[DEFAULT_MODE]) {
+	case 0: // RULE_NAME
+		t.lex.BuildTokenData(RULE_NAME, tok)
+		if tt, ok := keywords[tok.Data]; ok {
+			tok.Type, tok.Data = tt, ""
+		}
+	case 1: // TOKEN_NAME
+		t.lex.BuildTokenData(TOKEN_NAME, tok)
+	case 2: // TOKEN_LIT
+		t.lex.BuildTokenData(TOKEN_LIT, tok)
+	case 6: // RARROW
+		t.lex.BuildToken(RARROW, tok)
+	case 7: // DOT
+		t.lex.BuildToken(DOT, tok)
+	case 8: // COLON
+		t.lex.BuildToken(COLON, tok)
+	case 9: // SEMI
+		t.lex.BuildToken(SEMI, tok)
+	case 10: // PIPE
+		t.lex.BuildToken(PIPE, tok)
+	case 11: // LPAREN
+		t.lex.BuildToken(LPAREN, tok)
+	case 12: // RPAREN
+		t.lex.BuildToken(RPAREN, tok)
+	case 13: // PLUS
+		t.lex.BuildToken(PLUS, tok)
+	case 14: // STAR
+		t.lex.BuildToken(STAR, tok)
+	case 15: // QUEST_MARK
+		t.lex.BuildToken(QUEST_MARK, tok)
+	case 16: // TILDE
+		t.lex.BuildToken(TILDE, tok)
+	case 17: // COMMA
+		t.lex.BuildToken(COMMA, tok)
+	case 18: // EQUALS
+		t.lex.BuildToken(EQUALS, tok)
+	case 19: // PLUS_ASSIGN
+		t.lex.BuildToken(PLUS_ASSIGN, tok)
+	case 20: // POUND
+		t.lex.BuildToken(POUND, tok)
+	case 21: // AMP
+		t.lex.BuildToken(AMP, tok)
+	case 22: // BANG
+		t.lex.BuildToken(BANG, tok)
+	case 23: // LBRACE
+		t.lex.BuildToken(LBRACE, tok)
+	case 24: // RBRACE
+		t.lex.BuildToken(RBRACE, tok)
+	case 25: // LBRACK
+		t.lex.BuildToken(LBRACK, tok)
+		t.lex.PushMode(CHAR_CLASS)
+	case 3, 4, 5: // COMMENT, ML_COMMENT, WS
+		t.lex.DiscardTokenData()
+		return true
+	default:
+		if t.lex.CurrChar() == runtime.EOFChar {
+			t.lex.BuildToken(runtime.EOF, tok)
+		} else {
+			t.lex.BuildIllegalToken(tok)
+		}
+	}
+	return false
+}
+
+// nextCharClassToken matches the next token in the CHAR_CLASS mode and
+// returns true if it was skipped
+func (t *Tokenizer) nextCharClassToken(tok *runtime.Token) bool {
+	switch t.lex.LongestMatch(t.matchers[CHAR_CLASS]) {
+	case 0: // UNICODE_ESCAPE_CHAR
+		t.lex.BuildTokenData(UNICODE_ESCAPE_CHAR, tok)
+	case 1: // ESCAPE_CHAR
+		t.lex.BuildTokenData(ESCAPE_CHAR, tok)
+	case 2: // BASIC_CHAR
+		t.lex.BuildTokenData(BASIC_CHAR, tok)
+	case 3: // DASH
+		t.lex.BuildToken(DASH, tok)
+	case 4: // RBRACK
+		t.lex.BuildToken(RBRACK, tok)
+		if t.lex.PopMode() != nil {
+			// No mode to return to, so the token is not valid here
+			t.lex.MakeIllegal(tok, runtime.ModeStackUnderflow)
+		}
+	default:
+		if t.lex.CurrChar() == runtime.EOFChar {
+			t.lex.BuildToken(runtime.EOF, tok)
+		} else {
+			t.lex.BuildIllegalToken(tok)
+		}
+	}
+	return false
+}
+
+// *** HEX_DIGIT ***
+
+// matchHexDigit matches the HEX_DIGIT lexer rule
+func (t *Tokenizer) matchHexDigit() bool {
+	// [0-9A-Fa-f]
+	return t.lex.MatchCharInRange('0', '9') ||
+		t.lex.MatchCharInRange('A', 'F') ||
+		t.lex.MatchCharInRange('a', 'f')
+}
+
+// *** NAME ***
+
+// matchName matches the NAME lexer rule
+func (t *Tokenizer) matchName() bool {
+	// [0-9A-Z_a-z]*
+	for t.lex.MatchCharInRange('0', '9') ||
+		t.lex.MatchCharInRange('A', 'Z') ||
+		t.lex.MatchCharInRange('a', 'z') ||
+		t.lex.MatchChar('_') {
+	}
+
+	return true
 }
 
-func (t *Tokenizer) processRuleName(tok *runtime.Token) bool {
+// *** RULE_NAME ***
+
+// matchRuleName matches the RULE_NAME lexer rule
+func (t *Tokenizer) matchRuleName() bool {
+	pos := t.lex.Pos()
+
 	// [a-z]
 	if !t.lex.MatchCharInRange('a', 'z') {
 		return false
 	}
 
-	// [A-Za-z0-9_]*
-	for t.lex.MatchCharInRange('A', 'Z') || t.lex.MatchCharInRange('a', 'z') ||
-		t.lex.MatchCharInRange('0', '9') || t.lex.MatchChar('_') {
+	// NAME
+	if !t.matchName() {
+		t.lex.SetPos(pos)
+		return false
 	}
 
-	t.lex.BuildTokenData(RULE_NAME, tok)
+	return true
+}
+
+// *** TOKEN_NAME ***
 
-	// Possible conflicting keyword
-	tt, ok := keywords[tok.Data]
-	if ok {
-		tok.Type = tt
-		tok.Data = ""
+// matchTokenName matches the TOKEN_NAME lexer rule
+func (t *Tokenizer) matchTokenName() bool {
+	pos := t.lex.Pos()
+
+	// [A-Z]
+	if !t.lex.MatchCharInRange('A', 'Z') {
+		return false
+	}
+
+	// NAME
+	if !t.matchName() {
+		t.lex.SetPos(pos)
+		return false
 	}
 
 	return true
 }
 
-func (t *Tokenizer) processTokenName(tok *runtime.Token) bool {
-	// [A-Z]
-	if !t.lex.MatchCharInRange('A', 'Z') {
+// *** TOKEN_LIT ***
+
+// matchTokenLit matches the TOKEN_LIT lexer rule
+func (t *Tokenizer) matchTokenLit() bool {
+	pos := t.lex.Pos()
+
+	// '\''
+	if !t.lex.MatchChar('\'') {
 		return false
 	}
 
-	// [A-Za-z0-9_]*
-	for t.lex.MatchCharInRange('A', 'Z') || t.lex.MatchCharInRange('a', 'z') ||
-		t.lex.MatchCharInRange('0', '9') || t.lex.MatchChar('_') {
+	// ('\\' . | ~['\\])+
+	if !t.matchTokenLitSub1() {
+		t.lex.SetPos(pos)
+		return false
+	}
+	for t.matchTokenLitSub1() {
 	}
 
-	t.lex.BuildTokenData(TOKEN_NAME, tok)
+	// '\''
+	if !t.lex.MatchChar('\'') {
+		t.lex.SetPos(pos)
+		return false
+	}
 
 	return true
 }
 
-func (t *Tokenizer) charClassNextToken(ch rune, tok *runtime.Token) {
-	switch ch {
-	case '\\':
-		switch t.lex.NextChar() {
-		case 'u':
-			t.lex.NextChar()
-
-			// HEX_DIGIT+
-			t.lex.MarkPos()
-			matched := false
-			for t.lex.MatchCharInRange('A', 'F') || t.lex.MatchCharInRange('a', 'f') ||
-				t.lex.MatchCharInRange('0', '9') {
-				matched = true
-			}
-			if matched {
-				t.lex.BuildTokenData(UNICODE_ESCAPE_CHAR, tok)
-				break
-			}
-
-			// '{'
-			t.lex.ResetPos()
-			if !t.lex.MatchChar('{') {
-				t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-				break
-			}
-
-			// HEX_DIGIT+
-			matched = false
-			for t.lex.MatchCharInRange('A', 'F') || t.lex.MatchCharInRange('a', 'f') ||
-				t.lex.MatchCharInRange('0', '9') {
-				matched = true
-			}
-			if !matched {
-				t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-				break
-			}
-
-			if !t.lex.MatchChar('}') {
-				t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-				break
-			}
-
-			t.lex.BuildTokenData(UNICODE_ESCAPE_CHAR, tok)
-		default:
-			t.lex.BuildTokenDataNext(ESCAPE_CHAR, tok)
-		}
-	case '-':
-		t.lex.BuildTokenNext(DASH, tok)
-	case ']':
-		t.lex.BuildTokenNext(RBRACK, tok)
-		t.mode = REGULAR
-	default:
-		t.lex.BuildTokenDataNext(BASIC_CHAR, tok)
+// matchTokenLitSub1 matches part of the TOKEN_LIT lexer rule
+func (t *Tokenizer) matchTokenLitSub1() bool {
+	// '\\' . | ~['\\]
+	return t.matchTokenLitSub2() || t.lex.MatchCharExceptInSeq("'\\")
+}
+
+// matchTokenLitSub2 matches part of the TOKEN_LIT lexer rule
+func (t *Tokenizer) matchTokenLitSub2() bool {
+	pos := t.lex.Pos()
+
+	// '\\'
+	if !t.lex.MatchChar('\\') {
+		return false
 	}
+
+	// .
+	if !t.lex.MatchAnyChar() {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
 }
 
-func (t *Tokenizer) NextToken(tok *runtime.Token) {
-	if t.mode == CHAR_CLASS {
-		t.charClassNextToken(t.lex.CurrChar(), tok)
-		return
+// *** COMMENT ***
+
+// matchComment matches the COMMENT lexer rule
+func (t *Tokenizer) matchComment() bool {
+	// '//'
+	if !t.lex.MatchSeq("//") {
+		return false
 	}
 
-	// Skip
-	skipping := true
-	for skipping {
-		switch t.lex.CurrChar() {
-		case '/':
-			switch t.lex.NextChar() {
-			// '//'
-			case '/':
-				t.lex.NextChar()
-
-				// ~[\r\n]*
-				for t.lex.MatchCharExceptInSeq("\r\n") {
-				}
-				t.lex.DiscardTokenData()
-			// '/*'
-			case '*':
-				t.lex.NextChar()
-				t.lex.MatchUntilSeq("*/")
-				t.lex.MatchSeq("*/")
-				t.lex.DiscardTokenData()
-			default:
-				t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-				return
-			}
-		// [ \t\r\n\f]+
-		case ' ', '\t', '\r', '\n', '\f':
-			t.lex.NextChar()
-
-			for t.lex.MatchCharInSeq(" \t\r\n\f") {
-			}
-			t.lex.DiscardTokenData()
-		default:
-			skipping = false
+	// ~[\n\r]*
+	for t.lex.MatchCharExceptInSeq("\n\r") {
+	}
+
+	return true
+}
+
+// *** ML_COMMENT ***
+
+// matchMlComment matches the ML_COMMENT lexer rule
+func (t *Tokenizer) matchMlComment() bool {
+	pos := t.lex.Pos()
+
+	// '/*'
+	if !t.lex.MatchSeq("/*") {
+		return false
+	}
+
+	// .*? '*/'
+	for !t.lex.MatchSeq("*/") {
+		if !t.lex.MatchAnyChar() {
+			t.lex.SetPos(pos)
+			return false
 		}
 	}
+	return true
+}
+
+// *** WS ***
 
-	if t.processRuleName(tok) {
-		return
+// matchWs matches the WS lexer rule
+func (t *Tokenizer) matchWs() bool {
+	// [\t\n\f\r ]+
+	if !t.lex.MatchCharInSeq("\t\n\f\r ") {
+		return false
 	}
-	if t.processTokenName(tok) {
-		return
+	for t.lex.MatchCharInSeq("\t\n\f\r ") {
 	}
 
-	switch t.lex.CurrChar() {
-	case '\'':
-		t.lex.NextChar()
+	return true
+}
 
-		// ('\\\'' | ~'\'')+
-		matched := false
-		for t.lex.MatchSeq("\\'") || t.lex.MatchCharExcept('\'') {
-			matched = true
-		}
-		if !matched {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
-		}
+// *** RARROW ***
 
-		// '\''
-		if !t.lex.MatchChar('\'') {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
-		}
+// matchRarrow matches the RARROW lexer rule
+func (t *Tokenizer) matchRarrow() bool {
+	// '->'
+	return t.lex.MatchSeq("->")
+}
 
-		t.lex.BuildTokenData(TOKEN_LIT, tok)
-	case '-':
-		t.lex.NextChar()
+// *** DOT ***
 
-		// '>'
-		if !t.lex.MatchChar('>') {
-			t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
-			return
-		}
+// matchDot matches the DOT lexer rule
+func (t *Tokenizer) matchDot() bool {
+	// '.'
+	return t.lex.MatchChar('.')
+}
 
-		t.lex.BuildToken(RARROW, tok)
-	case '.':
-		t.lex.BuildTokenNext(DOT, tok)
-	case ':':
-		t.lex.BuildTokenNext(COLON, tok)
-	case ';':
-		t.lex.BuildTokenNext(SEMI, tok)
-	case '|':
-		t.lex.BuildTokenNext(PIPE, tok)
-	case '(':
-		t.lex.BuildTokenNext(LPAREN, tok)
-	case ')':
-		t.lex.BuildTokenNext(RPAREN, tok)
-	case '+':
-		t.lex.BuildTokenNext(PLUS, tok)
-	case '*':
-		t.lex.BuildTokenNext(STAR, tok)
-	case '?':
-		t.lex.BuildTokenNext(QUEST_MARK, tok)
-	case '~':
-		t.lex.BuildTokenNext(TILDE, tok)
-	case ',':
-		t.lex.BuildTokenNext(COMMA, tok)
-	case '[':
-		t.lex.BuildTokenNext(LBRACK, tok)
-		t.mode = CHAR_CLASS
-	case runtime.EOFChar:
-		t.lex.BuildToken(runtime.EOF, tok)
-	default:
-		t.lex.BuildTokenDataNext(runtime.ILLEGAL, tok)
+// *** COLON ***
+
+// matchColon matches the COLON lexer rule
+func (t *Tokenizer) matchColon() bool {
+	// ':'
+	return t.lex.MatchChar(':')
+}
+
+// *** SEMI ***
+
+// matchSemi matches the SEMI lexer rule
+func (t *Tokenizer) matchSemi() bool {
+	// ';'
+	return t.lex.MatchChar(';')
+}
+
+// *** PIPE ***
+
+// matchPipe matches the PIPE lexer rule
+func (t *Tokenizer) matchPipe() bool {
+	// '|'
+	return t.lex.MatchChar('|')
+}
+
+// *** LPAREN ***
+
+// matchLparen matches the LPAREN lexer rule
+func (t *Tokenizer) matchLparen() bool {
+	// '('
+	return t.lex.MatchChar('(')
+}
+
+// *** RPAREN ***
+
+// matchRparen matches the RPAREN lexer rule
+func (t *Tokenizer) matchRparen() bool {
+	// ')'
+	return t.lex.MatchChar(')')
+}
+
+// *** PLUS ***
+
+// matchPlus matches the PLUS lexer rule
+func (t *Tokenizer) matchPlus() bool {
+	// '+'
+	return t.lex.MatchChar('+')
+}
+
+// *** STAR ***
+
+// matchStar matches the STAR lexer rule
+func (t *Tokenizer) matchStar() bool {
+	// '*'
+	return t.lex.MatchChar('*')
+}
+
+// *** QUEST_MARK ***
+
+// matchQuestMark matches the QUEST_MARK lexer rule
+func (t *Tokenizer) matchQuestMark() bool {
+	// '?'
+	return t.lex.MatchChar('?')
+}
+
+// *** TILDE ***
+
+// matchTilde matches the TILDE lexer rule
+func (t *Tokenizer) matchTilde() bool {
+	// '~'
+	return t.lex.MatchChar('~')
+}
+
+// *** COMMA ***
+
+// matchComma matches the COMMA lexer rule
+func (t *Tokenizer) matchComma() bool {
+	// ','
+	return t.lex.MatchChar(',')
+}
+
+// *** EQUALS ***
+
+// matchEquals matches the EQUALS lexer rule
+func (t *Tokenizer) matchEquals() bool {
+	// '='
+	return t.lex.MatchChar('=')
+}
+
+// *** PLUS_ASSIGN ***
+
+// matchPlusAssign matches the PLUS_ASSIGN lexer rule
+func (t *Tokenizer) matchPlusAssign() bool {
+	// '+='
+	return t.lex.MatchSeq("+=")
+}
+
+// *** POUND ***
+
+// matchPound matches the POUND lexer rule
+func (t *Tokenizer) matchPound() bool {
+	// '#'
+	return t.lex.MatchChar('#')
+}
+
+// *** AMP ***
+
+// matchAmp matches the AMP lexer rule
+func (t *Tokenizer) matchAmp() bool {
+	// '&'
+	return t.lex.MatchChar('&')
+}
+
+// *** BANG ***
+
+// matchBang matches the BANG lexer rule
+func (t *Tokenizer) matchBang() bool {
+	// '!'
+	return t.lex.MatchChar('!')
+}
+
+// *** LBRACE ***
+
+// matchLbrace matches the LBRACE lexer rule
+func (t *Tokenizer) matchLbrace() bool {
+	// '{'
+	return t.lex.MatchChar('{')
+}
+
+// *** RBRACE ***
+
+// matchRbrace matches the RBRACE lexer rule
+func (t *Tokenizer) matchRbrace() bool {
+	// '}'
+	return t.lex.MatchChar('}')
+}
+
+// *** LBRACK ***
+
+// matchLbrack matches the LBRACK lexer rule
+func (t *Tokenizer) matchLbrack() bool {
+	// '['
+	return t.lex.MatchChar('[')
+}
+
+// *** UNICODE_ESCAPE_CHAR ***
+
+// matchUnicodeEscapeChar matches the UNICODE_ESCAPE_CHAR lexer rule
+func (t *Tokenizer) matchUnicodeEscapeChar() bool {
+	pos := t.lex.Pos()
+
+	// '\\u'
+	if !t.lex.MatchSeq("\\u") {
+		return false
+	}
+
+	// (HEX_DIGIT+ | '{' HEX_DIGIT+ '}')
+	if !t.matchUnicodeEscapeCharSub1() {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
+
+// matchUnicodeEscapeCharSub1 matches part of the UNICODE_ESCAPE_CHAR lexer rule
+func (t *Tokenizer) matchUnicodeEscapeCharSub1() bool {
+	// HEX_DIGIT+ | '{' HEX_DIGIT+ '}'
+	return t.matchUnicodeEscapeCharSub2() || t.matchUnicodeEscapeCharSub3()
+}
+
+// matchUnicodeEscapeCharSub2 matches part of the UNICODE_ESCAPE_CHAR lexer rule
+func (t *Tokenizer) matchUnicodeEscapeCharSub2() bool {
+	// HEX_DIGIT+
+	if !t.matchHexDigit() {
+		return false
+	}
+	for t.matchHexDigit() {
+	}
+
+	return true
+}
+
+// matchUnicodeEscapeCharSub3 matches part of the UNICODE_ESCAPE_CHAR lexer rule
+func (t *Tokenizer) matchUnicodeEscapeCharSub3() bool {
+	pos := t.lex.Pos()
+
+	// '{'
+	if !t.lex.MatchChar('{') {
+		return false
+	}
+
+	// HEX_DIGIT+
+	if !t.matchHexDigit() {
+		t.lex.SetPos(pos)
+		return false
+	}
+	for t.matchHexDigit() {
+	}
+
+	// '}'
+	if !t.lex.MatchChar('}') {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
+
+// *** ESCAPE_CHAR ***
+
+// matchEscapeChar matches the ESCAPE_CHAR lexer rule
+func (t *Tokenizer) matchEscapeChar() bool {
+	pos := t.lex.Pos()
+
+	// '\\'
+	if !t.lex.MatchChar('\\') {
+		return false
 	}
+
+	// .
+	if !t.lex.MatchAnyChar() {
+		t.lex.SetPos(pos)
+		return false
+	}
+
+	return true
+}
+
+// *** BASIC_CHAR ***
+
+// matchBasicChar matches the BASIC_CHAR lexer rule
+func (t *Tokenizer) matchBasicChar() bool {
+	// ~[\-\\\]]
+	return t.lex.MatchCharExceptInSeq("-\\]")
+}
+
+// *** DASH ***
+
+// matchDash matches the DASH lexer rule
+func (t *Tokenizer) matchDash() bool {
+	// '-'
+	return t.lex.MatchChar('-')
+}
+
+// *** RBRACK ***
+
+// matchRbrack matches the RBRACK lexer rule
+func (t *Tokenizer) matchRbrack() bool {
+	// ']'
+	return t.lex.MatchChar(']')
 }
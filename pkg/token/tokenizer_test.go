@@ -25,6 +25,8 @@ fragment WS: [ \t\r\n\f]+ -> skip;
 
 RULE_NAME: [a-z] NAME;
 
+ESC: '\\' '\'';
+
 BOGUS: [\uffff\u{abcd}];
 `
 )
@@ -83,6 +85,13 @@ var (
 		{Type: token.TOKEN_NAME, Data: "NAME"},
 		{Type: token.SEMI},
 
+		// ESC
+		{Type: token.TOKEN_NAME, Data: "ESC"},
+		{Type: token.COLON},
+		{Type: token.TOKEN_LIT, Data: `'\\'`},
+		{Type: token.TOKEN_LIT, Data: `'\''`},
+		{Type: token.SEMI},
+
 		// BOGUS
 		{Type: token.TOKEN_NAME, Data: "BOGUS"},
 		{Type: token.COLON},
@@ -119,3 +128,54 @@ func TestLexerTokenizer(t *testing.T) {
 		assert.Equal(t, tok2.Data, tok.Data)
 	}
 }
+
+func TestTokenizerModes(t *testing.T) {
+	// Char classes are lexed in their own mode, and a stray ']' has no mode
+	// to return to
+	lex := runtime.NewLexerFromString("A: ['] ']';")
+	tokenizer := token.New(lex)
+
+	expected := []runtime.Token{
+		{Type: token.TOKEN_NAME, Data: "A"},
+		{Type: token.COLON},
+		{Type: token.LBRACK},
+		{Type: token.BASIC_CHAR, Data: "'"},
+		{Type: token.RBRACK},
+		{Type: token.TOKEN_LIT, Data: "']'"},
+		{Type: token.SEMI},
+		{Type: runtime.EOF},
+	}
+	for _, tok2 := range expected {
+		var tok runtime.Token
+		tokenizer.NextToken(&tok)
+		assert.Equal(t, tok2.Type, tok.Type)
+		assert.Equal(t, tok2.Data, tok.Data)
+	}
+
+	var tok runtime.Token
+	token.New(runtime.NewLexerFromString("]")).NextToken(&tok)
+	assert.Equal(t, runtime.ILLEGAL, tok.Type)
+}
+
+func TestTokenizerComments(t *testing.T) {
+	// Comments end at the first '*/', stars inside of them included
+	lex := runtime.NewLexerFromString("/* a * b **/ A /**/ */")
+	tokenizer := token.New(lex)
+
+	expected := []runtime.Token{
+		{Type: token.TOKEN_NAME, Data: "A"},
+		{Type: token.STAR},
+		{Type: runtime.ILLEGAL, Data: "/"},
+		{Type: runtime.EOF},
+	}
+	for _, tok2 := range expected {
+		var tok runtime.Token
+		tokenizer.NextToken(&tok)
+		assert.Equal(t, tok2.Type, tok.Type)
+		assert.Equal(t, tok2.Data, tok.Data)
+	}
+
+	var tok runtime.Token
+	token.New(runtime.NewLexerFromString("/* unterminated")).NextToken(&tok)
+	assert.Equal(t, runtime.ILLEGAL, tok.Type)
+}
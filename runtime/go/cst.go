@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+const cstSpaces = 3
+
+// CSTNode is a node of a concrete syntax tree. It is either a token or a named
+// node with the nodes below it in input order. A tree built from tokens that
+// kept their trivia (see Lexer.BuildTrivia) covers all of its input text
+type CSTNode struct {
+	// Name describes what the node matched (ex: a parser rule name). It is
+	// empty for tokens
+	Name     string
+	Token    *Token
+	Children []*CSTNode
+}
+
+// Text returns the input text covered by the node: the trivia and data of
+// each of its tokens in order
+func (n *CSTNode) Text() string {
+	buff := strings.Builder{}
+	n.writeText(&buff)
+	return buff.String()
+}
+
+func (n *CSTNode) writeText(buff *strings.Builder) {
+	if n.Token != nil {
+		for _, trivia := range n.Token.Trivia {
+			buff.WriteString(trivia.Data)
+		}
+		buff.WriteString(n.Token.Data)
+	}
+	for _, child := range n.Children {
+		child.writeText(buff)
+	}
+}
+
+// Tokens returns the tokens below the node in input order
+func (n *CSTNode) Tokens() []*Token {
+	tokens := []*Token{}
+	n.eachToken(func(tok *Token) {
+		tokens = append(tokens, tok)
+	})
+	return tokens
+}
+
+func (n *CSTNode) eachToken(fn func(*Token)) {
+	if n.Token != nil {
+		fn(n.Token)
+	}
+	for _, child := range n.Children {
+		child.eachToken(fn)
+	}
+}
+
+// String returns the tree below the node with one node per line. Tokens are
+// printed by their quoted data, so trivia is left out
+func (n *CSTNode) String() string {
+	buff := strings.Builder{}
+	n.writeTree(&buff, 0)
+	return buff.String()
+}
+
+func (n *CSTNode) writeTree(buff *strings.Builder, indent int) {
+	if indent > 0 {
+		buff.WriteString(strings.Repeat(" ", indent*cstSpaces))
+		buff.WriteString("└──")
+	}
+	if n.Token != nil {
+		buff.WriteString(fmt.Sprintf("%q\n", n.Token.Data))
+	} else {
+		buff.WriteString(fmt.Sprintf("%s:\n", n.Name))
+	}
+	for _, child := range n.Children {
+		child.writeTree(buff, indent+1)
+	}
+}
@@ -0,0 +1,64 @@
+package runtime_test
+
+import (
+	"testing"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	word runtime.TokenType = iota + runtime.EOF + 1
+	space
+)
+
+// triviaTokenizer splits words on spaces, keeping the spaces as trivia
+type triviaTokenizer struct {
+	lex *runtime.Lexer
+}
+
+func (t *triviaTokenizer) NextToken(tok *runtime.Token) {
+	for t.lex.MatchChar(' ') {
+		for t.lex.MatchChar(' ') {
+		}
+		t.lex.BuildTrivia(space)
+	}
+	if t.lex.MatchCharExcept(' ') {
+		for t.lex.MatchCharExcept(' ') {
+		}
+		t.lex.BuildTokenData(word, tok)
+		return
+	}
+	t.lex.BuildToken(runtime.EOF, tok)
+}
+
+func TestCSTNode(t *testing.T) {
+	const input = " ab  c d "
+	tokens := []runtime.Token{}
+	tokenizer := &triviaTokenizer{lex: runtime.NewLexerFromString(input)}
+	for {
+		var tok runtime.Token
+		tokenizer.NextToken(&tok)
+		tokens = append(tokens, tok)
+		if tok.Type == runtime.EOF {
+			break
+		}
+	}
+	require.Len(t, tokens, 4)
+	require.Len(t, tokens[1].Trivia, 1)
+	assert.Equal(t, runtime.Token{
+		Type: space, Data: "  ", StartRow: 1, StartCol: 4, EndRow: 1, EndCol: 5, StartOffset: 3, EndOffset: 5,
+	}, tokens[1].Trivia[0])
+
+	// Trivia at the end of the input belongs to EOF
+	cst := &runtime.CSTNode{Name: "list", Children: []*runtime.CSTNode{
+		{Token: &tokens[0]},
+		{Name: "pair", Children: []*runtime.CSTNode{{Token: &tokens[1]}, {Token: &tokens[2]}}},
+		{Token: &tokens[3]},
+	}}
+	assert.Equal(t, input, cst.Text())
+	assert.Equal(t, " d", cst.Children[1].Children[1].Text())
+	assert.Len(t, cst.Tokens(), 4)
+	assert.Equal(t, "list:\n   └──\"ab\"\n   └──pair:\n      └──\"c\"\n      └──\"d\"\n   └──\"\"\n", cst.String())
+}
@@ -0,0 +1,213 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity is how serious a diagnostic is
+type Severity int
+
+const (
+	// SeverityError is a problem that must be fixed (ex: a syntax error, or a
+	// grammar problem that prevents code generation)
+	SeverityError Severity = iota
+	// SeverityWarning is likely a mistake, but doesn't stop processing
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is a problem found in the input, such as a syntax error
+type Diagnostic struct {
+	Severity Severity
+	// Span is the text the problem was found at
+	Span Span
+	// Filename, Line and Col locate the start of the span when its file isn't
+	// in the FileSet the diagnostic is printed with. Line and Col are 0 when
+	// unknown
+	Filename  string
+	Line, Col int
+	Message   string
+	// Notes add detail to the message (ex: what was expected instead)
+	Notes []string
+}
+
+// ErrorDiagnostic returns a diagnostic for a syntax error or lexer error, or
+// false if the error (or any error it wraps) isn't one
+func ErrorDiagnostic(err error) (*Diagnostic, bool) {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		d := &Diagnostic{
+			Span: syntaxErr.Found.Span(), Filename: syntaxErr.Filename,
+			Line: int(syntaxErr.Found.StartRow), Col: int(syntaxErr.Found.StartCol),
+			Message: "unexpected " + syntaxErr.FoundText(),
+		}
+		if len(syntaxErr.Expected) > 0 {
+			d.Notes = []string{"expected " + syntaxErr.ExpectedText()}
+		}
+		d.Notes = append(d.Notes, syntaxErr.Failed...)
+		return d, true
+	}
+
+	var lexErr *LexError
+	if errors.As(err, &lexErr) {
+		return &Diagnostic{
+			Span:    Span{File: lexErr.File, Start: lexErr.Offset, End: lexErr.Offset + len(lexErr.Bytes)},
+			Line:    int(lexErr.Row),
+			Col:     int(lexErr.Col),
+			Message: fmt.Sprintf("%s %q", lexErr.Kind, lexErr.Bytes),
+		}, true
+	}
+	return nil, false
+}
+
+// *** Printing ***
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// DiagnosticPrinter formats diagnostics the way compilers do, for example:
+//
+//	calc.txt:1:8: error: unexpected ';'
+//	 1 | x = 1 +;
+//	   |        ^
+//	   = note: expected '(', IDENT or NUMBER
+//
+// The source line and the underline of the span are only shown when the file
+// of the span is in the printer's FileSet
+type DiagnosticPrinter struct {
+	// Files holds the files diagnostics are found in. It can be nil
+	Files *FileSet
+	// Color highlights the output with ANSI escape codes for terminals
+	Color bool
+}
+
+// FormatError formats an error as a diagnostic if ErrorDiagnostic can convert
+// it, otherwise as just its message
+func (p *DiagnosticPrinter) FormatError(err error) string {
+	if d, ok := ErrorDiagnostic(err); ok {
+		return p.Format(d)
+	}
+	return p.style(ansiRed, "error:") + " " + p.style(ansiBold, err.Error()) + "\n"
+}
+
+// Format formats a diagnostic as a header with its location and message
+// followed by the source line with the span underlined and its notes. Each
+// line ends in a newline
+func (p *DiagnosticPrinter) Format(d *Diagnostic) string {
+	var f *File
+	if p.Files != nil {
+		f = p.Files.File(d.Span.File)
+	}
+	name, line, col := d.Filename, d.Line, d.Col
+	if f != nil {
+		name = f.Name
+		line, col = f.Lines().Position(d.Span.Start)
+	}
+
+	buff := strings.Builder{}
+	loc := ""
+	if name != "" {
+		loc = name + ":"
+	}
+	if line > 0 {
+		loc += fmt.Sprintf("%d:%d:", line, col)
+	}
+	if loc != "" {
+		buff.WriteString(p.style(ansiBold, loc))
+		buff.WriteByte(' ')
+	}
+	color := ansiRed
+	if d.Severity == SeverityWarning {
+		color = ansiYellow
+	}
+	buff.WriteString(p.style(color, d.Severity.String()+":"))
+	buff.WriteByte(' ')
+	buff.WriteString(p.style(ansiBold, d.Message))
+	buff.WriteByte('\n')
+
+	// The gutter is as wide as the line number
+	num := strconv.Itoa(line)
+	pad := strings.Repeat(" ", len(num)+2)
+	if f != nil {
+		p.writeSnippet(&buff, f, d.Span, line, color, pad)
+	}
+	for _, note := range d.Notes {
+		buff.WriteString(pad)
+		buff.WriteString(p.style(ansiBlue, "="))
+		buff.WriteByte(' ')
+		buff.WriteString(p.style(ansiCyan, "note:"))
+		buff.WriteByte(' ')
+		buff.WriteString(note)
+		buff.WriteByte('\n')
+	}
+	return buff.String()
+}
+
+// writeSnippet writes the line a span starts on, underlining the span up to
+// the end of the line (at least one char, so empty spans like EOF are shown)
+func (p *DiagnosticPrinter) writeSnippet(buff *strings.Builder, f *File, span Span, line int, color, pad string) {
+	lines := f.Lines()
+	text := lines.Line(line)
+	lineStart, lineEnd := lines.LineSpan(line)
+	start, end := clamp(span.Start, lineStart, lineEnd), clamp(span.End, lineStart, lineEnd)
+
+	buff.WriteString(p.style(ansiBlue, fmt.Sprintf(" %d |", line)))
+	if text != "" {
+		buff.WriteByte(' ')
+		buff.WriteString(text)
+	}
+	buff.WriteByte('\n')
+
+	// Tabs are kept so the underline lines up however wide they are shown
+	indent := strings.Builder{}
+	for _, ch := range string(f.Input[lineStart:start]) {
+		if ch == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	width := utf8.RuneCount(f.Input[start:end])
+	if width == 0 {
+		width = 1
+	}
+	buff.WriteString(pad)
+	buff.WriteString(p.style(ansiBlue, "|"))
+	buff.WriteByte(' ')
+	buff.WriteString(indent.String())
+	buff.WriteString(p.style(color, strings.Repeat("^", width)))
+	buff.WriteByte('\n')
+}
+
+func clamp(offset, min, max int) int {
+	switch {
+	case offset < min:
+		return min
+	case offset > max:
+		return max
+	}
+	return offset
+}
+
+func (p *DiagnosticPrinter) style(code, s string) string {
+	if !p.Color {
+		return s
+	}
+	return code + s + ansiReset
+}
@@ -0,0 +1,84 @@
+package runtime_test
+
+import (
+	"fmt"
+	"testing"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticPrinter(t *testing.T) {
+	fs := runtime.NewFileSet()
+	f := fs.AddFile("calc.txt", []byte("x = 1\n\ty = 1 + abc;\n"))
+	p := &runtime.DiagnosticPrinter{Files: fs}
+
+	// The span is underlined below the line it starts on, keeping tabs
+	d := &runtime.Diagnostic{
+		Severity: runtime.SeverityWarning, Span: runtime.Span{File: f.ID, Start: 15, End: 18},
+		Message: "unused variable", Notes: []string{"declared here", "never read"},
+	}
+	assert.Equal(t, "calc.txt:2:10: warning: unused variable\n"+
+		" 2 | \ty = 1 + abc;\n"+
+		"   | \t        ^^^\n"+
+		"   = note: declared here\n"+
+		"   = note: never read\n", p.Format(d))
+
+	// Empty spans (ex: EOF) still get a caret, spans past the end of the line
+	// are cut off there
+	d = &runtime.Diagnostic{Span: runtime.Span{File: f.ID, Start: 20, End: 20}, Message: "missing"}
+	assert.Equal(t, "calc.txt:3:1: error: missing\n 3 |\n   | ^\n", p.Format(d))
+	d = &runtime.Diagnostic{Span: runtime.Span{File: f.ID, Start: 4, End: 9}, Message: "bad"}
+	assert.Equal(t, "calc.txt:1:5: error: bad\n 1 | x = 1\n   |     ^\n", p.Format(d))
+
+	// Without the file, only the header and notes are shown
+	d = &runtime.Diagnostic{Filename: "other.txt", Line: 12, Col: 3, Message: "bad", Notes: []string{"note"}}
+	assert.Equal(t, "other.txt:12:3: error: bad\n    = note: note\n", p.Format(d))
+
+	p.Color = true
+	d = &runtime.Diagnostic{Span: runtime.Span{File: f.ID, Start: 0, End: 1}, Message: "bad"}
+	assert.Equal(t, "\x1b[1mcalc.txt:1:1:\x1b[0m \x1b[1;31merror:\x1b[0m \x1b[1mbad\x1b[0m\n"+
+		"\x1b[1;34m 1 |\x1b[0m x = 1\n"+
+		"   \x1b[1;34m|\x1b[0m \x1b[1;31m^\x1b[0m\n", p.Format(d))
+}
+
+func TestDiagnosticPrinterErrors(t *testing.T) {
+	fs := runtime.NewFileSet()
+	f := fs.AddFile("x.txt", []byte("a b\xff"))
+	p := &runtime.DiagnosticPrinter{Files: fs}
+
+	tokens := []runtime.Token{}
+	lex := runtime.NewLexerForFile(f)
+	for _, ch := range "a b" {
+		var tok runtime.Token
+		if ch == ' ' {
+			lex.DiscardTokenDataNext()
+			continue
+		}
+		lex.MatchChar(ch)
+		lex.BuildTokenData(bogus, &tok)
+		tokens = append(tokens, tok)
+	}
+
+	err := &runtime.SyntaxError{
+		Filename: "x.txt", Found: tokens[1], FoundName: "NAME", Expected: []string{"';'", "'='"},
+	}
+	assert.Equal(t, "x.txt:1:3: error: unexpected NAME \"b\"\n"+
+		" 1 | a b\xff\n"+
+		"   |   ^\n"+
+		"   = note: expected ';' or '='\n", p.FormatError(fmt.Errorf("parsing: %w", err)))
+
+	// Without the file, the header comes from the error
+	assert.Equal(t, "x.txt:1:3: error: unexpected NAME \"b\"\n   = note: expected ';' or '='\n",
+		(&runtime.DiagnosticPrinter{}).FormatError(err))
+
+	var tok runtime.Token
+	lex.BuildIllegalToken(&tok)
+	require.NotNil(t, tok.Err)
+	assert.Equal(t, "x.txt:1:4: error: invalid UTF-8 encoding \"\\xff\"\n"+
+		" 1 | a b\xff\n"+
+		"   |    ^\n", p.FormatError(tok.Err))
+
+	assert.Equal(t, "error: something else\n", p.FormatError(fmt.Errorf("something else")))
+}
@@ -0,0 +1,110 @@
+package runtime
+
+import "io/ioutil"
+
+// FileID identifies a file in a FileSet. It is small enough to be kept on
+// every token, unlike the name of the file
+type FileID int32
+
+// NoFile is the FileID of tokens lexed from input that isn't a file of a
+// FileSet (ex: a lexer created by NewLexerFromString)
+const NoFile FileID = 0
+
+// File is a source file of a FileSet
+type File struct {
+	ID   FileID
+	Name string
+	// Input is the contents of the file that tokens lexed from it were sliced
+	// from
+	Input []byte
+
+	lines *LineIndex
+}
+
+// Lines returns the line index of the file, which is built the first time it
+// is needed
+func (f *File) Lines() *LineIndex {
+	if f.lines == nil {
+		f.lines = NewLineIndex(f.Input)
+	}
+	return f.lines
+}
+
+// FileSet holds the files tokens were lexed from, so the file of a token can
+// be found from its FileID. The first file added gets ID 1 as NoFile is 0
+type FileSet struct {
+	files []*File
+}
+
+// NewFileSet creates an empty file set
+func NewFileSet() *FileSet {
+	return &FileSet{}
+}
+
+// AddFile adds a file with the given name and contents to the set
+func (s *FileSet) AddFile(name string, input []byte) *File {
+	f := &File{ID: FileID(len(s.files) + 1), Name: name, Input: input}
+	s.files = append(s.files, f)
+	return f
+}
+
+// ReadFile reads a file and adds it to the set
+func (s *FileSet) ReadFile(filename string) (*File, error) {
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return s.AddFile(filename, input), nil
+}
+
+// File returns the file with the given ID, or nil if it isn't in the set
+func (s *FileSet) File(id FileID) *File {
+	if id <= NoFile || int(id) > len(s.files) {
+		return nil
+	}
+	return s.files[id-1]
+}
+
+// Lookup returns the last file added with the given name, or nil if there is
+// none
+func (s *FileSet) Lookup(name string) *File {
+	for i := len(s.files) - 1; i >= 0; i-- {
+		if s.files[i].Name == name {
+			return s.files[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the input text covered by a span, or an empty string if its
+// file isn't in the set
+func (s *FileSet) Text(span Span) string {
+	f := s.File(span.File)
+	if f == nil || span.Start < 0 || span.End > len(f.Input) || span.Start > span.End {
+		return ""
+	}
+	return string(f.Input[span.Start:span.End])
+}
+
+// Span is a range of bytes of the input of a file. End is the byte just past
+// the range, so a span of a token covers its text
+type Span struct {
+	File       FileID
+	Start, End int
+}
+
+// Len returns the number of bytes the span covers
+func (s Span) Len() int {
+	return s.End - s.Start
+}
+
+// Contains returns true if the byte at the given offset is within the span
+func (s Span) Contains(offset int) bool {
+	return offset >= s.Start && offset < s.End
+}
+
+// NewLexerForFile creates a new lexer from the input of a file. Tokens it
+// builds are marked as coming from the file
+func NewLexerForFile(f *File) *Lexer {
+	return newLexer(f.Input, f.ID)
+}
@@ -0,0 +1,64 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSet(t *testing.T) {
+	fs := runtime.NewFileSet()
+	a := fs.AddFile("a.txt", []byte("ab cd"))
+	dir, err := ioutil.TempDir("", "runtime")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "b.txt")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("\xffé"), 0644))
+	b, err := fs.ReadFile(filename)
+	require.NoError(t, err)
+
+	assert.Equal(t, runtime.FileID(1), a.ID)
+	assert.Equal(t, runtime.FileID(2), b.ID)
+	assert.Same(t, b, fs.File(b.ID))
+	assert.Nil(t, fs.File(runtime.NoFile))
+	assert.Nil(t, fs.File(3))
+	assert.Same(t, a, fs.Lookup("a.txt"))
+	assert.Nil(t, fs.Lookup("c.txt"))
+
+	// Tokens remember the file they came from and the bytes they cover
+	lex := runtime.NewLexerForFile(a)
+	var tok runtime.Token
+	lex.MatchChar('a')
+	lex.MatchChar('b')
+	lex.BuildTokenData(bogus, &tok)
+	lex.MatchChar(' ')
+	lex.DiscardTokenData()
+	lex.MatchChar('c')
+	lex.MatchChar('d')
+	lex.BuildTokenData(bogus, &tok)
+	assert.Equal(t, a.ID, lex.File())
+	assert.Equal(t, runtime.Span{File: a.ID, Start: 3, End: 5}, tok.Span())
+	assert.Equal(t, "cd", fs.Text(tok.Span()))
+	assert.Equal(t, 2, tok.Span().Len())
+	assert.True(t, tok.Span().Contains(4))
+	assert.False(t, tok.Span().Contains(5))
+
+	// As do the problems found in them
+	lex = runtime.NewLexerForFile(b)
+	lex.BuildIllegalToken(&tok)
+	require.NotNil(t, tok.Err)
+	assert.Equal(t, b.ID, tok.Err.File)
+	assert.Equal(t, runtime.Span{File: b.ID, Start: 0, End: 1}, tok.Span())
+
+	// Other input is from no file
+	lex = runtime.NewLexerFromString("x")
+	lex.MatchChar('x')
+	lex.BuildToken(bogus, &tok)
+	assert.Equal(t, runtime.NoFile, tok.File)
+	assert.Equal(t, "", fs.Text(tok.Span()))
+}
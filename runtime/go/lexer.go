@@ -1,6 +1,8 @@
 package runtime
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -23,13 +25,60 @@ const (
 	EOF
 )
 
+// BOM is the type of the trivia token holding a byte order mark at the start
+// of the input (see Lexer.KeepBOM). It is negative so that it never collides
+// with the token types of a tokenizer
+const BOM TokenType = -1
+
+// Channel is a channel tokens are sent to the parser on. The parser only sees
+// tokens on the default channel, others become trivia of the next token it sees
+type Channel int
+
+const (
+	DefaultChannel Channel = iota
+	HiddenChannel
+)
+
 // Token represents a single token output by the lexer and contains the type of
-// token, the start/end coordinates, and optionally the string data
+// token, the start/end coordinates, and optionally the string data. The end
+// row/col is the last char of the token while the end offset is the byte just
+// past it, so the input sliced from start to end offset is the token text
 type Token struct {
-	Type               TokenType
-	Data               string
-	StartRow, StartCol int32
-	EndRow, EndCol     int32
+	Type                   TokenType
+	Data                   string
+	StartRow, StartCol     int32
+	EndRow, EndCol         int32
+	StartOffset, EndOffset int
+	// File is the file the token was lexed from, or NoFile if the input wasn't
+	// a file of a FileSet (see NewLexerForFile)
+	File FileID
+	// Err is the reason for ILLEGAL tokens built by BuildIllegalToken
+	Err *LexError
+	// Channel is the channel the token is on. Built tokens are on the default
+	// channel unless changed afterwards
+	Channel Channel
+	// Trivia are the tokens directly before this one the parser doesn't see:
+	// skipped tokens (ex: comments and whitespace) built with BuildTrivia and
+	// tokens on other channels. The text of the input is the trivia and data
+	// of each token in order
+	Trivia []Token
+}
+
+// Span returns the file and byte offsets of the token text
+func (t *Token) Span() Span {
+	return Span{File: t.File, Start: t.StartOffset, End: t.EndOffset}
+}
+
+// ChannelTrivia returns the trivia of the token on a given channel (ex: the
+// comments on the hidden channel before it)
+func (t *Token) ChannelTrivia(ch Channel) []Token {
+	tokens := []Token{}
+	for _, trivia := range t.Trivia {
+		if trivia.Channel == ch {
+			tokens = append(tokens, trivia)
+		}
+	}
+	return tokens
 }
 
 // A Tokenizer tokenizes an input stream. It typically will use a Lexer as the
@@ -38,6 +87,61 @@ type Tokenizer interface {
 	NextToken(*Token)
 }
 
+// Mode is a lexer mode. Each mode has its own set of lexer rules
+type Mode int
+
+// DefaultMode is the mode every lexer starts in
+const DefaultMode Mode = 0
+
+// ErrModeStackEmpty is returned when popping a mode without a matching push
+var ErrModeStackEmpty = errors.New("lexer mode stack is empty")
+
+// LexErrorKind is the kind of problem a LexError describes
+type LexErrorKind int
+
+const (
+	InvalidUTF8 LexErrorKind = iota
+	StrayBOM
+	UnexpectedChar
+	Unterminated
+	BadEscape
+	// ModeStackUnderflow is a token that pops the lexer mode when there is no
+	// mode to return to
+	ModeStackUnderflow
+)
+
+func (k LexErrorKind) String() string {
+	switch k {
+	case InvalidUTF8:
+		return "invalid UTF-8 encoding"
+	case StrayBOM:
+		return "unexpected byte order mark"
+	case UnexpectedChar:
+		return "unexpected char"
+	case Unterminated:
+		return "unterminated token"
+	case BadEscape:
+		return "invalid escape sequence"
+	case ModeStackUnderflow:
+		return "mode stack underflow"
+	default:
+		return fmt.Sprintf("LexErrorKind(%d)", int(k))
+	}
+}
+
+// LexError is a problem found in the input while lexing
+type LexError struct {
+	Kind     LexErrorKind
+	File     FileID
+	Offset   int // Byte offset of the start of the offending bytes
+	Row, Col int32
+	Bytes    []byte
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("%d:%d: %s %q", e.Row, e.Col, e.Kind, e.Bytes)
+}
+
 // Lexer represents all the internal state needed to perform lexing
 type Lexer struct {
 	pos, nextPos, tokenStart, mark, markNext int
@@ -45,15 +149,40 @@ type Lexer struct {
 	startRow, startCol, endRow, endCol       int32
 	currCh, markCh                           rune
 	input                                    []byte
+	// file is the file the input was read from, if any
+	file FileID
+	// bom is the size of the byte order mark at the start of the input, if any
+	bom int
+
+	mode  Mode
+	modes []Mode
+
+	// farthest is the farthest position any matcher reached in the last
+	// LongestMatch, used to explain why nothing matched
+	farthest LexerPos
+	errs     []*LexError
+
+	// Trivia built since the last token
+	trivia []Token
 }
 
 // NewLexerFromBytes creates a new lexer from a byte array. The byte array should
 // be backed by UTF-8 data
 func NewLexerFromBytes(input []byte) *Lexer {
+	return newLexer(input, NoFile)
+}
+
+func newLexer(input []byte, file FileID) *Lexer {
 	l := &Lexer{
-		input: input, row: 1, col: 0, // inc'd first time by NextChar
+		input: input, file: file, row: 1, col: 0, // inc'd first time by NextChar
 		startCol: 1, startRow: 1, endRow: 1, endCol: 1,
 	}
+	// A byte order mark at the start of the input isn't part of the text, so it
+	// is skipped unless kept as trivia (see KeepBOM). Anywhere else it is a
+	// stray BOM
+	if r, size := utf8.DecodeRune(input); r == bomChar {
+		l.nextPos, l.tokenStart, l.bom = size, size, size
+	}
 	l.NextChar()
 	return l
 }
@@ -84,31 +213,82 @@ func NewLexerFromFile(filename string) (*Lexer, error) {
 	return NewLexerFromReader(f)
 }
 
+// KeepBOM keeps the byte order mark at the start of the input, if any, as
+// trivia of the first token (with type BOM), so that a tree of the tokens with
+// their trivia covers all of the input. Columns don't count the BOM, so it
+// is at row 1, col 1 along with the first char. It must be called before the
+// first token is built
+func (l *Lexer) KeepBOM() {
+	if l.bom == 0 || l.tokenStart != l.bom || len(l.trivia) > 0 {
+		return
+	}
+	l.trivia = append(l.trivia, Token{
+		Type: BOM, Data: string(l.input[:l.bom]), StartRow: 1, StartCol: 1, EndRow: 1, EndCol: 1,
+		StartOffset: 0, EndOffset: l.bom, File: l.file,
+	})
+}
+
 func (l *Lexer) readChar() (ch rune, size int) {
 	ch, size = rune(l.input[l.pos]), 1
 
 	if ch >= utf8.RuneSelf {
 		// Is not a single byte wide, so fallback to full UTF8 decode
 		ch, size = utf8.DecodeRune(l.input[l.pos:])
-		if ch == utf8.RuneError {
-			if size > 0 {
-				// TODO: record illegal encoding error
-				ch = ErrChar
-			} else {
-				// TODO: record error
-				ch = ErrChar
-			}
-			return
-		} else if ch == bomChar && l.pos > 0 {
-			// TODO: record illegal byte order mark
+		if ch == utf8.RuneError && size == 1 {
+			l.recordError(InvalidUTF8, size)
+			ch = ErrChar
+		} else if ch == bomChar {
+			l.recordError(StrayBOM, size)
 			ch = ErrChar
-			return
 		}
 	}
 
 	return
 }
 
+// recordError records a problem with the char at the current position. Chars
+// are read again when backtracking, so each position is only recorded once
+func (l *Lexer) recordError(kind LexErrorKind, size int) {
+	if l.errorAt(l.pos) == nil {
+		l.addError(&LexError{
+			Kind: kind, File: l.file, Offset: l.pos, Row: l.row, Col: l.col, Bytes: l.input[l.pos : l.pos+size],
+		})
+	}
+}
+
+// addError adds an error keeping the errors in input order. Matchers read
+// ahead, so errors are not always found in order
+func (l *Lexer) addError(err *LexError) {
+	i := len(l.errs)
+	for i > 0 && l.errs[i-1].Offset > err.Offset {
+		i--
+	}
+	l.errs = append(l.errs, nil)
+	copy(l.errs[i+1:], l.errs[i:])
+	l.errs[i] = err
+}
+
+// errorAt returns the error recorded at a given offset, if any
+func (l *Lexer) errorAt(offset int) *LexError {
+	for i := len(l.errs) - 1; i >= 0 && l.errs[i].Offset >= offset; i-- {
+		if l.errs[i].Offset == offset {
+			return l.errs[i]
+		}
+	}
+	return nil
+}
+
+// File returns the file the input was read from, or NoFile if the lexer wasn't
+// created by NewLexerForFile
+func (l *Lexer) File() FileID {
+	return l.file
+}
+
+// Errors returns the problems found in the input so far, in input order
+func (l *Lexer) Errors() []*LexError {
+	return l.errs
+}
+
 // CurrChar returns the current character, but in no way consumes it
 func (l *Lexer) CurrChar() rune {
 	return l.currCh
@@ -132,14 +312,16 @@ func (l *Lexer) NextChar() rune {
 	// Are we done?
 	if l.nextPos >= len(l.input) {
 		l.currCh = EOFChar
-		return l.currCh
+	} else {
+		ch, size := l.readChar()
+		l.currCh = ch
+		l.nextPos += size
 	}
 
-	ch, size := l.readChar()
-	l.currCh = ch
-
-	l.nextPos += size
-	return ch
+	if l.pos > l.farthest.pos {
+		l.farthest = l.Pos()
+	}
+	return l.currCh
 }
 
 // MarkPos saves all position/current char information for possible later
@@ -158,17 +340,97 @@ func (l *Lexer) ResetPos() {
 	l.currCh = l.markCh
 }
 
+// LexerPos is a saved location in the input data returned by Pos
+type LexerPos struct {
+	pos, nextPos             int
+	row, col, endRow, endCol int32
+	ch                       rune
+}
+
+// Pos returns the current location in the input data. Unlike MarkPos, any
+// number of locations can be saved at once, which makes it suitable for
+// backtracking in nested matches. The same restrictions as ResetPos apply.
+func (l *Lexer) Pos() LexerPos {
+	return LexerPos{
+		pos: l.pos, nextPos: l.nextPos, row: l.row, col: l.col,
+		endRow: l.endRow, endCol: l.endCol, ch: l.currCh,
+	}
+}
+
+// SetPos restores a location previously returned by Pos
+func (l *Lexer) SetPos(pos LexerPos) {
+	l.pos, l.nextPos, l.row, l.col = pos.pos, pos.nextPos, pos.row, pos.col
+	l.endRow, l.endCol, l.currCh = pos.endRow, pos.endCol, pos.ch
+}
+
+// Offset returns the byte offset of the current character in the input data
+func (l *Lexer) Offset() int {
+	return l.pos
+}
+
+// LongestMatch tries each matcher in turn starting from the current location
+// and leaves the lexer positioned after the longest match. When two matchers
+// match the same number of characters, the earlier one wins. It returns the
+// index of the winning matcher or -1 if none matched at least one character
+func (l *Lexer) LongestMatch(matchers []func() bool) int {
+	start := l.Pos()
+	best, end := -1, start
+	l.farthest = start
+
+	for i, match := range matchers {
+		if match() && l.pos > end.pos {
+			best, end = i, l.Pos()
+		}
+		l.SetPos(start)
+	}
+
+	l.SetPos(end)
+	return best
+}
+
+// *** Modes ***
+
+// CurrentMode returns the mode the lexer is currently in
+func (l *Lexer) CurrentMode() Mode {
+	return l.mode
+}
+
+// PushMode saves the current mode on the mode stack and switches to the given
+// mode
+func (l *Lexer) PushMode(mode Mode) {
+	l.modes = append(l.modes, l.mode)
+	l.mode = mode
+}
+
+// PopMode switches back to the mode saved by the most recent PushMode. It
+// returns ErrModeStackEmpty (and leaves the mode unchanged) if there is none
+func (l *Lexer) PopMode() error {
+	if len(l.modes) == 0 {
+		return ErrModeStackEmpty
+	}
+	l.mode = l.modes[len(l.modes)-1]
+	l.modes = l.modes[:len(l.modes)-1]
+	return nil
+}
+
 // *** Build/Discard token ***
 
-// BuildToken builds a token with the given token type, but no data
+// BuildToken builds a token with the given token type, but no data. Any trivia
+// built since the last token is attached to it
 func (l *Lexer) BuildToken(tt TokenType, t *Token) {
-	t.Type = tt
-	t.StartRow, t.EndRow = l.startRow, l.endRow
-	t.StartCol, t.EndCol = l.startCol, l.endCol
+	l.fillToken(tt, t)
+	t.Trivia, l.trivia = l.trivia, nil
 
 	l.DiscardTokenData()
 }
 
+func (l *Lexer) fillToken(tt TokenType, t *Token) {
+	t.Type, t.Err, t.Channel = tt, nil, DefaultChannel
+	t.StartRow, t.EndRow = l.startRow, l.endRow
+	t.StartCol, t.EndCol = l.startCol, l.endCol
+	t.StartOffset, t.EndOffset, t.File = l.tokenStart, l.pos, l.file
+}
+
 // BuildTokenNext builds a token with the given token type (but no data) after
 // advancing to the next character in the input. This version must be used after
 // matching using primitives instead of the match functions
@@ -191,6 +453,61 @@ func (l *Lexer) BuildTokenDataNext(tt TokenType, t *Token) {
 	l.BuildTokenData(tt, t)
 }
 
+// BuildIllegalToken builds an ILLEGAL token when no rule matched at the
+// current position and records why in its Err field. When a rule got as far
+// as the end of the input the rest of the input becomes the token, when one
+// failed just after a backslash the escape sequence is included, otherwise
+// the token is just the current char
+func (l *Lexer) BuildIllegalToken(t *Token) {
+	kind := UnexpectedChar
+	switch far := l.farthest; {
+	case l.currCh == ErrChar && l.errorAt(l.pos) != nil:
+		// Already recorded when read
+		kind = l.errorAt(l.pos).Kind
+	case far.pos > l.pos && far.ch == EOFChar:
+		kind = Unterminated
+		l.SetPos(far)
+	case far.pos > l.pos && l.input[far.pos-1] == '\\':
+		kind = BadEscape
+		l.SetPos(far)
+	}
+	l.BuildTokenDataNext(ILLEGAL, t)
+
+	if kind == InvalidUTF8 || kind == StrayBOM {
+		t.Err = l.errorAt(t.StartOffset)
+		return
+	}
+	t.Err = &LexError{
+		Kind: kind, File: t.File, Offset: t.StartOffset, Row: t.StartRow, Col: t.StartCol, Bytes: []byte(t.Data),
+	}
+	l.addError(t.Err)
+}
+
+// MakeIllegal turns a token that was just built into an ILLEGAL token on the
+// default channel because of a problem found after it matched (ex:
+// ModeStackUnderflow), recording why in its Err field. Its data is the text it
+// covers, even if it was built without data
+func (l *Lexer) MakeIllegal(t *Token, kind LexErrorKind) {
+	t.Type, t.Channel = ILLEGAL, DefaultChannel
+	t.Data = string(l.input[t.StartOffset:t.EndOffset])
+	t.Err = &LexError{
+		Kind: kind, File: t.File, Offset: t.StartOffset, Row: t.StartRow, Col: t.StartCol, Bytes: []byte(t.Data),
+	}
+	l.addError(t.Err)
+}
+
+// BuildTrivia builds a token with the given token type and string data like
+// BuildTokenData, but instead of returning it, keeps it as trivia of the next
+// token built. It is used in place of DiscardTokenData to skip a token without
+// losing its text
+func (l *Lexer) BuildTrivia(tt TokenType) {
+	t := Token{Data: string(l.input[l.tokenStart:l.pos])}
+	l.fillToken(tt, &t)
+	l.trivia = append(l.trivia, t)
+
+	l.DiscardTokenData()
+}
+
 // DiscardTokenData discards any matched characers and resets the start of the
 // next potential token to the current position
 func (l *Lexer) DiscardTokenData() {
@@ -219,10 +536,17 @@ func (l *Lexer) MatchChar(char rune) bool {
 	return true
 }
 
+// MatchAnyChar attempts to match any char and returns true if it does or false
+// if the end of the input has been reached
+func (l *Lexer) MatchAnyChar() bool {
+	return l.MatchCharExcept(EOFChar)
+}
+
 // MatchCharExcept attempts to match any char except the one given and returns true
-// if it does or false otherwise
+// if it does or false otherwise. The end of the input is never matched
 func (l *Lexer) MatchCharExcept(char rune) bool {
-	if l.CurrChar() == char {
+	ch := l.CurrChar()
+	if ch == char || ch == EOFChar {
 		return false
 	}
 
@@ -244,10 +568,10 @@ func (l *Lexer) MatchCharInRange(start, end rune) bool {
 
 // MatchCharExceptInRange attempts to match any char except those between and
 // inclusive of the start and end characters. It returns true if it does or
-// false otherwise
+// false otherwise. The end of the input is never matched
 func (l *Lexer) MatchCharExceptInRange(start, end rune) bool {
 	ch := l.CurrChar()
-	if ch >= start && ch <= end {
+	if ch >= start && ch <= end || ch == EOFChar {
 		return false
 	}
 
@@ -270,9 +594,13 @@ func (l *Lexer) MatchCharInSeq(seq string) bool {
 }
 
 // MatchCharExceptInSeq attempts to match a character that is not in the given
-// sequence and returns true if it does or false otherwise
+// sequence and returns true if it does or false otherwise. The end of the input
+// is never matched
 func (l *Lexer) MatchCharExceptInSeq(seq string) bool {
 	ch := l.CurrChar()
+	if ch == EOFChar {
+		return false
+	}
 
 	for _, c := range seq {
 		if ch == c {
@@ -284,6 +612,44 @@ func (l *Lexer) MatchCharExceptInSeq(seq string) bool {
 	return true
 }
 
+// MatchCharExceptInRanges attempts to match any char except those between and
+// inclusive of any of the given start and end pairs of characters (start1, end1,
+// start2, end2, etc.). It returns true if it does or false otherwise. The end of
+// the input is never matched
+func (l *Lexer) MatchCharExceptInRanges(ranges ...rune) bool {
+	ch := l.CurrChar()
+	if ch == EOFChar {
+		return false
+	}
+
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ch >= ranges[i] && ch <= ranges[i+1] {
+			return false
+		}
+	}
+
+	l.NextChar()
+	return true
+}
+
+// MatchCharUnlessSeq attempts to match any single char as long as the input at
+// the current position does not start with the given sequence. It returns true
+// if it does or false otherwise. The end of the input is never matched
+func (l *Lexer) MatchCharUnlessSeq(seq string) bool {
+	if l.CurrChar() == EOFChar {
+		return false
+	}
+
+	pos := l.Pos()
+	if l.MatchSeq(seq) {
+		l.SetPos(pos)
+		return false
+	}
+
+	l.NextChar()
+	return true
+}
+
 // MatchSeq attempts to match the exact sequence of characers given and returns
 // true if it does or false otherwise
 func (l *Lexer) MatchSeq(seq string) bool {
@@ -5,6 +5,7 @@ import (
 
 	runtime "github.com/nu11ptr/parsegen/runtime/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -108,3 +109,252 @@ func TestLexer(t *testing.T) {
 		assert.Equal(t, lex.CurrChar(), runtime.EOFChar)
 	})
 }
+
+func TestLexerOffsets(t *testing.T) {
+	lex := runtime.NewLexerFromString(input)
+	var tok runtime.Token
+
+	require.True(t, lex.MatchSeq("abc\n\td"))
+	lex.DiscardTokenData()
+	require.True(t, lex.MatchSeq("e/"))
+	lex.BuildTokenData(bogus, &tok)
+	assert.Equal(t, 6, tok.StartOffset)
+	assert.Equal(t, 8, tok.EndOffset)
+
+	require.True(t, lex.MatchUntilSeq("😊"))
+	lex.DiscardTokenData()
+	lex.NextChar()
+	lex.BuildTokenData(bogus, &tok)
+	assert.Equal(t, tok.Data, input[tok.StartOffset:tok.EndOffset])
+	assert.Equal(t, len(input), tok.EndOffset)
+
+	// Tokens without data still cover their input
+	lex.BuildToken(runtime.EOF, &tok)
+	assert.Equal(t, len(input), tok.StartOffset)
+	assert.Equal(t, len(input), tok.EndOffset)
+}
+
+func TestLexerBacktracking(t *testing.T) {
+	lex := runtime.NewLexerFromString("ab*/c")
+	var tok runtime.Token
+
+	t.Run("Pos/SetPos", func(t *testing.T) {
+		pos := lex.Pos()
+		assert.True(t, lex.MatchSeq("ab"))
+		assert.Equal(t, 2, lex.Offset())
+
+		lex.SetPos(pos)
+		assert.Equal(t, 0, lex.Offset())
+		assert.Equal(t, 'a', lex.CurrChar())
+	})
+
+	t.Run("LongestMatch", func(t *testing.T) {
+		matchers := []func() bool{
+			func() bool { return lex.MatchChar('a') },
+			func() bool { return lex.MatchSeq("ab") },
+			func() bool { return lex.MatchChar('a') && lex.MatchChar('b') },
+			func() bool { return lex.MatchChar('x') },
+		}
+		assert.Equal(t, 1, lex.LongestMatch(matchers))
+		assert.Equal(t, -1, lex.LongestMatch(matchers[3:]))
+
+		lex.BuildTokenData(bogus, &tok)
+		assertToken(t, &tok, bogus, "ab", 1, 1, 1, 2)
+	})
+
+	t.Run("MatchCharUnlessSeq", func(t *testing.T) {
+		assert.False(t, lex.MatchCharUnlessSeq("*/"))
+		assert.True(t, lex.MatchCharUnlessSeq("*-"))
+
+		lex.BuildTokenData(bogus, &tok)
+		assertToken(t, &tok, bogus, "*", 1, 3, 1, 3)
+	})
+
+	t.Run("MatchCharExceptInRanges", func(t *testing.T) {
+		assert.False(t, lex.MatchCharExceptInRanges('a', 'c', '/', '/'))
+		assert.True(t, lex.MatchCharExceptInRanges('a', 'c', '0', '9'))
+
+		lex.BuildTokenData(bogus, &tok)
+		assertToken(t, &tok, bogus, "/", 1, 4, 1, 4)
+	})
+
+	t.Run("MatchAnyChar", func(t *testing.T) {
+		assert.True(t, lex.MatchAnyChar())
+		assert.False(t, lex.MatchAnyChar())
+
+		lex.BuildTokenData(bogus, &tok)
+		assertToken(t, &tok, bogus, "c", 1, 5, 1, 5)
+	})
+
+	t.Run("Except never matches EOF", func(t *testing.T) {
+		assert.False(t, lex.MatchCharExcept('a'))
+		assert.False(t, lex.MatchCharExceptInRange('a', 'z'))
+		assert.False(t, lex.MatchCharExceptInSeq("abc"))
+		assert.False(t, lex.MatchCharExceptInRanges('a', 'z'))
+		assert.False(t, lex.MatchCharUnlessSeq("*/"))
+	})
+}
+
+func TestLexerModes(t *testing.T) {
+	const (
+		inner runtime.Mode = iota + 1
+		innerMost
+	)
+	lex := runtime.NewLexerFromString("")
+	assert.Equal(t, runtime.DefaultMode, lex.CurrentMode())
+
+	lex.PushMode(inner)
+	lex.PushMode(innerMost)
+	assert.Equal(t, innerMost, lex.CurrentMode())
+
+	assert.NoError(t, lex.PopMode())
+	assert.Equal(t, inner, lex.CurrentMode())
+	assert.NoError(t, lex.PopMode())
+	assert.Equal(t, runtime.DefaultMode, lex.CurrentMode())
+
+	// Underflow leaves the mode alone
+	assert.Equal(t, runtime.ErrModeStackEmpty, lex.PopMode())
+	assert.Equal(t, runtime.DefaultMode, lex.CurrentMode())
+}
+
+func TestLexerEncodingErrors(t *testing.T) {
+	lex := runtime.NewLexerFromString("a\xffb\ufeff\ufffd")
+	for lex.CurrChar() != runtime.EOFChar {
+		lex.NextChar()
+	}
+
+	// Reading the same chars again doesn't record them twice
+	lex2 := runtime.NewLexerFromString("a\xffb")
+	pos := lex2.Pos()
+	lex2.NextChar()
+	lex2.NextChar()
+	lex2.SetPos(pos)
+	lex2.NextChar()
+	lex2.NextChar()
+	assert.Len(t, lex2.Errors(), 1)
+
+	errs := lex.Errors()
+	require.Len(t, errs, 2)
+	assert.Equal(t, &runtime.LexError{
+		Kind: runtime.InvalidUTF8, Offset: 1, Row: 1, Col: 2, Bytes: []byte("\xff"),
+	}, errs[0])
+	assert.Equal(t, &runtime.LexError{
+		Kind: runtime.StrayBOM, Offset: 3, Row: 1, Col: 4, Bytes: []byte("\ufeff"),
+	}, errs[1])
+	assert.Equal(t, `1:2: invalid UTF-8 encoding "\xff"`, errs[0].Error())
+}
+
+func TestLexerMakeIllegal(t *testing.T) {
+	lex := runtime.NewLexerFromString("ab")
+	var tok runtime.Token
+	lex.MatchChar('a')
+	lex.DiscardTokenData()
+	lex.MatchChar('b')
+	lex.BuildToken(bogus, &tok)
+	tok.Channel = runtime.HiddenChannel
+
+	lex.MakeIllegal(&tok, runtime.ModeStackUnderflow)
+	assert.Equal(t, runtime.ILLEGAL, tok.Type)
+	assert.Equal(t, runtime.DefaultChannel, tok.Channel)
+	assert.Equal(t, "b", tok.Data)
+	assert.Equal(t, &runtime.LexError{
+		Kind: runtime.ModeStackUnderflow, Offset: 1, Row: 1, Col: 2, Bytes: []byte("b"),
+	}, tok.Err)
+	assert.Equal(t, []*runtime.LexError{tok.Err}, lex.Errors())
+}
+
+// A byte order mark is only stray when it isn't at the start of the input
+func TestLexerLeadingBOM(t *testing.T) {
+	lex := runtime.NewLexerFromString("\ufeffab\ufeff")
+	var tok runtime.Token
+
+	assert.Equal(t, 'a', lex.CurrChar())
+	require.True(t, lex.MatchSeq("ab"))
+	lex.BuildTokenData(bogus, &tok)
+	assert.Equal(t, "ab", tok.Data)
+	assert.Equal(t, int32(1), tok.StartCol)
+	assert.Equal(t, 3, tok.StartOffset)
+
+	lex.NextChar()
+	require.Len(t, lex.Errors(), 1)
+	assert.Equal(t, runtime.StrayBOM, lex.Errors()[0].Kind)
+	assert.Equal(t, 5, lex.Errors()[0].Offset)
+}
+
+// A kept byte order mark is trivia of the first token, which stays at its
+// offset in the input
+func TestLexerKeepBOM(t *testing.T) {
+	lex := runtime.NewLexerFromString("\ufeffab")
+	lex.KeepBOM()
+	var tok runtime.Token
+
+	require.True(t, lex.MatchSeq("ab"))
+	lex.BuildTokenData(bogus, &tok)
+	assert.Equal(t, "ab", tok.Data)
+	assert.Equal(t, 3, tok.StartOffset)
+	assert.Equal(t, int32(1), tok.StartCol)
+	assert.Equal(t, []runtime.Token{{
+		Type: runtime.BOM, Data: "\ufeff", StartRow: 1, StartCol: 1, EndRow: 1, EndCol: 1, EndOffset: 3,
+	}}, tok.Trivia)
+
+	// Only the first token can have it
+	lex.KeepBOM()
+	lex.BuildToken(runtime.EOF, &tok)
+	assert.Nil(t, tok.Trivia)
+
+	lex = runtime.NewLexerFromString("ab")
+	lex.KeepBOM()
+	require.True(t, lex.MatchSeq("ab"))
+	lex.BuildTokenData(bogus, &tok)
+	assert.Nil(t, tok.Trivia)
+}
+
+func TestLexerBuildIllegalToken(t *testing.T) {
+	tests := []struct {
+		name, input, data string
+		kind              runtime.LexErrorKind
+	}{
+		{name: "unexpected char", input: "#x", data: "#", kind: runtime.UnexpectedChar},
+		{name: "unterminated", input: `"ab`, data: `"ab`, kind: runtime.Unterminated},
+		{name: "bad escape", input: `"a\q"`, data: `"a\q`, kind: runtime.BadEscape},
+		{name: "invalid UTF-8", input: "\xff", data: "\xff", kind: runtime.InvalidUTF8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := runtime.NewLexerFromString(test.input)
+			// "(\\[nt] | ~["\\])*"
+			str := func() bool {
+				pos := lex.Pos()
+				if !lex.MatchChar('"') {
+					return false
+				}
+				for {
+					if lex.MatchChar('\\') {
+						if !lex.MatchCharInSeq("nt") {
+							lex.SetPos(pos)
+							return false
+						}
+					} else if !lex.MatchCharExceptInSeq("\"\\") {
+						break
+					}
+				}
+				if !lex.MatchChar('"') {
+					lex.SetPos(pos)
+					return false
+				}
+				return true
+			}
+
+			require.Equal(t, -1, lex.LongestMatch([]func() bool{str}))
+			var tok runtime.Token
+			lex.BuildIllegalToken(&tok)
+			assert.Equal(t, runtime.ILLEGAL, tok.Type)
+			assert.Equal(t, test.data, tok.Data)
+			require.NotNil(t, tok.Err)
+			assert.Equal(t, test.kind, tok.Err.Kind)
+			assert.Equal(t, []byte(test.data), tok.Err.Bytes)
+			assert.Equal(t, []*runtime.LexError{tok.Err}, lex.Errors())
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package runtime
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// LineIndex converts between byte offsets of an input and line/column
+// positions. Lines and columns start at 1 like the rows and columns of tokens
+// (editor protocols that count from 0, like LSP, need to subtract 1). Columns
+// can be counted in chars like the lexer does, in UTF-16 code units like most
+// editors do, or in bytes. Lines end after each '\n' as they do for the lexer,
+// and a byte order mark at the start of the input is skipped like the lexer
+// skips it.
+//
+// Positions that are out of range are clamped to the input: a column past the
+// end of its line is the end of the line and a line past the last line is the
+// last line. Offsets or columns within a char refer to that char
+type LineIndex struct {
+	input []byte
+	// Byte offset of the start of each line
+	lines []int
+}
+
+// NewLineIndex indexes the lines of an input
+func NewLineIndex(input []byte) *LineIndex {
+	lines := []int{0}
+	if r, size := utf8.DecodeRune(input); r == bomChar {
+		lines[0] = size
+	}
+	for i, b := range input {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &LineIndex{input: input, lines: lines}
+}
+
+// LineCount returns the number of lines of the input. An input ending in a
+// '\n' ends in an empty line
+func (x *LineIndex) LineCount() int {
+	return len(x.lines)
+}
+
+// LineSpan returns the byte offsets of the start and end of a line. The end is
+// just past the last char of the line, not counting its line ending
+func (x *LineIndex) LineSpan(line int) (start, end int) {
+	line = x.clampLine(line)
+	start, end = x.lines[line-1], len(x.input)
+	if line < len(x.lines) {
+		end = x.lines[line] - 1
+	}
+	if end > start && x.input[end-1] == '\r' {
+		end--
+	}
+	return start, end
+}
+
+// Line returns the text of a line without its line ending (ex: to show it in
+// an error message)
+func (x *LineIndex) Line(line int) string {
+	start, end := x.LineSpan(line)
+	return string(x.input[start:end])
+}
+
+// Position returns the line and char column of a byte offset
+func (x *LineIndex) Position(offset int) (line, col int) {
+	return x.position(offset, charWidth)
+}
+
+// UTF16Position returns the line and UTF-16 column of a byte offset
+func (x *LineIndex) UTF16Position(offset int) (line, col int) {
+	return x.position(offset, utf16Width)
+}
+
+// BytePosition returns the line and byte column of a byte offset
+func (x *LineIndex) BytePosition(offset int) (line, col int) {
+	return x.position(offset, byteWidth)
+}
+
+// Offset returns the byte offset of a line and char column
+func (x *LineIndex) Offset(line, col int) int {
+	return x.offset(line, col, charWidth)
+}
+
+// UTF16Offset returns the byte offset of a line and UTF-16 column
+func (x *LineIndex) UTF16Offset(line, col int) int {
+	return x.offset(line, col, utf16Width)
+}
+
+// ByteOffset returns the byte offset of a line and byte column
+func (x *LineIndex) ByteOffset(line, col int) int {
+	return x.offset(line, col, byteWidth)
+}
+
+// width returns how many columns a char of a given size in bytes takes up
+type width func(ch rune, size int) int
+
+func charWidth(rune, int) int {
+	return 1
+}
+
+func utf16Width(ch rune, _ int) int {
+	// Chars outside the BMP are a surrogate pair
+	if ch > 0xFFFF {
+		return 2
+	}
+	return 1
+}
+
+func byteWidth(_ rune, size int) int {
+	return size
+}
+
+func (x *LineIndex) clampLine(line int) int {
+	switch {
+	case line < 1:
+		return 1
+	case line > len(x.lines):
+		return len(x.lines)
+	}
+	return line
+}
+
+func (x *LineIndex) position(offset int, w width) (line, col int) {
+	switch {
+	case offset < x.lines[0]:
+		offset = x.lines[0]
+	case offset > len(x.input):
+		offset = len(x.input)
+	}
+	// The last line starting at or before the offset
+	line = sort.Search(len(x.lines), func(i int) bool { return x.lines[i] > offset })
+
+	col = 1
+	for pos := x.lines[line-1]; pos < offset; {
+		ch, size := utf8.DecodeRune(x.input[pos:])
+		if pos+size > offset {
+			// Within this char
+			break
+		}
+		col += w(ch, size)
+		pos += size
+	}
+	return line, col
+}
+
+func (x *LineIndex) offset(line, col int, w width) int {
+	start, end := x.LineSpan(line)
+	pos := start
+	for n := 1; pos < end; {
+		ch, size := utf8.DecodeRune(x.input[pos:end])
+		n += w(ch, size)
+		if n > col {
+			// At or within this char
+			break
+		}
+		pos += size
+	}
+	return pos
+}
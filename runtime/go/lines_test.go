@@ -0,0 +1,88 @@
+package runtime_test
+
+import (
+	"testing"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineIndex(t *testing.T) {
+	// 'é' is 2 bytes and 1 UTF-16 unit, '😊' is 4 bytes and 2 UTF-16 units
+	x := runtime.NewLineIndex([]byte("ab\r\néx😊y\n\nz"))
+	assert.Equal(t, 4, x.LineCount())
+	assert.Equal(t, "ab", x.Line(1))
+	assert.Equal(t, "éx😊y", x.Line(2))
+	assert.Equal(t, "", x.Line(3))
+	assert.Equal(t, "z", x.Line(4))
+
+	start, end := x.LineSpan(2)
+	assert.Equal(t, []int{4, 12}, []int{start, end})
+
+	tests := []struct {
+		offset, line, col, utf16Col, byteCol int
+	}{
+		{offset: 0, line: 1, col: 1, utf16Col: 1, byteCol: 1},
+		{offset: 4, line: 2, col: 1, utf16Col: 1, byteCol: 1},
+		{offset: 6, line: 2, col: 2, utf16Col: 2, byteCol: 3},
+		{offset: 7, line: 2, col: 3, utf16Col: 3, byteCol: 4},
+		{offset: 11, line: 2, col: 4, utf16Col: 5, byteCol: 8},
+		{offset: 13, line: 3, col: 1, utf16Col: 1, byteCol: 1},
+		{offset: 15, line: 4, col: 2, utf16Col: 2, byteCol: 2},
+	}
+	for _, test := range tests {
+		line, col := x.Position(test.offset)
+		assert.Equal(t, []int{test.line, test.col}, []int{line, col}, "Position(%d)", test.offset)
+		line, col = x.UTF16Position(test.offset)
+		assert.Equal(t, []int{test.line, test.utf16Col}, []int{line, col}, "UTF16Position(%d)", test.offset)
+		line, col = x.BytePosition(test.offset)
+		assert.Equal(t, []int{test.line, test.byteCol}, []int{line, col}, "BytePosition(%d)", test.offset)
+
+		assert.Equal(t, test.offset, x.Offset(test.line, test.col), "Offset(%d, %d)", test.line, test.col)
+		assert.Equal(t, test.offset, x.UTF16Offset(test.line, test.utf16Col),
+			"UTF16Offset(%d, %d)", test.line, test.utf16Col)
+		assert.Equal(t, test.offset, x.ByteOffset(test.line, test.byteCol),
+			"ByteOffset(%d, %d)", test.line, test.byteCol)
+	}
+
+	// Within a char is that char
+	line, col := x.Position(9)
+	assert.Equal(t, []int{2, 3}, []int{line, col})
+	assert.Equal(t, 7, x.UTF16Offset(2, 4))
+	assert.Equal(t, 4, x.ByteOffset(2, 2))
+
+	// Out of range is clamped to the input
+	line, col = x.Position(-1)
+	assert.Equal(t, []int{1, 1}, []int{line, col})
+	line, col = x.Position(100)
+	assert.Equal(t, []int{4, 2}, []int{line, col})
+	assert.Equal(t, 2, x.Offset(1, 10))
+	assert.Equal(t, 15, x.Offset(9, 9))
+	assert.Equal(t, 0, x.Offset(0, 0))
+}
+
+// Positions agree with those of the lexer
+func TestLineIndexLexer(t *testing.T) {
+	// Including when the input starts with a byte order mark
+	for _, input := range []string{input, "\ufeff" + input} {
+		x := runtime.NewLineIndex([]byte(input))
+		lex := runtime.NewLexerFromString(input)
+		var tok runtime.Token
+		for lex.CurrChar() != runtime.EOFChar {
+			lex.NextChar()
+			lex.BuildToken(bogus, &tok)
+
+			line, col := x.Position(tok.StartOffset)
+			assert.Equal(t, []int{int(tok.StartRow), int(tok.StartCol)}, []int{line, col})
+			assert.Equal(t, tok.StartOffset, x.Offset(line, col))
+		}
+	}
+	bom := runtime.NewLineIndex([]byte("\ufeffab"))
+	assert.Equal(t, "ab", bom.Line(1))
+	line, col := bom.Position(0)
+	assert.Equal(t, []int{1, 1}, []int{line, col})
+
+	f := runtime.NewFileSet().AddFile("x", []byte(input))
+	assert.Same(t, f.Lines(), f.Lines())
+	assert.Equal(t, "\t  */fghi😊", f.Lines().Line(3))
+}
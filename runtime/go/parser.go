@@ -1,9 +1,38 @@
 package runtime
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Memo is a memoized parse result for a single rule at a given token position.
+// It records the token position parsing ended at so that a memoized success
+// can be replayed without reparsing
+type Memo struct {
+	Result interface{}
+	EndPos int
+}
+
+// TokenNamer is optionally implemented by a Tokenizer to name its token types
+// in syntax errors. Tokens that always match the same literal should be named
+// by the quoted literal (ex: ';')
+type TokenNamer interface {
+	TokenName(tt TokenType) string
+}
+
+// Parser holds the state of a parse: the tokens read so far and the farthest
+// failed match. Only tokens on the default channel are parsed
 type Parser struct {
-	t      Tokenizer
-	tokens []Token
-	pos    int
+	t        Tokenizer
+	tokens   []Token
+	pos      int
+	filename string
+
+	// The farthest token position any match was attempted at, the token types
+	// that were expected there and the predicates that failed there
+	farthest int
+	expected []TokenType
+	failed   []string
 }
 
 // NewParser creates a new parser with a given tokenizer
@@ -13,6 +42,16 @@ func NewParser(t Tokenizer) *Parser {
 	return p
 }
 
+// SetFilename sets the filename syntax errors are reported against
+func (p *Parser) SetFilename(filename string) {
+	p.filename = filename
+}
+
+// Filename returns the filename set by SetFilename
+func (p *Parser) Filename() string {
+	return p.filename
+}
+
 func (p *Parser) Pos() int {
 	return p.pos
 }
@@ -33,16 +72,36 @@ func (p *Parser) NextToken() *Token {
 	}
 
 	// Get a new token from the tokenizer and append it to our token history before returning it
+	p.tokens = append(p.tokens, p.nextChannelToken())
+	return &p.tokens[p.pos]
+}
+
+// nextChannelToken gets the next token on the default channel from the
+// tokenizer. Tokens on other channels (and their trivia) become trivia of it
+func (p *Parser) nextChannelToken() Token {
 	var tok Token
 	p.t.NextToken(&tok)
-	p.tokens = append(p.tokens, tok)
-	return &p.tokens[0]
+
+	var trivia []Token
+	for tok.Channel != DefaultChannel {
+		trivia = append(trivia, tok.Trivia...)
+		tok.Trivia = nil
+		trivia = append(trivia, tok)
+
+		tok = Token{}
+		p.t.NextToken(&tok)
+	}
+	if trivia != nil {
+		tok.Trivia = append(trivia, tok.Trivia...)
+	}
+	return tok
 }
 
 func (p *Parser) MatchTokenOrRollback(tt TokenType, oldPos int) *Token {
 	tok := p.CurrToken()
 	if tok.Type != tt {
 		// Failed - rollback
+		p.expect(tt)
 		p.SetPos(oldPos)
 		return nil
 	}
@@ -53,8 +112,202 @@ func (p *Parser) MatchTokenOrRollback(tt TokenType, oldPos int) *Token {
 func (p *Parser) TryMatchToken(tt TokenType) *Token {
 	tok := p.CurrToken()
 	if tok.Type != tt {
+		p.expect(tt)
 		return nil
 	}
 	p.NextToken()
 	return tok
 }
+
+// AndPredicate reports whether match succeeds at the current position. No
+// tokens are consumed either way
+func (p *Parser) AndPredicate(match func() bool) bool {
+	pos := p.pos
+	ok := match()
+	p.pos = pos
+	return ok
+}
+
+// NotPredicate reports whether match fails at the current position. No tokens
+// are consumed either way. The tokens match expected are not what the input
+// should contain, so they are left out of syntax errors. Instead, the
+// predicate itself is recorded as failed when match succeeds
+func (p *Parser) NotPredicate(match func() bool) bool {
+	pos, farthest := p.pos, p.farthest
+	expected := append([]TokenType(nil), p.expected...)
+	failed := append([]string(nil), p.failed...)
+	ok := match()
+	p.pos, p.farthest, p.expected, p.failed = pos, farthest, expected, failed
+	if ok {
+		p.fail("not-predicate failed")
+	}
+	return !ok
+}
+
+// FailPredicate records that a semantic predicate failed at the current
+// position, so that a parse failing because of it still has a syntax error
+func (p *Parser) FailPredicate() {
+	p.fail("semantic predicate failed")
+}
+
+// GrowSeed parses a left recursive rule at the current position using the
+// seed growing algorithm of Warth et al. The rule's memo is first seeded with
+// a failure, so the left recursive call fails and the rule falls back on its
+// other alternatives. The rule is then parsed again and again, each time
+// building on the previous result via the memo, until the match stops getting
+// longer. The longest result (nil if the rule failed) is memoized and returned
+func (p *Parser) GrowSeed(memos map[int]Memo, parse func() (interface{}, bool)) interface{} {
+	pos := p.pos
+	if memo, ok := memos[pos]; ok {
+		p.pos = memo.EndPos
+		return memo.Result
+	}
+
+	memo := Memo{EndPos: pos}
+	memos[pos] = memo
+	for {
+		p.pos = pos
+		result, ok := parse()
+		if !ok || (memo.Result != nil && p.pos <= memo.EndPos) {
+			break
+		}
+		memo = Memo{Result: result, EndPos: p.pos}
+		memos[pos] = memo
+	}
+
+	p.pos = memo.EndPos
+	return memo.Result
+}
+
+// expect records that a token type was expected at the current position
+func (p *Parser) expect(tt TokenType) {
+	switch {
+	case p.pos > p.farthest:
+		p.farthest, p.expected, p.failed = p.pos, append(p.expected[:0], tt), p.failed[:0]
+	case p.pos == p.farthest:
+		for _, exp := range p.expected {
+			if exp == tt {
+				return
+			}
+		}
+		p.expected = append(p.expected, tt)
+	}
+}
+
+// fail records that a predicate failed at the current position
+func (p *Parser) fail(reason string) {
+	switch {
+	case p.pos > p.farthest:
+		p.farthest, p.expected, p.failed = p.pos, p.expected[:0], append(p.failed[:0], reason)
+	case p.pos == p.farthest:
+		for _, f := range p.failed {
+			if f == reason {
+				return
+			}
+		}
+		p.failed = append(p.failed, reason)
+	}
+}
+
+// *** Errors ***
+
+// SyntaxError describes where parsing failed. Since parsing backtracks, this
+// is the farthest position any match was attempted at
+type SyntaxError struct {
+	Filename string
+	Found    Token
+	// FoundName is the name of the found token's type
+	FoundName string
+	// Expected are the names of the token types expected instead
+	Expected []string
+	// Failed are the reasons predicates failed at the found token (ex:
+	// "not-predicate failed")
+	Failed []string
+}
+
+func (e *SyntaxError) Error() string {
+	buff := strings.Builder{}
+	if e.Filename != "" {
+		buff.WriteString(e.Filename)
+		buff.WriteByte(':')
+	}
+	buff.WriteString(fmt.Sprintf("%d:%d: ", e.Found.StartRow, e.Found.StartCol))
+	switch {
+	case len(e.Expected) > 0:
+		buff.WriteString(fmt.Sprintf("expected %s, found %s", e.ExpectedText(), e.FoundText()))
+	case len(e.Failed) > 0:
+		buff.WriteString(fmt.Sprintf("%s at %s", strings.Join(e.Failed, ", "), e.FoundText()))
+	default:
+		buff.WriteString("unexpected " + e.FoundText())
+	}
+	return buff.String()
+}
+
+// ExpectedText describes the expected token types (ex: "'(', IDENT or NUMBER")
+func (e *SyntaxError) ExpectedText() string {
+	buff := strings.Builder{}
+	for i, name := range e.Expected {
+		switch {
+		case i == 0:
+		case i == len(e.Expected)-1:
+			buff.WriteString(" or ")
+		default:
+			buff.WriteString(", ")
+		}
+		buff.WriteString(name)
+	}
+	return buff.String()
+}
+
+// FoundText describes the found token (ex: "IDENT \"x\"")
+func (e *SyntaxError) FoundText() string {
+	buff := strings.Builder{}
+	buff.WriteString(e.FoundName)
+	// Literal tokens are already fully described by their name
+	if e.Found.Data != "" && !strings.HasPrefix(e.FoundName, "'") {
+		buff.WriteString(fmt.Sprintf(" %q", e.Found.Data))
+	}
+	if e.Found.Err != nil {
+		buff.WriteString(fmt.Sprintf(" (%s)", e.Found.Err.Kind))
+	}
+	return buff.String()
+}
+
+// Err returns a syntax error describing the farthest failed match. If no match
+// has failed, the error is at the current token. It is only meaningful once
+// parsing has failed
+func (p *Parser) Err() error {
+	pos := p.farthest
+	if len(p.expected) == 0 && len(p.failed) == 0 {
+		pos = p.pos
+	}
+
+	err := &SyntaxError{
+		Filename:  p.filename,
+		Found:     p.tokens[pos],
+		FoundName: p.TokenName(p.tokens[pos].Type),
+		Expected:  make([]string, len(p.expected)),
+		Failed:    append([]string(nil), p.failed...),
+	}
+	for i, tt := range p.expected {
+		err.Expected[i] = p.TokenName(tt)
+	}
+	return err
+}
+
+// TokenName returns the name of a token type, asking the tokenizer if it
+// implements TokenNamer
+func (p *Parser) TokenName(tt TokenType) string {
+	switch tt {
+	case ILLEGAL:
+		return "ILLEGAL"
+	case EOF:
+		return "EOF"
+	}
+	if namer, ok := p.t.(TokenNamer); ok {
+		if name := namer.TokenName(tt); name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("token %d", tt)
+}
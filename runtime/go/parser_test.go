@@ -0,0 +1,205 @@
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	runtime "github.com/nu11ptr/parsegen/runtime/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	name runtime.TokenType = iota + runtime.EOF + 1
+	semi
+	pipe
+)
+
+// sliceTokenizer returns the given tokens followed by EOF
+type sliceTokenizer struct {
+	tokens []runtime.Token
+}
+
+func (s *sliceTokenizer) NextToken(tok *runtime.Token) {
+	if len(s.tokens) == 0 {
+		*tok = runtime.Token{Type: runtime.EOF}
+		return
+	}
+	*tok, s.tokens = s.tokens[0], s.tokens[1:]
+}
+
+type namedTokenizer struct {
+	sliceTokenizer
+}
+
+func (n *namedTokenizer) TokenName(tt runtime.TokenType) string {
+	return map[runtime.TokenType]string{name: "NAME", semi: "';'"}[tt]
+}
+
+func newTokens() []runtime.Token {
+	return []runtime.Token{
+		{Type: name, Data: "a", StartRow: 1, StartCol: 1},
+		{Type: name, Data: "b", StartRow: 1, StartCol: 3},
+	}
+}
+
+func TestParserNextToken(t *testing.T) {
+	p := runtime.NewParser(&sliceTokenizer{tokens: newTokens()})
+
+	assert.Equal(t, "a", p.CurrToken().Data)
+	assert.Equal(t, "b", p.NextToken().Data)
+	assert.Equal(t, runtime.EOF, p.NextToken().Type)
+
+	// Replayed tokens come from the history
+	p.SetPos(1)
+	assert.Equal(t, "b", p.CurrToken().Data)
+	assert.Equal(t, runtime.EOF, p.NextToken().Type)
+}
+
+func TestParserChannels(t *testing.T) {
+	p := runtime.NewParser(&sliceTokenizer{tokens: []runtime.Token{
+		{Type: pipe, Data: "/* a */", Channel: runtime.HiddenChannel,
+			Trivia: []runtime.Token{{Type: semi, Data: " "}}},
+		{Type: name, Data: "a", Trivia: []runtime.Token{{Type: semi, Data: " "}}},
+		{Type: pipe, Data: "// b", Channel: runtime.HiddenChannel},
+		{Type: pipe, Data: "// c", Channel: 2},
+	}})
+
+	// Only tokens on the default channel are parsed
+	tok := p.CurrToken()
+	assert.Equal(t, "a", tok.Data)
+	require.Len(t, tok.Trivia, 3)
+	assert.Equal(t, []string{" ", "/* a */", " "}, []string{tok.Trivia[0].Data, tok.Trivia[1].Data, tok.Trivia[2].Data})
+	assert.Nil(t, tok.Trivia[1].Trivia)
+
+	tok = p.NextToken()
+	assert.Equal(t, runtime.EOF, tok.Type)
+	require.Len(t, tok.ChannelTrivia(runtime.HiddenChannel), 1)
+	assert.Equal(t, "// b", tok.ChannelTrivia(runtime.HiddenChannel)[0].Data)
+	assert.Equal(t, "// c", tok.ChannelTrivia(2)[0].Data)
+}
+
+func TestParserErr(t *testing.T) {
+	p := runtime.NewParser(&namedTokenizer{sliceTokenizer{tokens: newTokens()}})
+	p.SetFilename("test.g4")
+
+	// Before any match fails, the error is at the current token
+	assert.EqualError(t, p.Err(), `test.g4:1:1: unexpected NAME "a"`)
+
+	// Failures before the farthest position don't matter
+	require.NotNil(t, p.TryMatchToken(name))
+	assert.Nil(t, p.MatchTokenOrRollback(semi, 0))
+	assert.Equal(t, 0, p.Pos())
+	require.NotNil(t, p.TryMatchToken(name))
+	assert.Nil(t, p.TryMatchToken(semi))
+	assert.Nil(t, p.TryMatchToken(pipe))
+	assert.Nil(t, p.TryMatchToken(semi))
+	p.SetPos(0)
+	assert.Nil(t, p.TryMatchToken(runtime.EOF))
+
+	err := p.Err()
+	require.Error(t, err)
+	assert.Equal(t, `test.g4:1:3: expected ';' or token 4, found NAME "b"`, err.Error())
+
+	var syntaxErr *runtime.SyntaxError
+	require.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, []string{"';'", "token 4"}, syntaxErr.Expected)
+	assert.Equal(t, "b", syntaxErr.Found.Data)
+}
+
+func TestParserErrNoNames(t *testing.T) {
+	p := runtime.NewParser(&sliceTokenizer{})
+	assert.Nil(t, p.TryMatchToken(semi))
+
+	assert.EqualError(t, p.Err(), "0:0: expected token 3, found EOF")
+}
+
+func TestParserGrowSeed(t *testing.T) {
+	// expr: expr '|' NAME | NAME;
+	p := runtime.NewParser(&sliceTokenizer{tokens: []runtime.Token{
+		{Type: name, Data: "a"}, {Type: pipe}, {Type: name, Data: "b"}, {Type: pipe},
+		{Type: name, Data: "c"}, {Type: semi},
+	}})
+	memos := make(map[int]runtime.Memo)
+
+	var expr func() interface{}
+	expr = func() interface{} {
+		return p.GrowSeed(memos, func() (interface{}, bool) {
+			pos := p.Pos()
+			if left, ok := expr().(string); ok {
+				if p.TryMatchToken(pipe) != nil {
+					if tok := p.TryMatchToken(name); tok != nil {
+						return "(" + left + "|" + tok.Data + ")", true
+					}
+				}
+			}
+			p.SetPos(pos)
+			if tok := p.TryMatchToken(name); tok != nil {
+				return tok.Data, true
+			}
+			return nil, false
+		})
+	}
+
+	// Left associative and stops before the ';'
+	assert.Equal(t, "((a|b)|c)", expr())
+	assert.Equal(t, semi, p.CurrToken().Type)
+
+	// Memoized
+	p.SetPos(0)
+	assert.Equal(t, "((a|b)|c)", expr())
+	assert.Equal(t, 5, p.Pos())
+
+	// Failure
+	p.SetPos(5)
+	assert.Nil(t, expr())
+	assert.Equal(t, 5, p.Pos())
+}
+
+func TestParserPredicates(t *testing.T) {
+	p := runtime.NewParser(&namedTokenizer{sliceTokenizer{tokens: newTokens()}})
+	matchName := func() bool { return p.TryMatchToken(name) != nil }
+	matchSemi := func() bool { return p.TryMatchToken(semi) != nil }
+
+	// Neither predicate consumes tokens
+	assert.True(t, p.AndPredicate(matchName))
+	assert.False(t, p.NotPredicate(matchName))
+	assert.Equal(t, 0, p.Pos())
+	assert.False(t, p.AndPredicate(matchSemi))
+
+	// Only the tokens an and-predicate expected are in syntax errors
+	assert.True(t, p.NotPredicate(func() bool { return p.TryMatchToken(pipe) != nil }))
+	assert.Equal(t, 0, p.Pos())
+	assert.Equal(t, `1:1: expected ';', found NAME "a"`, p.Err().Error())
+
+	// A not-predicate that fails on its own is the reason for the error
+	p = runtime.NewParser(&namedTokenizer{sliceTokenizer{tokens: newTokens()}})
+	assert.False(t, p.NotPredicate(func() bool { return p.TryMatchToken(name) != nil }))
+	assert.Equal(t, `1:1: not-predicate failed at NAME "a"`, p.Err().Error())
+
+	var syntaxErr *runtime.SyntaxError
+	require.True(t, errors.As(p.Err(), &syntaxErr))
+	assert.Empty(t, syntaxErr.Expected)
+	assert.Equal(t, []string{"not-predicate failed"}, syntaxErr.Failed)
+}
+
+func TestParserFailPredicate(t *testing.T) {
+	p := runtime.NewParser(&namedTokenizer{sliceTokenizer{tokens: newTokens()}})
+	require.NotNil(t, p.TryMatchToken(name))
+	p.FailPredicate()
+	assert.EqualError(t, p.Err(), `1:3: semantic predicate failed at NAME "b"`)
+
+	// Tokens expected at the same position are reported first
+	assert.Nil(t, p.TryMatchToken(semi))
+	assert.EqualError(t, p.Err(), `1:3: expected ';', found NAME "b"`)
+	var syntaxErr *runtime.SyntaxError
+	require.True(t, errors.As(p.Err(), &syntaxErr))
+	assert.Equal(t, []string{"semantic predicate failed"}, syntaxErr.Failed)
+
+	// Failures before the farthest position don't matter
+	p.SetPos(0)
+	p.FailPredicate()
+	require.True(t, errors.As(p.Err(), &syntaxErr))
+	assert.Equal(t, []string{"semantic predicate failed"}, syntaxErr.Failed)
+	assert.Equal(t, "b", syntaxErr.Found.Data)
+}